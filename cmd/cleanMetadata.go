@@ -32,10 +32,10 @@ var cleanMetadataCmd = &cobra.Command{
 	Long: `Remove vendor metadata from media files. 
 	files argument may be dir (process all files) or wildcards file names (process only matched files)`,
 	Args: cobra.RangeArgs(1, 1),
-	Run:  runCleanMetadata,
+	Run:  exitOnError(runCleanMetadata),
 }
 
-func runCleanMetadata(cmd *cobra.Command, args []string) {
+func runCleanMetadata(cmd *cobra.Command, args []string) error {
 	printCommandArgs(cmd, args)
 
 	files := extractPath(args, 0, ".")
@@ -61,8 +61,7 @@ func runCleanMetadata(cmd *cobra.Command, args []string) {
 	}
 
 	//Images and video
-	//imgArgs.forImages()
-	//imgArgs.forVideoMp4()
+	//imgArgs.forMedia(mediaImage, mediaRaw, mediaVideo)
 
 	if recursively {
 		imgArgs.recursively()
@@ -70,9 +69,11 @@ func runCleanMetadata(cmd *cobra.Command, args []string) {
 
 	imgArgs.src(files)
 
-	if !DryRun {
-		exifTool.exec()
+	if DryRun {
+		return nil
 	}
+	result, err := exifTool.exec()
+	return result.report(err)
 }
 
 func init() {
@@ -28,10 +28,10 @@ var cleanNamesCmd = &cobra.Command{
 	Long: `Renaming files with the -copy suffix to shorten variations. 
 	files argument may be dir (process all files) or wildcards file names (process only matched files)`,
 	Args: cobra.RangeArgs(1, 1),
-	Run:  runCleanNames,
+	Run:  exitOnError(runCleanNames),
 }
 
-func runCleanNames(cmd *cobra.Command, args []string) {
+func runCleanNames(cmd *cobra.Command, args []string) error {
 	printCommandArgs(cmd, args)
 
 	files := extractPath(args, 0, ".")
@@ -52,8 +52,7 @@ func runCleanNames(cmd *cobra.Command, args []string) {
 	imgArgs.changeTag(tagName, "${filename;s/ - Copy/%-c/gi;s/ Copy/%-c/gi}")
 
 	//Images and video
-	//imgArgs.forImages()
-	//imgArgs.forVideoMp4()
+	//imgArgs.forMedia(mediaImage, mediaRaw, mediaVideo)
 	if recursively {
 		imgArgs.recursively()
 	}
@@ -61,7 +60,8 @@ func runCleanNames(cmd *cobra.Command, args []string) {
 	imgArgs.src(files)
 
 	//tagName = "testname" should avoid real renaming
-	exifTool.exec()
+	result, err := exifTool.exec()
+	return result.report(err)
 }
 
 func init() {
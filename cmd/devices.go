@@ -0,0 +1,45 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var devicesSource string
+
+// devicesCmd represents the devices command
+var devicesCmd = &cobra.Command{
+	Use:   "devices",
+	Short: "Inspect connected devices",
+	Long: `Show connected cameras, cards and other devices which may be used by import commands. 
+	Helps to understand why a device was skipped by an import command.`,
+	Aliases: []string{"device"},
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if cmd.Flags().Changed("source") {
+			viper.Set(cfgImportSource, devicesSource)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(devicesCmd)
+
+	devicesCmd.PersistentFlags().StringVar(&devicesSource, "source", "", "Devices source: 'wpd' (Windows Portable Devices), 'fs' (mounted volumes) or 'auto'. Default is 'import.source' configuration")
+}
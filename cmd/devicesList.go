@@ -0,0 +1,124 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+// devicesListCmd represents the devices list command
+var devicesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List connected devices",
+	Long: `Print connected devices with their storages, free space and top-level folders. 
+	For each import profile prints whether it matches the device (or which filters failed) 
+	and how many files would be imported.`,
+	Args:    cobra.NoArgs,
+	Aliases: []string{"ls"},
+	Run:     runDevicesList,
+}
+
+func runDevicesList(cmd *cobra.Command, args []string) {
+	printCommandArgs(cmd, args)
+
+	options := mtp.ImportOptions{Ledger: openLedger()}
+	defer options.Close()
+
+	reports, err := mtp.InspectDevices(getMediaSource(), getDeviceProfiles(), options)
+	if err != nil {
+		log.Errorf("Unable to read devices: %v", err)
+		os.Exit(1)
+	}
+
+	if len(reports) == 0 {
+		log.Infof("No devices were found")
+	}
+	for _, report := range reports {
+		printDeviceReport(report)
+	}
+}
+
+// getDeviceProfiles returns device profiles of all valid import profiles
+func getDeviceProfiles() []mtp.DeviceProfile {
+	result := make([]mtp.DeviceProfile, 0)
+	for _, name := range getImportProfileNames() {
+		profile, err := getImportProfile(name)
+		if err != nil {
+			log.Warningf("%v", err)
+			continue
+		}
+		deviceProfile, err := profile.toDeviceProfile()
+		if err != nil {
+			log.Warningf("Invalid '%s' profile: %v", profile.Name, err)
+			continue
+		}
+		result = append(result, deviceProfile)
+	}
+	return result
+}
+
+func printDeviceReport(report mtp.DeviceReport) {
+	log.Infof("%s", report.Info.Label)
+	if report.Error != nil {
+		log.Infof("  unable to open device: %v", report.Error)
+		return
+	}
+
+	if report.Info.Manufacturer != "" {
+		log.Infof("  manufacturer: %s", report.Info.Manufacturer)
+	}
+	if report.Info.SerialNumber != "" {
+		log.Infof("  serial number: %s", report.Info.SerialNumber)
+	}
+	for _, storage := range report.Storages {
+		log.Infof("  %s", formatStorageReport(storage))
+	}
+	for _, profile := range report.Profiles {
+		log.Infof("  %s", formatProfileReport(profile))
+	}
+}
+
+func formatStorageReport(storage mtp.StorageReport) string {
+	space := "unknown free space"
+	if storage.Capacity > 0 {
+		space = fmt.Sprintf("%s free of %s", mtp.SizeToLabel(storage.FreeSpace), mtp.SizeToLabel(storage.Capacity))
+	}
+	return fmt.Sprintf("storage #%v '%s': %s, folders: [%s]", storage.Index, storage.Name, space, strings.Join(storage.Folders, ", "))
+}
+
+func formatProfileReport(profile mtp.ProfileReport) string {
+	if !profile.Matched {
+		return fmt.Sprintf("profile '%s': not matched, failed: %s", profile.Name, profile.Reason)
+	}
+
+	result := fmt.Sprintf("profile '%s': matched, %v file(s) (%s) to import", profile.Name, profile.FilesCount, mtp.SizeToLabel(profile.TotalSize))
+	if profile.SkippedCount > 0 {
+		result += fmt.Sprintf(", %v file(s) already imported", profile.SkippedCount)
+	}
+	return result
+}
+
+func init() {
+	devicesCmd.AddCommand(devicesListCmd)
+}
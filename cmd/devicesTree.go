@@ -0,0 +1,75 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+var devicesTreeDepth int
+
+// devicesTreeCmd represents the devices tree command
+var devicesTreeCmd = &cobra.Command{
+	Use:   "tree id",
+	Short: "Print device objects tree",
+	Long: `Print storages, folders and files of the device. 
+	Device id is the number from 'devices list' output (e.g. 1 for 'MTP#1')`,
+	Args: cobra.RangeArgs(1, 1),
+	Run:  runDevicesTree,
+}
+
+func runDevicesTree(cmd *cobra.Command, args []string) {
+	printCommandArgs(cmd, args)
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id < 0 {
+		log.Errorf("Invalid '%s' device id", args[0])
+		os.Exit(1)
+	}
+
+	source := getMediaSource()
+	err = mtp.WalkDeviceTree(source, id, devicesTreeDepth, func(obj *mtp.Object, depth int) {
+		log.Infof("%s", formatTreeObject(obj, depth))
+	})
+	if err != nil {
+		log.Errorf("Unable to read #%v device: %v", id, err)
+		os.Exit(1)
+	}
+}
+
+func formatTreeObject(obj *mtp.Object, depth int) string {
+	indent := strings.Repeat("  ", depth)
+	if obj.IsDir {
+		return fmt.Sprintf("%s%s/", indent, obj.Name)
+	}
+	return fmt.Sprintf("%s%s (%s, %s)", indent, obj.Name, mtp.SizeToLabel(obj.Size), time.Unix(obj.ModTime, 0).Format("2006-01-02 15:04:05"))
+}
+
+func init() {
+	devicesCmd.AddCommand(devicesTreeCmd)
+
+	devicesTreeCmd.Flags().IntVarP(&devicesTreeDepth, "depth", "l", 0, "Max depth of printed tree, 0 means unlimited")
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+func TestDevicesCmd_CommandStructure(t *testing.T) {
+	assert.Equal(t, "media-tool devices list", devicesListCmd.CommandPath())
+	assert.Equal(t, "media-tool devices tree", devicesTreeCmd.CommandPath())
+
+	assert.NoError(t, devicesListCmd.Args(devicesListCmd, []string{}))
+	assert.Error(t, devicesListCmd.Args(devicesListCmd, []string{"0"}))
+	assert.Error(t, devicesTreeCmd.Args(devicesTreeCmd, []string{}))
+	assert.NoError(t, devicesTreeCmd.Args(devicesTreeCmd, []string{"0"}))
+}
+
+func TestFormatStorageReport(t *testing.T) {
+	storage := mtp.StorageReport{
+		Storage: mtp.Storage{Name: "SD", FreeSpace: 1 << 30, Capacity: 64 << 30},
+		Index:   1,
+		Folders: []string{"DCIM", "MISC"},
+	}
+	assert.Equal(t, "storage #1 'SD': 1.0 GiB free of 64.0 GiB, folders: [DCIM, MISC]", formatStorageReport(storage))
+
+	storage.Capacity = 0
+	assert.Equal(t, "storage #1 'SD': unknown free space, folders: [DCIM, MISC]", formatStorageReport(storage))
+}
+
+func TestFormatProfileReport(t *testing.T) {
+	assert.Equal(t, "profile 'gopro': matched, 2 file(s) (1.5 KiB) to import, 3 file(s) already imported",
+		formatProfileReport(mtp.ProfileReport{Name: "gopro", Matched: true, FilesCount: 2, SkippedCount: 3, TotalSize: 1536}))
+	assert.Equal(t, "profile 'camvideo': not matched, failed: label~CAM",
+		formatProfileReport(mtp.ProfileReport{Name: "camvideo", Reason: "label~CAM"}))
+}
+
+func TestFormatTreeObject(t *testing.T) {
+	modTime := time.Date(2024, 5, 18, 10, 30, 15, 0, time.Local)
+
+	assert.Equal(t, "  DCIM/", formatTreeObject(&mtp.Object{Name: "DCIM", IsDir: true}, 1))
+	assert.Equal(t, "    GX010001.MP4 (2.0 KiB, 2024-05-18 10:30:15)",
+		formatTreeObject(&mtp.Object{Name: "GX010001.MP4", Size: 2048, ModTime: modTime.Unix()}, 2))
+}
+
+func TestGetDeviceProfiles_BuiltIn(t *testing.T) {
+	names := make([]string, 0)
+	for _, profile := range getDeviceProfiles() {
+		names = append(names, profile.Name)
+	}
+	assert.Subset(t, names, []string{"camvideo", "gopro", "sdphotos"})
+}
@@ -0,0 +1,305 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+// minExifToolVersion is the oldest exiftool version which is known to work with the tool
+const minExifToolVersion = 12.0
+
+// minQuickTimeUTCVersion is the first exiftool version with '-api QuickTimeUTC' option
+const minQuickTimeUTCVersion = 10.10
+
+type doctorStatus int
+
+const (
+	doctorOk doctorStatus = iota
+	doctorWarning
+	doctorError
+)
+
+// doctorCheck is a single diagnostics result
+type doctorCheck struct {
+	name    string
+	status  doctorStatus
+	message string
+}
+
+// doctor checks configuration and external tools. Commands and device sources are replaceable for tests
+type doctor struct {
+	execCommand func(name string, args ...string) *exec.Cmd
+	newSource   func(sourceType string, mountDirs []string) (mtp.Source, error)
+	checks      []doctorCheck
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [targetDir...]",
+	Short: "Check environment and configuration",
+	Long: `Print resolved config file, exiftool and ffmpeg paths and versions, required exiftool features, 
+	writability and free space of temp and target dirs and availability of the devices source.
+	Target dirs of import profiles are checked if no dirs were specified. Exits with non-zero code if any problem was found.`,
+	Run: exitOnError(runDoctor),
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	printCommandArgs(cmd, args)
+
+	diagnostics := newDoctor()
+	diagnostics.run(args)
+	diagnostics.print()
+
+	if problems := diagnostics.count(doctorError); problems > 0 {
+		return fmt.Errorf("%v problem(s) found", problems)
+	}
+	return nil
+}
+
+func newDoctor() *doctor {
+	return &doctor{execCommand: exec.Command, newSource: mtp.NewSource}
+}
+
+// run executes all checks. Target dirs of import profiles are checked if targetDirs are empty
+func (diagnostics *doctor) run(targetDirs []string) {
+	diagnostics.checkConfig()
+	if exifTool, found := diagnostics.checkTool("exiftool", cfgExifToolPath, doctorError, "it is required by most commands"); found {
+		diagnostics.checkExifTool(exifTool)
+	}
+	diagnostics.checkTool("ffmpeg", cfgFfmpegPath, doctorWarning, "it is required to merge GoPro chapters only")
+
+	diagnostics.checkDir("temp dir", os.TempDir(), false)
+	if len(targetDirs) == 0 {
+		targetDirs = diagnostics.profileTargetDirs()
+	}
+	for _, dir := range targetDirs {
+		diagnostics.checkDir("target dir", dir, true)
+	}
+
+	diagnostics.checkDevices()
+}
+
+func (diagnostics *doctor) add(name string, status doctorStatus, format string, args ...interface{}) {
+	diagnostics.checks = append(diagnostics.checks, doctorCheck{name: name, status: status, message: fmt.Sprintf(format, args...)})
+}
+
+func (diagnostics *doctor) count(status doctorStatus) int {
+	result := 0
+	for _, check := range diagnostics.checks {
+		if check.status == status {
+			result++
+		}
+	}
+	return result
+}
+
+func (diagnostics *doctor) print() {
+	for _, check := range diagnostics.checks {
+		switch check.status {
+		case doctorOk:
+			log.Infof("[OK]      %s: %s", check.name, check.message)
+		case doctorWarning:
+			log.Warningf("[WARNING] %s: %s", check.name, check.message)
+		default:
+			log.Errorf("[ERROR]   %s: %s", check.name, check.message)
+		}
+	}
+	log.Infof("%v check(s) passed, %v warning(s), %v problem(s)", diagnostics.count(doctorOk), diagnostics.count(doctorWarning), diagnostics.count(doctorError))
+}
+
+// checkConfig reports used config file and invalid import profiles
+func (diagnostics *doctor) checkConfig() {
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		diagnostics.add("config file", doctorOk, "'%s'", configFile)
+	} else {
+		diagnostics.add("config file", doctorWarning, "no config file was found, defaults are used")
+	}
+
+	for _, name := range getImportProfileNames() {
+		if _, err := getImportProfile(name); err != nil {
+			diagnostics.add("import profile", doctorError, "%v", err)
+		}
+	}
+	for _, name := range getMediaTypeNames() {
+		if _, err := getMediaType(name); err != nil {
+			diagnostics.add("media type", doctorError, "%v", err)
+		}
+	}
+}
+
+// checkTool reports custom tool path which was not found (the tool falls back to $PATH) and the resolved tool path.
+// Purpose is added to the message if the tool was not found at all
+func (diagnostics *doctor) checkTool(name string, pathKey string, status doctorStatus, purpose string) (string, bool) {
+	customPath := viper.GetString(pathKey)
+	if customPath != "" {
+		expandedPath, err := expandToolPath(customPath)
+		if err == nil {
+			_, err = os.Stat(expandedPath)
+		}
+		if err != nil {
+			diagnostics.add(name, status, "'%s' path '%s' was not found, '%s' from $PATH is used: %v", pathKey, customPath, name, err)
+		}
+	}
+
+	toolPath, err := exec.LookPath(resolveToolPath(customPath, name))
+	if err != nil {
+		diagnostics.add(name, status, "%v, %s", err, purpose)
+		return "", false
+	}
+	diagnostics.add(name, doctorOk, "'%s'", toolPath)
+	return toolPath, true
+}
+
+// checkExifTool checks exiftool version and features used by the tool
+func (diagnostics *doctor) checkExifTool(exifTool string) {
+	output, err := diagnostics.execCommand(exifTool, "-ver").Output()
+	if err != nil {
+		diagnostics.add("exiftool version", doctorError, "unable to run exiftool: %v", err)
+		return
+	}
+	versionText := strings.TrimSpace(string(output))
+	version, err := strconv.ParseFloat(versionText, 64)
+	switch {
+	case err != nil:
+		diagnostics.add("exiftool version", doctorError, "unable to parse '%s' version", versionText)
+	case version < minExifToolVersion:
+		diagnostics.add("exiftool version", doctorError, "%s, at least %.2f is required", versionText, minExifToolVersion)
+	default:
+		diagnostics.add("exiftool version", doctorOk, "%s", versionText)
+	}
+
+	// unknown options are ignored by exiftool, so the support is derived from the version
+	switch {
+	case err != nil:
+		diagnostics.add("exiftool QuickTimeUTC", doctorWarning, "unknown, the version was not recognized")
+	case version < minQuickTimeUTCVersion:
+		diagnostics.add("exiftool QuickTimeUTC", doctorError, "'-api QuickTimeUTC' requires exiftool %.2f or newer", minQuickTimeUTCVersion)
+	default:
+		diagnostics.add("exiftool QuickTimeUTC", doctorOk, "supported")
+	}
+
+	output, err = diagnostics.execCommand(exifTool, "-listwf").Output()
+	if err != nil || !containsFold(strings.Fields(string(output)), "CR3") {
+		diagnostics.add("exiftool CR3 writing", doctorWarning, "CR3 files cannot be written, dates of Canon RAW files will not be fixed")
+	} else {
+		diagnostics.add("exiftool CR3 writing", doctorOk, "supported")
+	}
+}
+
+// checkDir checks that dir is writable and has free space. Missing target dirs are created by import commands
+func (diagnostics *doctor) checkDir(name string, dir string, mayBeMissing bool) {
+	label := fmt.Sprintf("%s '%s'", name, dir)
+	info, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err) && mayBeMissing:
+		diagnostics.add(label, doctorWarning, "does not exist and will be created")
+		return
+	case err != nil:
+		diagnostics.add(label, doctorError, "%v", err)
+		return
+	case !info.IsDir():
+		diagnostics.add(label, doctorError, "is not a directory")
+		return
+	}
+
+	file, err := os.CreateTemp(dir, ".media-tool-doctor-*")
+	if err != nil {
+		diagnostics.add(label, doctorError, "is not writable: %v", err)
+		return
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	free, total, err := mtp.GetDiskSpace(dir)
+	if err != nil {
+		diagnostics.add(label, doctorWarning, "writable, unable to check free space: %v", err)
+		return
+	}
+	margin, err := mtp.ParseSize(viper.GetString(cfgImportFreeSpaceMargin))
+	if err == nil && free < margin {
+		diagnostics.add(label, doctorWarning, "writable, %s free of %s is less than '%s' margin",
+			mtp.SizeToLabel(free), mtp.SizeToLabel(total), cfgImportFreeSpaceMargin)
+		return
+	}
+	diagnostics.add(label, doctorOk, "writable, %s free of %s", mtp.SizeToLabel(free), mtp.SizeToLabel(total))
+}
+
+// profileTargetDirs returns sorted target dirs of built-in and configured import profiles
+func (diagnostics *doctor) profileTargetDirs() []string {
+	dirs := make(map[string]bool)
+	for _, name := range getImportProfileNames() {
+		profile, err := getImportProfile(name)
+		if err != nil {
+			continue
+		}
+		dir := profile.TargetDir
+		if profile.targetDirKey != "" && viper.GetString(profile.targetDirKey) != "" {
+			dir = viper.GetString(profile.targetDirKey)
+		}
+		if dir != "" {
+			dirs[dir] = true
+		}
+	}
+
+	result := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		result = append(result, dir)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// checkDevices initializes configured devices source
+func (diagnostics *doctor) checkDevices() {
+	sourceType := viper.GetString(cfgImportSource)
+	source, err := diagnostics.newSource(sourceType, viper.GetStringSlice(cfgImportMountDirs))
+	if err == nil {
+		err = source.Init()
+	}
+	if err != nil {
+		diagnostics.add("devices source", doctorError, "'%s' source is not available: %v", sourceType, err)
+		return
+	}
+	defer source.Destroy()
+
+	diagnostics.add("devices source", doctorOk, "'%s', %v device(s) connected", source.GetName(), source.GetDeviceCount())
+}
+
+func containsFold(values []string, value string) bool {
+	for _, item := range values {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
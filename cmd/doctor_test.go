@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+// fakeExifToolForDoctor writes a script which prints the version and lists writable extensions
+func fakeExifToolForDoctor(t *testing.T, version string, writable string) string {
+	path := filepath.Join(t.TempDir(), "exiftool")
+	script := "#!/bin/sh\n" +
+		"case \"$1\" in\n" +
+		"  -listwf) echo 'Writable file extensions:'; echo '  " + writable + "' ;;\n" +
+		"  *) echo '" + version + "' ;;\n" +
+		"esac\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0755))
+
+	viper.Set(cfgExifToolPath, path)
+	t.Cleanup(func() { viper.Set(cfgExifToolPath, "") })
+	return path
+}
+
+func findDoctorCheck(t *testing.T, diagnostics *doctor, name string) doctorCheck {
+	for _, check := range diagnostics.checks {
+		if check.name == name {
+			return check
+		}
+	}
+	require.Failf(t, "check was not found", "'%s' check was not found in %v", name, diagnostics.checks)
+	return doctorCheck{}
+}
+
+func TestDoctor_CheckExifTool(t *testing.T) {
+	r := require.New(t)
+	exifTool := fakeExifToolForDoctor(t, "12.76", "360 3GP CR2 CR3 JPG MP4")
+
+	diagnostics := newDoctor()
+	toolPath, found := diagnostics.checkTool("exiftool", cfgExifToolPath, doctorError, "")
+	r.True(found)
+	diagnostics.checkExifTool(toolPath)
+
+	r.Equal(exifTool, toolPath)
+	r.Equal(0, diagnostics.count(doctorError))
+	r.Equal(0, diagnostics.count(doctorWarning))
+	r.Equal("12.76", findDoctorCheck(t, diagnostics, "exiftool version").message)
+	r.Equal("supported", findDoctorCheck(t, diagnostics, "exiftool QuickTimeUTC").message)
+}
+
+func TestDoctor_CheckExifToolOldVersion(t *testing.T) {
+	r := require.New(t)
+	exifTool := fakeExifToolForDoctor(t, "11.01", "JPG MP4")
+
+	diagnostics := newDoctor()
+	diagnostics.checkExifTool(exifTool)
+
+	version := findDoctorCheck(t, diagnostics, "exiftool version")
+	r.Equal(doctorError, version.status)
+	r.Equal("11.01, at least 12.00 is required", version.message)
+	r.Equal(doctorOk, findDoctorCheck(t, diagnostics, "exiftool QuickTimeUTC").status)
+	r.Equal(doctorWarning, findDoctorCheck(t, diagnostics, "exiftool CR3 writing").status)
+}
+
+func TestDoctor_CheckExifToolQuickTimeUTC(t *testing.T) {
+	tests := []struct {
+		version string
+		status  doctorStatus
+		message string
+	}{
+		{"10.10", doctorOk, "supported"},
+		{"10.09", doctorError, "'-api QuickTimeUTC' requires exiftool 10.10 or newer"},
+		{"unknown", doctorWarning, "unknown, the version was not recognized"},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			exifTool := fakeExifToolForDoctor(t, test.version, "JPG MP4")
+
+			diagnostics := newDoctor()
+			diagnostics.checkExifTool(exifTool)
+
+			check := findDoctorCheck(t, diagnostics, "exiftool QuickTimeUTC")
+			assert.Equal(t, test.status, check.status)
+			assert.Equal(t, test.message, check.message)
+		})
+	}
+}
+
+func TestDoctor_CheckToolMissingCustomPath(t *testing.T) {
+	viper.Set(cfgFfmpegPath, "$APP_DIR/missing/ffmpeg")
+	t.Cleanup(func() { viper.Set(cfgFfmpegPath, "") })
+
+	diagnostics := newDoctor()
+	diagnostics.checkTool("ffmpeg", cfgFfmpegPath, doctorError, "")
+
+	check := diagnostics.checks[0]
+	assert.Equal(t, doctorError, check.status)
+	assert.Contains(t, check.message, "'ffmpeg.path' path '$APP_DIR/missing/ffmpeg' was not found, 'ffmpeg' from $PATH is used")
+}
+
+func TestDoctor_CheckDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("text"), 0644))
+
+	tests := []struct {
+		name         string
+		dir          string
+		mayBeMissing bool
+		status       doctorStatus
+	}{
+		{"writable", dir, false, doctorOk},
+		{"missing target", filepath.Join(dir, "missing"), true, doctorWarning},
+		{"missing temp", filepath.Join(dir, "missing"), false, doctorError},
+		{"file", file, true, doctorError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diagnostics := newDoctor()
+			diagnostics.checkDir("dir", test.dir, test.mayBeMissing)
+
+			require.Len(t, diagnostics.checks, 1)
+			assert.Equal(t, test.status, diagnostics.checks[0].status, diagnostics.checks[0].message)
+		})
+	}
+
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 1)
+}
+
+func TestDoctor_CheckDevices(t *testing.T) {
+	diagnostics := newDoctor()
+	diagnostics.newSource = func(sourceType string, mountDirs []string) (mtp.Source, error) {
+		return nil, errors.New("'wpd' source is supported on Windows only")
+	}
+	viper.Set(cfgImportSource, mtp.SourceWpd)
+	t.Cleanup(func() { viper.Set(cfgImportSource, mtp.SourceAuto) })
+
+	diagnostics.checkDevices()
+
+	assert.Equal(t, []doctorCheck{{"devices source", doctorError, "'wpd' source is not available: 'wpd' source is supported on Windows only"}}, diagnostics.checks)
+}
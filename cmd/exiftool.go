@@ -21,8 +21,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -36,6 +36,8 @@ type exifToolWrapper struct {
 	defaultArgs []string
 	args        exifToolArgs
 	execCommand func(name string, args ...string) *exec.Cmd
+	// process is exiftool started in '-stay_open' mode, it is shared by all commands
+	process *exifToolProcess
 }
 
 type exifToolArgs struct {
@@ -63,45 +65,102 @@ func getExifTool() *exifToolWrapper {
 }
 
 func (tool *exifToolWrapper) initCmd() {
-	customPath := viper.GetString(cfgExifToolPath)
-	if customPath != "" {
-
-		if strings.Contains(customPath, "$APP_DIR") {
-			ex, err := os.Executable()
-			if err != nil {
-				log.Infof("Unable to find custom exiftool: '%s'. Trying to use '%s' from $PATH", err, tool.cmd)
-				return
-			}
-			customPath = strings.ReplaceAll(customPath, "$APP_DIR", filepath.Dir(ex))
-			customPath, err = filepath.Abs(customPath)
-			if err != nil {
-				log.Infof("Unable to find custom exiftool: '%s'. Trying to use '%s' from $PATH", err, tool.cmd)
-				return
-			}
-			if _, err := os.Stat(customPath); os.IsNotExist(err) {
-				log.Infof("Unable to find custom exiftool: '%s'. Trying to use '%s' from $PATH", err, tool.cmd)
-				return
-			}
+	tool.cmd = resolveToolPath(viper.GetString(cfgExifToolPath), tool.cmd)
+}
+
+// exec runs exiftool with current args. Its output is printed except summary lines which are returned as the result
+func (tool *exifToolWrapper) exec() (exifToolResult, error) {
+	output, err := tool.run(func(line string) {
+		if !isExifToolSummary(line) {
+			fmt.Println(line)
 		}
+	})
+	if err == nil && output.status != 0 {
+		err = fmt.Errorf("exit status %v", output.status)
+	}
+	return parseExifToolOutput(output), err
+}
 
-		tool.cmd = customPath
+// execOutput runs exiftool and returns its standard output instead of printing it
+func (tool *exifToolWrapper) execOutput() (string, error) {
+	output, err := tool.run(nil)
+	for _, line := range output.stderr {
+		log.Debugf("ExifTool: %s", line)
+	}
+	if err == nil && output.status != 0 {
+		err = fmt.Errorf("exit status %v", output.status)
 	}
+	return strings.Join(append(output.stdout, ""), "\n"), err
 }
 
-func (tool *exifToolWrapper) exec() {
-	cmd := tool.execCommand(tool.cmd, tool.args.args...)
+// run sends current args to exiftool process. The process is started on the first call and restarted if it was terminated
+func (tool *exifToolWrapper) run(onStdout func(line string)) (exifToolOutput, error) {
+	log.Debugf("ExifTool command: '%s'\n", strings.Join(append([]string{tool.cmd}, tool.args.args...), " "))
 
-	log.Debugf("ExifTool command: '%s'\n", cmd.String())
+	for _, arg := range tool.args.args {
+		if strings.HasPrefix(arg, "-execute") {
+			break
+		}
+		if !isArgFileLine(arg) {
+			log.Debugf("'%s' argument can not be passed to the running exiftool process, a separate process is started", arg)
+			return runExifToolOnce(tool.execCommand, tool.cmd, tool.args.args, onStdout)
+		}
+	}
+
+	if tool.process == nil {
+		process, err := startExifToolProcess(tool.execCommand, tool.cmd)
+		if err != nil {
+			return exifToolOutput{}, err
+		}
+		tool.process = process
+	}
+
+	output, err := tool.process.run(tool.args.args, onStdout)
+	if err != nil {
+		tool.close()
+	}
+	return output, err
+}
+
+// close stops exiftool process. A new process is started by the next command
+func (tool *exifToolWrapper) close() {
+	if tool.process == nil {
+		return
+	}
+	if err := tool.process.close(); err != nil {
+		log.Debugf("ExifTool process exit error: '%s'", err)
+	}
+	tool.process = nil
+}
 
-	cmd.Stdout = os.Stdout
-	if verbose {
-		cmd.Stderr = os.Stderr
+// closeExifTool stops shared exiftool process if it was started
+func closeExifTool() {
+	if exifToolObj != nil {
+		exifToolObj.close()
 	}
+}
 
-	err := cmd.Run()
+// formatDate formats the date by exiftool date format, e.g. naming with strftime codes which are not rendered natively.
+// Wall clock of the date is formatted as is
+func (tool *exifToolWrapper) formatDate(format string, date time.Time) (string, error) {
+	f, err := os.CreateTemp("", "media-tool-date-*")
 	if err != nil {
-		log.Warningf("ExifTool exec error: '%s'", err)
+		return "", err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	// exiftool prints file dates in the local time zone
+	local := time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), 0, time.Local)
+	if err := os.Chtimes(f.Name(), local, local); err != nil {
+		return "", err
 	}
+
+	toolArgs := tool.newReadArgs()
+	toolArgs.add("-s3", "-d", format, "-FileModifyDate")
+	toolArgs.src(f.Name())
+	output, err := tool.execOutput()
+	return strings.TrimRight(output, "\r\n"), err
 }
 
 func (tool *exifToolWrapper) newArgs() *exifToolArgs {
@@ -121,23 +180,18 @@ func (toolArgs *exifToolArgs) src(dirOrFilepath string) {
 	toolArgs.add(dirOrFilepath)
 }
 
-func (toolArgs *exifToolArgs) forImages() {
-	toolArgs.add("-ext", "jpg")
-	toolArgs.add("-ext", "nef")
-	toolArgs.add("-ext", "cr2")
-	toolArgs.add("-ext", "cr3")
-}
-
-func (toolArgs *exifToolArgs) forVideoMp4() {
-	toolArgs.add("-ext", "mp4")
-}
-
-func (toolArgs *exifToolArgs) forVideoLrv() {
-	toolArgs.add("-ext", "LRV")
-}
-
-func (toolArgs *exifToolArgs) forVideoAvchd() {
-	toolArgs.add("-ext", "mts")
+// forMedia limits processed files to extensions of media types, see 'media.types' configuration
+func (toolArgs *exifToolArgs) forMedia(names ...string) error {
+	for _, name := range names {
+		media, err := getMediaType(name)
+		if err != nil {
+			return err
+		}
+		for _, ext := range media.Extensions {
+			toolArgs.add("-ext", ext)
+		}
+	}
+	return nil
 }
 
 func (toolArgs *exifToolArgs) forDateFormat(dateFormat string) {
@@ -148,6 +202,10 @@ func (toolArgs *exifToolArgs) changeTag(tagName string, tagValue string) {
 	toolArgs.add(fmt.Sprintf("-%s<%s", tagName, tagValue))
 }
 
+func (toolArgs *exifToolArgs) setTag(tagName string, value string) {
+	toolArgs.add(fmt.Sprintf("-%s=%s", tagName, value))
+}
+
 func (toolArgs *exifToolArgs) changeFileDate(tagValue string) {
 	//File:
 	toolArgs.changeTag("FileModifyDate", tagValue)
@@ -170,6 +228,14 @@ func (toolArgs *exifToolArgs) changeMp4Date(tagValue string) {
 	toolArgs.changeTag("MediaModifyDate", tagValue)
 }
 
+func (toolArgs *exifToolArgs) copyMp4Dates(srcFile string) {
+	toolArgs.add("-tagsFromFile", srcFile)
+	//quicktime:
+	toolArgs.add("-CreateDate", "-ModifyDate", "-TrackCreateDate", "-TrackModifyDate", "-MediaCreateDate", "-MediaModifyDate")
+	//File:
+	toolArgs.add("-FileModifyDate", "-FileCreateDate")
+}
+
 func (toolArgs *exifToolArgs) cleanTag(tagName string) {
 	toolArgs.add(fmt.Sprintf("-%s=", tagName))
 }
@@ -0,0 +1,224 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// exifToolProcess is exiftool started in '-stay_open' mode. Commands are read from its stdin, each command is
+// finished by '-executeNUM' and its output is finished by '{readyNUM}' line
+type exifToolProcess struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  chan string
+	stderr  chan string
+	counter int
+}
+
+// exifToolOutput is output of a single exiftool command
+type exifToolOutput struct {
+	stdout []string
+	stderr []string
+	// status is exiftool exit status of the command: 0 - success, 1 - error, 2 - no files matched conditions
+	status int
+}
+
+func startExifToolProcess(execCommand func(name string, args ...string) *exec.Cmd, name string) (*exifToolProcess, error) {
+	cmd := execCommand(name, "-stay_open", "True", "-@", "-")
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &exifToolProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: readLines(stdout),
+		stderr: readLines(stderr),
+	}, nil
+}
+
+// readLines reads lines of the stream in background. Channel is closed when the stream is closed
+func readLines(r io.Reader) chan string {
+	lines := make(chan string, 64)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			lines <- strings.TrimRight(scanner.Text(), "\r")
+		}
+	}()
+	return lines
+}
+
+// run sends command args to exiftool and waits for its output. Every stdout line is passed to onStdout (if set)
+func (process *exifToolProcess) run(args []string, onStdout func(line string)) (exifToolOutput, error) {
+	process.counter++
+	ready := fmt.Sprintf("{ready%v}", process.counter)
+	done := fmt.Sprintf("{done%v}", process.counter)
+
+	var command strings.Builder
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-execute") {
+			return exifToolOutput{}, fmt.Errorf("'%s' argument is not supported, run commands one by one", arg)
+		}
+		if !isArgFileLine(arg) {
+			return exifToolOutput{}, fmt.Errorf("argument '%s' can not be passed as argfile line", arg)
+		}
+		command.WriteString(arg)
+		command.WriteByte('\n')
+	}
+	// -echo4 prints the status to stderr after the command is processed
+	fmt.Fprintf(&command, "-echo4\n%s${status}\n-execute%v\n", done, process.counter)
+	if _, err := io.WriteString(process.stdin, command.String()); err != nil {
+		return exifToolOutput{}, fmt.Errorf("unable to send exiftool command: %w", err)
+	}
+
+	// stdout and stderr are read together, otherwise exiftool blocks on writing to the stream which is not read
+	output := exifToolOutput{stdout: make([]string, 0), stderr: make([]string, 0)}
+	stdout, stderr := process.stdout, process.stderr
+	for stdout != nil || stderr != nil {
+		select {
+		case line, open := <-stdout:
+			switch {
+			case !open:
+				return output, fmt.Errorf("exiftool process was terminated")
+			case line == ready:
+				stdout = nil
+			default:
+				output.stdout = append(output.stdout, line)
+				if onStdout != nil {
+					onStdout(line)
+				}
+			}
+		case line, open := <-stderr:
+			if !open {
+				return output, fmt.Errorf("exiftool process was terminated")
+			}
+			if status, found := strings.CutPrefix(line, done); found {
+				output.status = parseExifToolStatus(status, output.stderr)
+				stderr = nil
+			} else {
+				output.stderr = append(output.stderr, line)
+			}
+		}
+	}
+	return output, nil
+}
+
+// isArgFileLine checks whether the arg can be passed as '-@' argfile line: exiftool trims leading and trailing
+// whitespace of each line and skips lines started by '#'
+func isArgFileLine(arg string) bool {
+	return !strings.ContainsAny(arg, "\r\n") && strings.TrimSpace(arg) == arg && !strings.HasPrefix(arg, "#")
+}
+
+// runExifToolOnce runs a separate exiftool process with args passed by its command line. It is used for args
+// which can not be passed as argfile lines, e.g. file names with leading spaces
+func runExifToolOnce(execCommand func(name string, args ...string) *exec.Cmd, name string, args []string, onStdout func(line string)) (exifToolOutput, error) {
+	cmd := execCommand(name, args...)
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return exifToolOutput{}, err
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return exifToolOutput{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return exifToolOutput{}, err
+	}
+
+	output := exifToolOutput{stdout: make([]string, 0), stderr: make([]string, 0)}
+	stdout, stderr := readLines(stdoutPipe), readLines(stderrPipe)
+	for stdout != nil || stderr != nil {
+		select {
+		case line, open := <-stdout:
+			if !open {
+				stdout = nil
+				continue
+			}
+			output.stdout = append(output.stdout, line)
+			if onStdout != nil {
+				onStdout(line)
+			}
+		case line, open := <-stderr:
+			if !open {
+				stderr = nil
+				continue
+			}
+			output.stderr = append(output.stderr, line)
+		}
+	}
+
+	err = cmd.Wait()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		output.status = exitErr.ExitCode()
+		return output, nil
+	}
+	return output, err
+}
+
+// parseExifToolStatus parses command status. Status is guessed from error messages if exiftool does not support '${status}'
+func parseExifToolStatus(value string, stderr []string) int {
+	if status, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		return status
+	}
+	for _, line := range stderr {
+		if strings.HasPrefix(line, "Error") {
+			return 1
+		}
+	}
+	return 0
+}
+
+// close asks exiftool to exit and waits for it
+func (process *exifToolProcess) close() error {
+	io.WriteString(process.stdin, "-stay_open\nFalse\n")
+	process.stdin.Close()
+
+	// output is drained so exiftool is not blocked on writing
+	drained := make(chan bool)
+	go func() {
+		for range process.stderr {
+		}
+		drained <- true
+	}()
+	for range process.stdout {
+	}
+	<-drained
+	return process.cmd.Wait()
+}
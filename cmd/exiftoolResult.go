@@ -0,0 +1,150 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// exifToolCountPattern matches exiftool summary lines, e.g. '    2 image files updated'
+var exifToolCountPattern = regexp.MustCompile(`^\s*(\d+) (image files|files|output files|directories) (.+)$`)
+
+// exifToolProgressPattern matches file headers printed with '-progress' option, e.g. '======== a.jpg [1/3]'
+var exifToolProgressPattern = regexp.MustCompile(`^======== (.+?)(?: \[\d+/\d+\])?$`)
+
+// exifToolMessage is a warning or an error reported by exiftool. File is empty for messages not related to a file
+type exifToolMessage struct {
+	File    string
+	Message string
+}
+
+// exifToolResult is parsed output of exiftool commands
+type exifToolResult struct {
+	Updated   int
+	Unchanged int
+	Created   int
+	Failed    int
+	// Files are processed files reported by '-progress' option
+	Files    []string
+	Warnings []exifToolMessage
+	Errors   []exifToolMessage
+}
+
+// parseExifToolOutput parses summary, progress lines and messages of a single command
+func parseExifToolOutput(output exifToolOutput) exifToolResult {
+	result := exifToolResult{}
+	for _, line := range output.stdout {
+		if match := exifToolProgressPattern.FindStringSubmatch(line); match != nil {
+			result.Files = append(result.Files, match[1])
+			continue
+		}
+
+		match := exifToolCountPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		count, _ := strconv.Atoi(match[1])
+		switch {
+		case match[2] == "directories":
+		case match[3] == "updated":
+			result.Updated += count
+		case match[3] == "unchanged":
+			result.Unchanged += count
+		case match[3] == "created" || match[3] == "copied":
+			result.Created += count
+		case strings.Contains(match[3], "due to errors") || match[3] == "could not be read":
+			result.Failed += count
+		}
+	}
+
+	for _, line := range output.stderr {
+		if message, found := strings.CutPrefix(line, "Warning: "); found {
+			result.Warnings = append(result.Warnings, parseExifToolMessage(message))
+		} else if message, found := strings.CutPrefix(line, "Error: "); found {
+			result.Errors = append(result.Errors, parseExifToolMessage(message))
+		} else if line != "" {
+			log.Debugf("ExifTool: %s", line)
+		}
+	}
+	return result
+}
+
+// isExifToolSummary checks whether stdout line is a summary line which is replaced by printSummary
+func isExifToolSummary(line string) bool {
+	return exifToolCountPattern.MatchString(line)
+}
+
+// parseExifToolMessage splits 'Message - file' text
+func parseExifToolMessage(text string) exifToolMessage {
+	if i := strings.LastIndex(text, " - "); i >= 0 {
+		return exifToolMessage{File: text[i+3:], Message: text[:i]}
+	}
+	return exifToolMessage{Message: text}
+}
+
+func (message exifToolMessage) String() string {
+	if message.File == "" {
+		return message.Message
+	}
+	return fmt.Sprintf("'%s': %s", message.File, message.Message)
+}
+
+// add sums results of several commands
+func (result *exifToolResult) add(other exifToolResult) {
+	result.Updated += other.Updated
+	result.Unchanged += other.Unchanged
+	result.Created += other.Created
+	result.Failed += other.Failed
+	result.Files = append(result.Files, other.Files...)
+	result.Warnings = append(result.Warnings, other.Warnings...)
+	result.Errors = append(result.Errors, other.Errors...)
+}
+
+// hasFailures checks whether exiftool was unable to process some files
+func (result exifToolResult) hasFailures() bool {
+	return result.Failed > 0 || len(result.Errors) > 0
+}
+
+func (result exifToolResult) printSummary() {
+	for _, warning := range result.Warnings {
+		if strings.HasPrefix(warning.Message, "[minor]") {
+			log.Debugf("ExifTool warning: %v", warning)
+		} else {
+			log.Warningf("ExifTool warning: %v", warning)
+		}
+	}
+	for _, err := range result.Errors {
+		log.Errorf("ExifTool error: %v", err)
+	}
+	log.Infof("ExifTool: %v file(s) updated, %v unchanged, %v created, %v failed", result.Updated, result.Unchanged, result.Created, result.Failed)
+}
+
+// report prints summary of exiftool commands. Returns error if exiftool failed or was unable to process some files
+func (result exifToolResult) report(err error) error {
+	result.printSummary()
+	if err != nil {
+		return fmt.Errorf("exiftool failed: %w", err)
+	}
+	if result.hasFailures() {
+		return fmt.Errorf("exiftool was unable to process %v file(s)", max(result.Failed, len(result.Errors)))
+	}
+	return nil
+}
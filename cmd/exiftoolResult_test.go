@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExifToolOutput(t *testing.T) {
+	output := exifToolOutput{
+		stdout: []string{
+			"======== ./2024/a.jpg [1/3]",
+			"======== ./2024/b.jpg [2/3]",
+			"'./2024/b.jpg' --> './2024/IMG_20240518_103015.jpg'",
+			"======== ./2024/c.mp4 [3/3]",
+			"    1 directories scanned",
+			"    1 directories created",
+			"    1 image files updated",
+			"    1 image files unchanged",
+			"    1 files weren't updated due to errors",
+		},
+		stderr: []string{
+			"Warning: [minor] Unrecognized MakerNotes - ./2024/a.jpg",
+			"Error: 'CreateDate' not defined - ./2024/c.mp4",
+			"Error: File not found",
+		},
+		status: 1,
+	}
+
+	result := parseExifToolOutput(output)
+
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Unchanged)
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, []string{"./2024/a.jpg", "./2024/b.jpg", "./2024/c.mp4"}, result.Files)
+	assert.Equal(t, []exifToolMessage{{File: "./2024/a.jpg", Message: "[minor] Unrecognized MakerNotes"}}, result.Warnings)
+	assert.Equal(t, []exifToolMessage{
+		{File: "./2024/c.mp4", Message: "'CreateDate' not defined"},
+		{Message: "File not found"},
+	}, result.Errors)
+	assert.Equal(t, "'./2024/c.mp4': 'CreateDate' not defined", result.Errors[0].String())
+}
+
+func TestIsExifToolSummary(t *testing.T) {
+	assert.True(t, isExifToolSummary("    2 image files updated"))
+	assert.True(t, isExifToolSummary("    1 files weren't updated due to errors"))
+	assert.False(t, isExifToolSummary("======== a.jpg [1/3]"))
+	assert.False(t, isExifToolSummary("'a.jpg' --> 'b.jpg'"))
+}
+
+func TestExifToolResult_Report(t *testing.T) {
+	result := exifToolResult{Updated: 2}
+	result.add(exifToolResult{Unchanged: 1, Files: []string{"a.jpg"}})
+
+	assert.NoError(t, result.report(nil))
+	assert.Equal(t, exifToolResult{Updated: 2, Unchanged: 1, Files: []string{"a.jpg"}}, result)
+
+	result.add(exifToolResult{Failed: 1, Errors: []exifToolMessage{{File: "b.jpg", Message: "failed"}}})
+	assert.EqualError(t, result.report(nil), "exiftool was unable to process 1 file(s)")
+	assert.EqualError(t, result.report(errors.New("exit status 1")), "exiftool failed: exit status 1")
+}
@@ -110,31 +110,203 @@ func TestExifToolArgs_Add(t *testing.T) {
 	assert.Equal(t, "arg3", sut.args[4])
 }
 
+// stayOpenExifToolScript emulates exiftool '-stay_open' protocol. Args of each command are collected to $args,
+// the command script prints the command output and may change its $status
+func stayOpenExifToolScript(command string) string {
+	return `
+while IFS= read -r line; do
+  case "$line" in
+    -stay_open) read -r flag; [ "$flag" = "False" ] && exit 0 ;;
+    -echo4) read -r done ;;
+    -execute*)
+      n=${line#-execute}
+      status=0
+` + command + `
+      echo "{ready$n}"
+      echo "{done$n}$status" >&2
+      args="" ;;
+    *) args="$args $line" ;;
+  esac
+done
+`
+}
+
+// fakeExifToolScript prints received args of each command, fails or updates a file according to args
+var fakeExifToolScript = stayOpenExifToolScript(`
+      echo "args:$args"
+      case "$args" in
+        *-fail*) status=1; echo "    1 files weren't updated due to errors"; echo "Error: failed - a.jpg" >&2 ;;
+        *-update*) echo "======== a.jpg [1/1]"; echo "    1 image files updated" ;;
+        *-noisy*) i=0; while [ $i -lt 5000 ]; do echo "Warning: minor problem $i - a.jpg" >&2; i=$((i+1)); done ;;
+      esac`)
+
+// fakeOnceExifToolScript prints args of exiftool started without '-stay_open' mode
+const fakeOnceExifToolScript = `
+echo "once:$*"
+case "$*" in
+  *-fail*) echo "Error: failed - a.jpg" >&2; exit 1 ;;
+  *-update*) echo "    1 image files updated" ;;
+esac`
+
+func newFakeExifTool(starts *int, capturedArgs *[]string) *exifToolWrapper {
+	return &exifToolWrapper{
+		cmd:         "test-exiftool",
+		defaultArgs: []string{"-v0", "-progress"},
+		execCommand: func(name string, args ...string) *exec.Cmd {
+			*starts++
+			*capturedArgs = args
+			if len(args) == 0 || args[0] != "-stay_open" {
+				return exec.Command("sh", append([]string{"-c", fakeOnceExifToolScript, name}, args...)...)
+			}
+			return exec.Command("sh", "-c", fakeExifToolScript)
+		},
+	}
+}
+
 func TestExifToolWrapper_Exec(t *testing.T) {
-	var capturedCmd string
+	var starts int
 	var capturedArgs []string
+	tool := newFakeExifTool(&starts, &capturedArgs)
+	defer tool.close()
 
-	// Create test instance with mock command
-	mockExecCommand := func(name string, args ...string) *exec.Cmd {
-		capturedCmd = name
-		capturedArgs = args
-		return exec.Command("echo", "test") // Use a harmless command
-	}
+	tool.newArgs()
+	tool.args.add("-test", "value")
+	output, err := tool.execOutput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "args: -v0 -progress -test value\n", output)
+	assert.Equal(t, []string{"-stay_open", "True", "-@", "-"}, capturedArgs, "Incorrect arguments passed")
+
+	// the same process executes next commands
+	tool.newArgs()
+	tool.args.add("-next")
+	output, err = tool.execOutput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "args: -v0 -progress -next\n", output)
+	assert.Equal(t, 1, starts)
+}
+
+func TestExifToolWrapper_ExecStatus(t *testing.T) {
+	var starts int
+	var capturedArgs []string
+	tool := newFakeExifTool(&starts, &capturedArgs)
+	defer tool.close()
+
+	tool.newArgs()
+	tool.args.add("-fail")
+	output, err := tool.run(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, output.status)
+	assert.Equal(t, []string{"Error: failed - a.jpg"}, output.stderr)
+
+	result, err := tool.exec()
+	assert.EqualError(t, err, "exit status 1")
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, []exifToolMessage{{File: "a.jpg", Message: "failed"}}, result.Errors)
+}
+
+func TestExifToolWrapper_ExecResult(t *testing.T) {
+	var starts int
+	var capturedArgs []string
+	tool := newFakeExifTool(&starts, &capturedArgs)
+	defer tool.close()
+
+	tool.newArgs()
+	tool.args.add("-update")
+	result, err := tool.exec()
+
+	assert.NoError(t, err)
+	assert.Equal(t, exifToolResult{Updated: 1, Files: []string{"a.jpg"}}, result)
+}
 
+func TestExifToolWrapper_ExecLargeStderr(t *testing.T) {
+	var starts int
+	var capturedArgs []string
+	tool := newFakeExifTool(&starts, &capturedArgs)
+	defer tool.close()
+
+	// stderr exceeds pipe buffer before '{ready1}' is written to stdout
+	tool.newArgs()
+	tool.args.add("-noisy")
+	output, err := tool.run(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, output.status)
+	assert.Len(t, output.stderr, 5000)
+	assert.Equal(t, []string{"args: -v0 -progress -noisy"}, output.stdout)
+}
+
+func TestExifToolWrapper_RestartsTerminatedProcess(t *testing.T) {
+	starts := 0
 	tool := &exifToolWrapper{
 		cmd:         "test-exiftool",
-		defaultArgs: []string{"-v0", "-progress"},
-		execCommand: mockExecCommand,
+		defaultArgs: []string{"-v0"},
+		execCommand: func(name string, args ...string) *exec.Cmd {
+			starts++
+			return exec.Command("echo", "test")
+		},
 	}
+
 	tool.newArgs()
-	tool.args.add("-test", "value")
+	_, err := tool.execOutput()
+	assert.Error(t, err)
+	assert.Nil(t, tool.process)
 
-	// Execute
-	tool.exec()
+	_, err = tool.execOutput()
+	assert.Error(t, err)
+	assert.Equal(t, 2, starts)
+}
 
-	// Verify command and arguments
-	assert.Equal(t, "test-exiftool", capturedCmd, "Incorrect command executed")
-	assert.Equal(t, []string{"-v0", "-progress", "-test", "value"}, capturedArgs, "Incorrect arguments passed")
+func TestIsArgFileLine(t *testing.T) {
+	assert.True(t, isArgFileLine("-FileName<CreateDate"))
+	assert.True(t, isArgFileLine("/media/My Video #1.MP4"))
+	assert.False(t, isArgFileLine("a\nb"))
+	assert.False(t, isArgFileLine(" a.jpg"))
+	assert.False(t, isArgFileLine("a.jpg\t"))
+	assert.False(t, isArgFileLine("#1.jpg"))
+}
+
+func TestExifToolWrapper_ExecFallsBackForArgFileLimits(t *testing.T) {
+	var starts int
+	var capturedArgs []string
+	tool := newFakeExifTool(&starts, &capturedArgs)
+	defer tool.close()
+
+	tool.newArgs()
+	tool.args.add("-update", " a.jpg")
+	result, err := tool.exec()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, []string{"-v0", "-progress", "-update", " a.jpg"}, capturedArgs, "args are passed by command line")
+
+	tool.newArgs()
+	tool.args.add("-fail", "#1.jpg")
+	result, err = tool.exec()
+	assert.EqualError(t, err, "exit status 1")
+	assert.Len(t, result.Errors, 1)
+
+	// other commands are still sent to the running process
+	tool.newArgs()
+	tool.args.add("-update", "a.jpg")
+	result, err = tool.exec()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 3, starts)
+	assert.Equal(t, 1, tool.process.counter)
+
+	tool.newArgs()
+	tool.args.add("-execute")
+	_, err = tool.exec()
+	assert.EqualError(t, err, "'-execute' argument is not supported, run commands one by one")
+}
+
+func TestParseExifToolStatus(t *testing.T) {
+	assert.Equal(t, 0, parseExifToolStatus("0", nil))
+	assert.Equal(t, 2, parseExifToolStatus("2", nil))
+	assert.Equal(t, 1, parseExifToolStatus("${status}", []string{"Warning: minor", "Error: File not found - a.jpg"}))
+	assert.Equal(t, 0, parseExifToolStatus("", []string{"Warning: minor"}))
 }
 
 func TestExifToolArgs_Recursively(t *testing.T) {
@@ -161,58 +333,33 @@ func TestExifToolArgs_Src(t *testing.T) {
 	assert.Equal(t, "some_path", sut.args[2])
 }
 
-func TestExifToolArgs_ForImages(t *testing.T) {
+func TestExifToolArgs_ForMedia(t *testing.T) {
 	sut := newExifTool().newArgs()
 
-	sut.forImages()
+	err := sut.forMedia("mp4", "LRV")
 
-	assert.Len(t, sut.args, 10)
-	assert.Equal(t, "-v0", sut.args[0])
-	assert.Equal(t, "-progress", sut.args[1])
-	assert.Equal(t, "-ext", sut.args[2])
-	assert.Equal(t, "jpg", sut.args[3])
-	assert.Equal(t, "-ext", sut.args[4])
-	assert.Equal(t, "nef", sut.args[5])
-	assert.Equal(t, "-ext", sut.args[6])
-	assert.Equal(t, "cr2", sut.args[7])
-	assert.Equal(t, "-ext", sut.args[8])
-	assert.Equal(t, "cr3", sut.args[9])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-v0", "-progress", "-ext", "mp4", "-ext", "lrv"}, sut.args)
 }
 
-func TestExifToolArgs_ForVideoMp4(t *testing.T) {
+func TestExifToolArgs_ForMediaIncludedTypes(t *testing.T) {
 	sut := newExifTool().newArgs()
 
-	sut.forVideoMp4()
+	err := sut.forMedia("images")
 
-	assert.Len(t, sut.args, 4)
-	assert.Equal(t, "-v0", sut.args[0])
-	assert.Equal(t, "-progress", sut.args[1])
-	assert.Equal(t, "-ext", sut.args[2])
-	assert.Equal(t, "mp4", sut.args[3])
-}
-
-func TestExifToolArgs_ForVideoLrv(t *testing.T) {
-	sut := newExifTool().newArgs()
-
-	sut.forVideoLrv()
-
-	assert.Len(t, sut.args, 4)
-	assert.Equal(t, "-v0", sut.args[0])
-	assert.Equal(t, "-progress", sut.args[1])
+	assert.NoError(t, err)
 	assert.Equal(t, "-ext", sut.args[2])
-	assert.Equal(t, "LRV", sut.args[3])
+	assert.Equal(t, "jpg", sut.args[3])
+	assert.Contains(t, sut.args, "heic")
+	assert.Contains(t, sut.args, "cr3")
+	assert.Contains(t, sut.args, "arw")
+	assert.Equal(t, 2+2*(len(mediaExtensions(mediaImage))+len(mediaExtensions(mediaRaw))), len(sut.args))
 }
 
-func TestExifToolArgs_ForVideoAvchd(t *testing.T) {
+func TestExifToolArgs_ForMediaUnknownType(t *testing.T) {
 	sut := newExifTool().newArgs()
 
-	sut.forVideoAvchd()
-
-	assert.Len(t, sut.args, 4)
-	assert.Equal(t, "-v0", sut.args[0])
-	assert.Equal(t, "-progress", sut.args[1])
-	assert.Equal(t, "-ext", sut.args[2])
-	assert.Equal(t, "mts", sut.args[3])
+	assert.EqualError(t, sut.forMedia("unknown"), "unknown 'unknown' media type")
 }
 
 func TestExifToolArgs_ForDateFormat(t *testing.T) {
@@ -359,18 +506,18 @@ func TestExifToolWrapper_ComplexUseCase1(t *testing.T) {
 	// Add multiple operations
 	sut.recursively()
 	sut.src("/path/to/files")
-	sut.forImages()
+	sut.forMedia("mp4", "avchd")
 	sut.changeTag("DateTime", "2023:01:01 12:00:00")
 	sut.cleanVendorTags()
 
-	// Verify all operations were added (2 default + 1 recursively + 1 src + 8 forImages + 1 changeTag + 10 cleanVendorTags)
-	assert.Len(t, sut.args, 23)
+	// Verify all operations were added (2 default + 1 recursively + 1 src + 6 forMedia + 1 changeTag + 10 cleanVendorTags)
+	assert.Len(t, sut.args, 21)
 	assert.Equal(t, "-v0", sut.args[0])
 	assert.Equal(t, "-progress", sut.args[1])
 	assert.Equal(t, "-r", sut.args[2])
 	assert.Equal(t, "/path/to/files", sut.args[3])
 	assert.Equal(t, "-ext", sut.args[4])
-	assert.Equal(t, "jpg", sut.args[5])
+	assert.Equal(t, "mp4", sut.args[5])
 
 	found := false
 	for _, arg := range sut.args {
@@ -389,11 +536,11 @@ func TestExifToolWrapper_ComplexUseCase2(t *testing.T) {
 	sut.add("-v0", "-progress")
 	sut.recursively()
 	sut.src("/test/path")
-	sut.forImages()
+	sut.forMedia("mp4", "avchd")
 	sut.changeTag("DateTime", "2023:01:01 12:00:00")
 
 	// Verify the command structure
 	assert.Equal(t, "exiftool", tool.cmd)
 	assert.Equal(t, []string{"-v0", "-progress"}, tool.defaultArgs)
-	assert.Len(t, sut.args, 15)
+	assert.Len(t, sut.args, 13)
 }
@@ -25,5 +25,6 @@ func newTestExifTool() *testExifToolWrapper {
 }
 
 func (testTool *testExifToolWrapper) clear() {
+	testTool.close()
 	exifToolObj = nil
 }
@@ -0,0 +1,138 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redrathnure/media-tool/cmd/gpmf"
+)
+
+const (
+	telemetryGpx = "gpx"
+	telemetryCsv = "csv"
+)
+
+var telemetryCsvEnabled bool
+
+// extractTelemetryCmd represents the extract telemetry command
+var extractTelemetryCmd = &cobra.Command{
+	Use:   "telemetry files...",
+	Short: "Extract GoPro telemetry",
+	Long: `Read GPMF telemetry of GoPro videos and write GPS track as '.gpx' file 
+	(and optionally motion sensors data as '.csv' file) next to each video`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		printCommandArgs(cmd, args)
+
+		formats := []string{telemetryGpx}
+		if telemetryCsvEnabled {
+			formats = append(formats, telemetryCsv)
+		}
+
+		failed := 0
+		for _, videoPath := range args {
+			if err := writeTelemetry(videoPath, videoPath, formats); err != nil {
+				log.Errorf("Unable to extract '%s' telemetry: %v", videoPath, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// validateTelemetryFormats checks telemetry file formats
+func validateTelemetryFormats(formats []string) error {
+	for _, format := range formats {
+		if format != telemetryGpx && format != telemetryCsv {
+			return fmt.Errorf("unknown '%s' telemetry format, '%s' or '%s' are expected", format, telemetryGpx, telemetryCsv)
+		}
+	}
+	return nil
+}
+
+// writeTelemetry extracts telemetry of the video and writes it next to the target video (with the same base name).
+// Videos without telemetry are skipped
+func writeTelemetry(videoPath string, targetVideo string, formats []string) error {
+	telemetry, err := gpmf.Extract(videoPath)
+	if errors.Is(err, gpmf.ErrNoTelemetry) {
+		log.Infof("'%s' has no telemetry", videoPath)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(targetVideo, filepath.Ext(targetVideo))
+	for _, format := range formats {
+		switch format {
+		case telemetryGpx:
+			if len(telemetry.GPS) == 0 {
+				log.Infof("'%s' has no GPS fixes", videoPath)
+				continue
+			}
+			err = writeTelemetryFile(base+".gpx", func(f *os.File) error {
+				return gpmf.WriteGPX(f, filepath.Base(base), telemetry.GPS)
+			})
+		case telemetryCsv:
+			if len(telemetry.Sensors) == 0 {
+				log.Infof("'%s' has no motion sensors data", videoPath)
+				continue
+			}
+			err = writeTelemetryFile(base+".csv", func(f *os.File) error {
+				return gpmf.WriteCSV(f, telemetry.Sensors)
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTelemetryFile(filePath string, write func(f *os.File) error) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return err
+	}
+	log.Infof("'%s' was written", filePath)
+	return nil
+}
+
+func init() {
+	extractCmd.AddCommand(extractTelemetryCmd)
+
+	extractTelemetryCmd.Flags().BoolVar(&telemetryCsvEnabled, "csv", false, "Also write accelerometer and gyroscope data as CSV file")
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTelemetryFormats(t *testing.T) {
+	assert.NoError(t, validateTelemetryFormats(nil))
+	assert.NoError(t, validateTelemetryFormats([]string{"gpx", "csv"}))
+	assert.EqualError(t, validateTelemetryFormats([]string{"gpx", "kml"}), "unknown 'kml' telemetry format, 'gpx' or 'csv' are expected")
+}
+
+func TestWriteTelemetry_NoTelemetry(t *testing.T) {
+	r := require.New(t)
+
+	videoPath := filepath.Join(t.TempDir(), "GX010001.MP4")
+	r.NoError(os.WriteFile(videoPath, testMovie(time.Minute), 0644))
+
+	r.NoError(writeTelemetry(videoPath, videoPath, []string{telemetryGpx, telemetryCsv}))
+
+	r.NoFileExists(filepath.Join(filepath.Dir(videoPath), "GX010001.gpx"))
+	r.NoFileExists(filepath.Join(filepath.Dir(videoPath), "GX010001.csv"))
+}
+
+func TestWriteTelemetry_InvalidVideo(t *testing.T) {
+	videoPath := filepath.Join(t.TempDir(), "GX010001.MP4")
+	require.NoError(t, os.WriteFile(videoPath, []byte("not a video"), 0644))
+
+	assert.Error(t, writeTelemetry(videoPath, videoPath, []string{telemetryGpx}))
+}
@@ -0,0 +1,145 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/redrathnure/media-tool/cmd/mp4"
+)
+
+const (
+	cfgFfmpegPath = "ffmpeg.path"
+)
+
+type ffmpegWrapper struct {
+	cmd         string
+	execCommand func(name string, args ...string) *exec.Cmd
+}
+
+var ffmpegObj *ffmpegWrapper
+
+func newFfmpeg() *ffmpegWrapper {
+	return &ffmpegWrapper{
+		cmd:         resolveToolPath(viper.GetString(cfgFfmpegPath), "ffmpeg"),
+		execCommand: exec.Command,
+	}
+}
+
+func getFfmpeg() *ffmpegWrapper {
+	if ffmpegObj == nil {
+		ffmpegObj = newFfmpeg()
+	}
+	return ffmpegObj
+}
+
+// concat joins videos into the target file without re-encoding (ffmpeg concat demuxer). Video and audio streams
+// are copied, GoPro telemetry track is copied too if the first video has it
+func (tool *ffmpegWrapper) concat(videos []string, target string) error {
+	list, err := os.CreateTemp(filepath.Dir(target), "concat-*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(list.Name())
+
+	_, err = list.WriteString(concatList(videos))
+	if closeErr := list.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	cmd := tool.execCommand(tool.cmd, concatArgs(list.Name(), telemetryStream(videos[0]), target)...)
+	log.Debugf("FFmpeg command: '%s'\n", cmd.String())
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg exec error: '%s' %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// concatList renders concat demuxer list of files
+func concatList(videos []string) string {
+	var builder strings.Builder
+	for _, video := range videos {
+		path, err := filepath.Abs(video)
+		if err != nil {
+			path = video
+		}
+		fmt.Fprintf(&builder, "file '%s'\n", strings.ReplaceAll(filepath.ToSlash(path), "'", `'\''`))
+	}
+	return builder.String()
+}
+
+// concatArgs are ffmpeg args which copy streams of listed files into the target. Negative telemetry stream means the stream is absent
+func concatArgs(listPath string, telemetryStream int, target string) []string {
+	args := []string{"-hide_banner", "-nostdin", "-loglevel", "error", "-f", "concat", "-safe", "0", "-i", listPath,
+		"-map", "0:v", "-map", "0:a?"}
+	if telemetryStream >= 0 {
+		args = append(args, "-map", fmt.Sprintf("0:%v", telemetryStream), "-copy_unknown", "-tag:d", "gpmd")
+	}
+	return append(args, "-c", "copy", "-map_metadata", "0", "-n", target)
+}
+
+// telemetryStream returns index of GoPro telemetry ('gpmd') track or -1 if the video has no such track
+func telemetryStream(videoPath string) int {
+	f, err := os.Open(videoPath)
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+
+	movie, err := mp4.ReadMovie(f)
+	if err != nil {
+		return -1
+	}
+	for i, track := range movie.Tracks() {
+		if track.HandlerType() == "meta" && track.SampleFormat() == "gpmd" {
+			return i
+		}
+	}
+	return -1
+}
+
+// readVideoDuration reads duration of MP4 (MOV) video
+func readVideoDuration(videoPath string) (time.Duration, error) {
+	f, err := os.Open(videoPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	movie, err := mp4.ReadMovie(f)
+	if err != nil {
+		return 0, err
+	}
+	return movie.Duration()
+}
+
+func init() {
+	viper.SetDefault(cfgFfmpegPath, "")
+}
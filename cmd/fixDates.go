@@ -30,10 +30,10 @@ var fixDatesCmd = &cobra.Command{
 	Long: `Reads dates from file name and put into Exif and QuickTime metadata attributes. 
 	files argument may be dir (process all files) or wildcards file names (process only matched files)`,
 	Args: cobra.RangeArgs(1, 1),
-	Run:  runFixDates,
+	Run:  exitOnError(runFixDates),
 }
 
-func runFixDates(cmd *cobra.Command, args []string) {
+func runFixDates(cmd *cobra.Command, args []string) error {
 	printCommandArgs(cmd, args)
 
 	files := extractPath(args, 0, ".")
@@ -48,16 +48,15 @@ func runFixDates(cmd *cobra.Command, args []string) {
 	imgArgs.changeFileDate("filename")
 	imgArgs.changeExifDate("filename")
 	imgArgs.changeMp4Date("filename")
-	//imgArgs.forImages()
-	//imgArgs.forVideoMp4()
+	//imgArgs.forMedia(mediaImage, mediaRaw, mediaVideo)
 	if recursively {
 		imgArgs.recursively()
 	}
 	imgArgs.src(files)
 
-	exifTool.exec()
-
 	// TODO dryRun?
+	result, err := exifTool.exec()
+	return result.report(err)
 }
 
 func init() {
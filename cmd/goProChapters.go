@@ -0,0 +1,262 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// goProFileNamePattern matches file names of HERO6 and later cameras: encoding ('GH' - AVC, 'GX' - HEVC,
+// 'GL' - low resolution preview), chapter and file number, e.g. 'GX020123.MP4' is the second chapter of 0123 recording
+var goProFileNamePattern = regexp.MustCompile(`(?i)^(G[HXL])(\d{2})(\d{4})\.\w+$`)
+
+// goProLegacyFileNamePattern matches file names of older cameras: 'GOPR0123.MP4' is the first chapter, 'GP010123.MP4' is the second one
+var goProLegacyFileNamePattern = regexp.MustCompile(`(?i)^(GOPR|GP(\d{2}))(\d{4})\.\w+$`)
+
+// previewSuffix is added to names of renamed '.LRV' previews by default gopro profile, e.g. 'VID_20240518_103015.preview.mp4'
+const previewSuffix = ".preview"
+
+// goProFileName is parsed GoPro file name
+type goProFileName struct {
+	// Encoding is 'GH', 'GX', 'GL' or 'GOPR' for cameras before HERO6
+	Encoding string
+	// Chapter starts from 1
+	Chapter int
+	// Number is file number shared by all chapters and previews of a recording
+	Number string
+}
+
+// goProChapter is a downloaded (or device) file of a recording
+type goProChapter struct {
+	path string
+	name goProFileName
+	rule importRule
+}
+
+// goProRecording groups chapters and previews of one recording
+type goProRecording struct {
+	chapters []goProChapter
+	// lead is the first chapter video, its date is used for all chapters
+	lead string
+}
+
+func parseGoProFileName(fileName string) (goProFileName, bool) {
+	if match := goProFileNamePattern.FindStringSubmatch(fileName); match != nil {
+		chapter, _ := strconv.Atoi(match[2])
+		return goProFileName{Encoding: strings.ToUpper(match[1]), Chapter: chapter, Number: match[3]}, chapter > 0
+	}
+	if match := goProLegacyFileNamePattern.FindStringSubmatch(fileName); match != nil {
+		chapter := 1
+		if match[2] != "" {
+			next, _ := strconv.Atoi(match[2])
+			chapter += next
+		}
+		return goProFileName{Encoding: "GOPR", Chapter: chapter, Number: match[3]}, true
+	}
+	return goProFileName{}, false
+}
+
+// groupGoProRecordings groups files moved by chapter rules by directory and GoPro file number
+func (profile importProfile) groupGoProRecordings(files []string) []*goProRecording {
+	recordings := make(map[string]*goProRecording)
+	for _, file := range files {
+		rule, exists := profile.findRule(file)
+		if !exists || !rule.Chapters {
+			continue
+		}
+		name, isGoPro := parseGoProFileName(filepath.Base(file))
+		if !isGoPro {
+			continue
+		}
+
+		key := filepath.Join(filepath.Dir(file), name.Number)
+		recording, exists := recordings[key]
+		if !exists {
+			recording = &goProRecording{}
+			recordings[key] = recording
+		}
+		recording.chapters = append(recording.chapters, goProChapter{path: file, name: name, rule: rule})
+	}
+
+	keys := make([]string, 0, len(recordings))
+	for key := range recordings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]*goProRecording, 0, len(keys))
+	for _, key := range keys {
+		recording := recordings[key]
+		sort.Slice(recording.chapters, func(i, j int) bool {
+			return recording.chapters[i].less(recording.chapters[j])
+		})
+		recording.lead = recording.chapters[0].path
+		result = append(result, recording)
+	}
+	return result
+}
+
+// less orders chapters by number, videos go before previews
+func (chapter goProChapter) less(other goProChapter) bool {
+	if chapter.name.Chapter != other.name.Chapter {
+		return chapter.name.Chapter < other.name.Chapter
+	}
+	if chapter.isPreview() != other.isPreview() {
+		return !chapter.isPreview()
+	}
+	return chapter.path < other.path
+}
+
+func (chapter goProChapter) isPreview() bool {
+	return chapter.name.Encoding == "GL" || strings.EqualFold(filepath.Ext(chapter.path), ".lrv")
+}
+
+// leadDateTag is the date tag of the recording lead
+func (recording *goProRecording) leadDateTag() string {
+	return recording.chapters[0].rule.dateTag()
+}
+
+// chapterDestinations renders slash separated destinations (relative to the target dir) of recording chapters.
+// All chapters are named by the lead date, chapters of recordings split into several chapters get '_NN' chapter
+// index, so chapters and their previews stay paired.
+// The same copy number ('%c') is used by all files of a recording. Recordings without lead date are skipped
+func chapterDestinations(recordings []*goProRecording, dates map[string]time.Time, targetDir string, used map[string]bool) map[string]string {
+	result := make(map[string]string)
+	for _, recording := range recordings {
+		date, exists := dates[recording.lead]
+		if !exists {
+			log.Infof("'%v' has no date, chapters of the recording are named as regular files", recording.lead)
+			continue
+		}
+
+		var destinations map[string]string
+		for copyNumber := 0; destinations == nil; copyNumber++ {
+			destinations = recording.render(date, copyNumber, targetDir, used)
+		}
+		for path, destination := range destinations {
+			used[strings.ToLower(destination)] = true
+			result[path] = destination
+		}
+	}
+	return result
+}
+
+// render returns destinations of recording chapters or nil if any destination is used or exists in the target dir
+func (recording *goProRecording) render(date time.Time, copyNumber int, targetDir string, used map[string]bool) map[string]string {
+	result := make(map[string]string, len(recording.chapters))
+	chaptered := recording.isChaptered()
+	for _, chapter := range recording.chapters {
+		destination := renderNaming(chapter.rule.Naming, date, chapter.path, copyNumber)
+		if chaptered {
+			destination = addChapterIndex(destination, chapter.name.Chapter)
+		}
+		_, err := os.Stat(filepath.Join(targetDir, filepath.FromSlash(destination)))
+		if used[strings.ToLower(destination)] || !os.IsNotExist(err) {
+			return nil
+		}
+		result[chapter.path] = destination
+	}
+	return result
+}
+
+// isChaptered checks whether the recording was split into several chapters. Single chapter recordings are named
+// without chapter index
+func (recording *goProRecording) isChaptered() bool {
+	for _, chapter := range recording.chapters {
+		if chapter.name.Chapter > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// addChapterIndex inserts chapter index before extensions of the file name, e.g. 'VID_20240518_103015_02.preview.mp4'
+func addChapterIndex(destination string, chapter int) string {
+	dir, fileName := filepath.Split(filepath.FromSlash(destination))
+	base, ext := splitChapterExt(fileName)
+	return filepath.ToSlash(filepath.Join(dir, fmt.Sprintf("%s_%02d%s", base, chapter, ext)))
+}
+
+// splitChapterExt splits the file extension and '.preview' suffix of preview names, other dots belong to the base name
+func splitChapterExt(fileName string) (string, string) {
+	ext := filepath.Ext(fileName)
+	base := strings.TrimSuffix(fileName, ext)
+	if suffix := filepath.Ext(base); strings.EqualFold(suffix, previewSuffix) {
+		return strings.TrimSuffix(base, suffix), suffix + ext
+	}
+	return base, ext
+}
+
+// goProChapterDestinations calculates absolute destinations of downloaded GoPro chapters. Lead dates are read
+// natively, exiftool reads dates of other leads
+func (profile importProfile) goProChapterDestinations(src string, dstDir string) map[string]string {
+	recordings := profile.groupGoProRecordings(listFiles(src))
+	if len(recordings) == 0 {
+		return map[string]string{}
+	}
+
+	leads := make(map[string][]string)
+	dates := make(map[string]time.Time)
+	for _, recording := range recordings {
+		if date, exists := readNativeDate(recording.lead, recording.leadDateTag()); exists {
+			dates[recording.lead] = date.Time
+			continue
+		}
+		leads[recording.leadDateTag()] = append(leads[recording.leadDateTag()], recording.lead)
+	}
+	for dateTag, files := range leads {
+		records, err := getExifTool().readMetadata(files)
+		if err != nil {
+			log.Warningf("Unable to read dates of GoPro recordings: %v", err)
+		}
+		for _, record := range records {
+			if date, exists := record.date(dateTag); exists {
+				dates[record.SourceFile] = date.Time
+			}
+		}
+	}
+
+	result := make(map[string]string)
+	for path, destination := range chapterDestinations(recordings, dates, dstDir, make(map[string]bool)) {
+		result[path] = filepath.Join(dstDir, filepath.FromSlash(destination))
+	}
+	return result
+}
+
+// writeTelemetry writes telemetry files of imported videos according to profile telemetry formats
+func (profile importProfile) writeTelemetry(files []string) {
+	if len(profile.Telemetry) == 0 {
+		return
+	}
+
+	for _, file := range files {
+		if !strings.EqualFold(filepath.Ext(file), ".mp4") {
+			continue
+		}
+		if err := writeTelemetry(file, file, profile.Telemetry); err != nil {
+			log.Warningf("Unable to extract '%s' telemetry: %v", file, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoProFileName(t *testing.T) {
+	tests := []struct {
+		fileName string
+		expected goProFileName
+		isGoPro  bool
+	}{
+		{"GX010123.MP4", goProFileName{Encoding: "GX", Chapter: 1, Number: "0123"}, true},
+		{"GH030123.MP4", goProFileName{Encoding: "GH", Chapter: 3, Number: "0123"}, true},
+		{"gl020123.lrv", goProFileName{Encoding: "GL", Chapter: 2, Number: "0123"}, true},
+		{"GOPR0123.MP4", goProFileName{Encoding: "GOPR", Chapter: 1, Number: "0123"}, true},
+		{"GP010123.MP4", goProFileName{Encoding: "GOPR", Chapter: 2, Number: "0123"}, true},
+		{"GX000123.MP4", goProFileName{}, false},
+		{"VID_20240518_103015.MP4", goProFileName{}, false},
+		{"GX0101234.MP4", goProFileName{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			name, isGoPro := parseGoProFileName(tt.fileName)
+
+			assert.Equal(t, tt.isGoPro, isGoPro)
+			if tt.isGoPro {
+				assert.Equal(t, tt.expected, name)
+			}
+		})
+	}
+}
+
+func TestGroupGoProRecordings(t *testing.T) {
+	r := require.New(t)
+	dir := filepath.FromSlash("/tmp/import/0")
+	file := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	recordings := builtInImportProfiles["gopro"].groupGoProRecordings([]string{
+		file("GL020123.LRV"), file("GX020123.MP4"), file("GX010123.MP4"), file("GL010123.LRV"),
+		file("GX010124.MP4"), file("GOPR0125.JPG"), file("GX010123.WAV"),
+	})
+
+	r.Len(recordings, 2)
+	r.Equal(file("GX010123.MP4"), recordings[0].lead)
+	paths := make([]string, 0)
+	for _, chapter := range recordings[0].chapters {
+		paths = append(paths, chapter.path)
+	}
+	r.Equal([]string{file("GX010123.MP4"), file("GL010123.LRV"), file("GX020123.MP4"), file("GL020123.LRV")}, paths)
+	r.Equal(file("GX010124.MP4"), recordings[1].lead)
+	r.Equal(defaultImportDateTag, recordings[1].leadDateTag())
+}
+
+func TestChapterDestinations(t *testing.T) {
+	date := time.Date(2024, 5, 18, 23, 50, 0, 0, time.UTC)
+	profile := builtInImportProfiles["gopro"]
+	recordings := profile.groupGoProRecordings([]string{"GX010123.MP4", "GX020123.MP4", "GL020123.LRV", "GX010124.MP4"})
+	used := map[string]bool{"2024.05.18/src/vid_20240518_235000_01.mp4": true}
+
+	result := chapterDestinations(recordings, map[string]time.Time{"GX010123.MP4": date}, t.TempDir(), used)
+
+	assert.Equal(t, map[string]string{
+		"GX010123.MP4": "2024.05.18/src/VID_20240518_235000-1_01.MP4",
+		"GX020123.MP4": "2024.05.18/src/VID_20240518_235000-1_02.MP4",
+		"GL020123.LRV": "2024.05.18/src/VID_20240518_235000-1_02.preview.mp4",
+	}, result)
+	assert.True(t, used["2024.05.18/src/vid_20240518_235000-1_02.mp4"])
+}
+
+func TestChapterDestinations_SingleChapter(t *testing.T) {
+	date := time.Date(2024, 5, 18, 23, 50, 0, 0, time.UTC)
+	profile := builtInImportProfiles["gopro"]
+	recordings := profile.groupGoProRecordings([]string{"GX010123.MP4", "GL010123.LRV"})
+
+	result := chapterDestinations(recordings, map[string]time.Time{"GX010123.MP4": date}, t.TempDir(), make(map[string]bool))
+
+	assert.Equal(t, map[string]string{
+		"GX010123.MP4": "2024.05.18/src/VID_20240518_235000.MP4",
+		"GL010123.LRV": "2024.05.18/src/VID_20240518_235000.preview.mp4",
+	}, result)
+}
+
+func TestAddChapterIndex(t *testing.T) {
+	assert.Equal(t, "2024/VID_1_02.MP4", addChapterIndex("2024/VID_1.MP4", 2))
+	assert.Equal(t, "2024/VID_1_12.preview.mp4", addChapterIndex("2024/VID_1.preview.mp4", 12))
+	assert.Equal(t, "VID_01", addChapterIndex("VID", 1))
+	assert.Equal(t, "2024.05.18/2024.05.18_103015_01.MP4", addChapterIndex("2024.05.18/2024.05.18_103015.MP4", 1))
+	assert.Equal(t, "2024.05.18_103015_02.Preview.MP4", addChapterIndex("2024.05.18_103015.Preview.MP4", 2))
+}
+
+func TestImportProfile_PlaceFilesReportsEveryCommand(t *testing.T) {
+	r := require.New(t)
+	viper.Set(cfgImportNativeDates, false)
+	t.Cleanup(func() { viper.Set(cfgImportNativeDates, true) })
+
+	var starts int
+	var capturedArgs []string
+	tool := newFakeExifTool(&starts, &capturedArgs)
+	exifToolObj = tool
+	defer func() {
+		tool.close()
+		exifToolObj = nil
+	}()
+
+	src, dstDir := t.TempDir(), t.TempDir()
+	failed := filepath.Join(src, "GX010001-fail.MP4")
+	profile := importProfile{Rules: []importRule{{Media: []string{"mp4"}}}}
+	_, result, err := profile.placeFiles(map[string]string{
+		failed:                             filepath.Join(dstDir, "VID_20240518_103015_01.MP4"),
+		filepath.Join(src, "GX020001.MP4"): filepath.Join(dstDir, "VID_20240518_103015_02.MP4"),
+	})
+
+	r.Error(err)
+	assert.Contains(t, err.Error(), failed)
+	assert.Equal(t, 1, result.Failed)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, 1, starts)
+	assert.Equal(t, 2, tool.process.counter, "every file is a separate command")
+}
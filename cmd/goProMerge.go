@@ -0,0 +1,205 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// mergeDurationTolerance is allowed difference (per chapter) between duration of merged video and total duration of its chapters
+const mergeDurationTolerance = 500 * time.Millisecond
+
+// chapterIndexPattern matches chapter index added by addChapterIndex
+var chapterIndexPattern = regexp.MustCompile(`_\d{2}$`)
+
+// placedFiles returns sorted destinations of placed files
+func placedFiles(placed map[string]string) []string {
+	result := make([]string, 0, len(placed))
+	for _, destination := range placed {
+		result = append(result, destination)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// mergeChapters joins placed chapters of each recording (videos and previews separately) into a single file.
+// Chapters are removed only after duration of the merged file is verified. Returns imported files: merged
+// videos and chapters which were not merged
+func (profile importProfile) mergeChapters(destinations map[string]string, placed map[string]string) []string {
+	paths := make([]string, 0, len(destinations))
+	for path := range destinations {
+		paths = append(paths, path)
+	}
+
+	merged := make(map[string]bool)
+	result := make([]string, 0)
+	for _, recording := range profile.groupGoProRecordings(paths) {
+		for _, chapters := range recording.mergeGroups() {
+			files := make([]string, 0, len(chapters))
+			for _, chapter := range chapters {
+				if destination, exists := placed[chapter.path]; exists {
+					files = append(files, destination)
+				}
+			}
+			if len(files) != len(chapters) {
+				log.Warningf("'%v' chapters were not merged: some chapters were not imported", recording.lead)
+				continue
+			}
+
+			target, err := mergeVideos(files)
+			if err != nil {
+				log.Warningf("Unable to merge '%v' chapters, chapters are kept: %v", files[0], err)
+				continue
+			}
+			for _, file := range files {
+				merged[file] = true
+			}
+			result = append(result, target)
+		}
+	}
+
+	for _, file := range placedFiles(placed) {
+		if !merged[file] {
+			result = append(result, file)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// mergeGroups splits recording chapters into videos and previews. Only groups of several consecutive chapters are returned
+func (recording *goProRecording) mergeGroups() [][]goProChapter {
+	videos, previews := make([]goProChapter, 0), make([]goProChapter, 0)
+	for _, chapter := range recording.chapters {
+		if chapter.isPreview() {
+			previews = append(previews, chapter)
+		} else {
+			videos = append(videos, chapter)
+		}
+	}
+
+	result := make([][]goProChapter, 0, 2)
+	for _, chapters := range [][]goProChapter{videos, previews} {
+		if len(chapters) < 2 {
+			continue
+		}
+		if !isConsecutive(chapters) {
+			log.Warningf("'%v' chapters were not merged: some chapters are missing", chapters[0].path)
+			continue
+		}
+		result = append(result, chapters)
+	}
+	return result
+}
+
+func isConsecutive(chapters []goProChapter) bool {
+	for i, chapter := range chapters {
+		if chapter.name.Chapter != i+1 {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeVideos joins chapters into a file named without chapter index. Dates of the first chapter are copied to the merged file
+func mergeVideos(chapters []string) (string, error) {
+	target := removeChapterIndex(chapters[0])
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		return "", fmt.Errorf("'%s' already exists", target)
+	}
+
+	expected := time.Duration(0)
+	for _, chapter := range chapters {
+		duration, err := readVideoDuration(chapter)
+		if err != nil {
+			return "", fmt.Errorf("unable to read '%s' duration: %w", chapter, err)
+		}
+		expected += duration
+	}
+
+	ext := filepath.Ext(target)
+	merging := strings.TrimSuffix(target, ext) + ".merging" + ext
+	if err := getFfmpeg().concat(chapters, merging); err != nil {
+		os.Remove(merging)
+		return "", err
+	}
+	if err := verifyMergedDuration(merging, expected, len(chapters)); err != nil {
+		os.Remove(merging)
+		return "", err
+	}
+
+	if err := copyVideoDates(chapters[0], merging); err != nil {
+		log.Warningf("Unable to copy '%s' dates to merged video: %v", chapters[0], err)
+	}
+	if err := os.Rename(merging, target); err != nil {
+		os.Remove(merging)
+		return "", err
+	}
+
+	for _, chapter := range chapters {
+		if err := os.Remove(chapter); err != nil {
+			log.Warningf("Unable to remove '%s' chapter: %v", chapter, err)
+		}
+	}
+	log.Infof("%v chapters were merged into '%s'", len(chapters), target)
+	return target, nil
+}
+
+// verifyMergedDuration checks that merged video is as long as all its chapters
+func verifyMergedDuration(videoPath string, expected time.Duration, chapters int) error {
+	duration, err := readVideoDuration(videoPath)
+	if err != nil {
+		return fmt.Errorf("unable to read merged video duration: %w", err)
+	}
+
+	diff := duration - expected
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > mergeDurationTolerance*time.Duration(chapters) {
+		return fmt.Errorf("merged video duration %v does not match %v of chapters", duration, expected)
+	}
+	return nil
+}
+
+// removeChapterIndex removes chapter index added by addChapterIndex, e.g. 'VID_20240518_103015.preview.mp4'
+func removeChapterIndex(chapterPath string) string {
+	dir, fileName := filepath.Split(chapterPath)
+	base, ext := splitChapterExt(fileName)
+	return filepath.Join(dir, chapterIndexPattern.ReplaceAllString(base, "")+ext)
+}
+
+// copyVideoDates copies QuickTime and file dates of the source video to the target one
+func copyVideoDates(srcFile string, targetFile string) error {
+	exifTool := getExifTool()
+	toolArgs := exifTool.newArgs()
+	toolArgs.add("-overwrite_original")
+	toolArgs.copyMp4Dates(srcFile)
+	toolArgs.src(targetFile)
+	result, err := exifTool.exec()
+	if err == nil && result.hasFailures() {
+		err = fmt.Errorf("%v error(s) reported", len(result.Errors))
+	}
+	return err
+}
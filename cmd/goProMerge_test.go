@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMovie builds minimal MP4 file with movie header of the duration (millisecond timescale)
+func testMovie(duration time.Duration) []byte {
+	mvhd := make([]byte, 108)
+	binary.BigEndian.PutUint32(mvhd, uint32(len(mvhd)))
+	copy(mvhd[4:], "mvhd")
+	binary.BigEndian.PutUint32(mvhd[20:], 1000)
+	binary.BigEndian.PutUint32(mvhd[24:], uint32(duration.Milliseconds()))
+
+	moov := binary.BigEndian.AppendUint32(nil, uint32(8+len(mvhd)))
+	return append(append(moov, "moov"...), mvhd...)
+}
+
+// fakeFfmpeg replaces ffmpeg by a command which copies merged video fixture to the target (the last argument)
+func fakeFfmpeg(t *testing.T, merged []byte) {
+	fixture := filepath.Join(t.TempDir(), "merged.mp4")
+	require.NoError(t, os.WriteFile(fixture, merged, 0644))
+
+	ffmpegObj = &ffmpegWrapper{
+		cmd: "ffmpeg",
+		execCommand: func(name string, args ...string) *exec.Cmd {
+			return exec.Command("cp", fixture, args[len(args)-1])
+		},
+	}
+	t.Cleanup(func() { ffmpegObj = nil })
+}
+
+func writeChapters(t *testing.T, dir string, durations ...time.Duration) []string {
+	result := make([]string, 0, len(durations))
+	for i, duration := range durations {
+		chapter := filepath.Join(dir, addChapterIndex("VID_20240518_103015.MP4", i+1))
+		require.NoError(t, os.WriteFile(chapter, testMovie(duration), 0644))
+		result = append(result, chapter)
+	}
+	return result
+}
+
+func TestMergeVideos(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+	chapters := writeChapters(t, dir, 8*time.Minute, 2*time.Minute+300*time.Millisecond)
+	fakeFfmpeg(t, testMovie(10*time.Minute))
+
+	target, err := mergeVideos(chapters)
+
+	r.NoError(err)
+	r.Equal(filepath.Join(dir, "VID_20240518_103015.MP4"), target)
+	r.FileExists(target)
+	r.NoFileExists(chapters[0])
+	r.NoFileExists(chapters[1])
+}
+
+func TestMergeVideos_KeepsChaptersIfDurationDiffers(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+	chapters := writeChapters(t, dir, 8*time.Minute, 2*time.Minute)
+	fakeFfmpeg(t, testMovie(8*time.Minute))
+
+	_, err := mergeVideos(chapters)
+
+	r.EqualError(err, "merged video duration 8m0s does not match 10m0s of chapters")
+	r.FileExists(chapters[0])
+	r.FileExists(chapters[1])
+	entries, _ := os.ReadDir(dir)
+	r.Len(entries, 2)
+}
+
+func TestMergeVideos_TargetExists(t *testing.T) {
+	dir := t.TempDir()
+	chapters := writeChapters(t, dir, time.Minute, time.Minute)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "VID_20240518_103015.MP4"), nil, 0644))
+
+	_, err := mergeVideos(chapters)
+
+	assert.ErrorContains(t, err, "already exists")
+	assert.FileExists(t, chapters[1])
+}
+
+func TestMergeGroups(t *testing.T) {
+	profile := builtInImportProfiles["gopro"]
+	recordings := profile.groupGoProRecordings([]string{
+		"GX010001.MP4", "GX020001.MP4", "GL010001.LRV", "GL020001.LRV",
+		"GX010002.MP4", "GL010002.LRV",
+		"GX010003.MP4", "GX030003.MP4",
+	})
+	require.Len(t, recordings, 3)
+
+	groups := recordings[0].mergeGroups()
+	require.Len(t, groups, 2)
+	assert.Equal(t, "GX020001.MP4", groups[0][1].path)
+	assert.Equal(t, "GL020001.LRV", groups[1][1].path)
+
+	assert.Empty(t, recordings[1].mergeGroups())
+	assert.Empty(t, recordings[2].mergeGroups())
+}
+
+func TestRemoveChapterIndex(t *testing.T) {
+	assert.Equal(t, filepath.Join("2024", "VID_20240518_103015.MP4"), removeChapterIndex(filepath.Join("2024", "VID_20240518_103015_01.MP4")))
+	assert.Equal(t, "VID_20240518_103015-1.preview.mp4", removeChapterIndex("VID_20240518_103015-1_02.preview.mp4"))
+	assert.Equal(t, "GX010001.MP4", removeChapterIndex("GX010001.MP4"))
+	assert.Equal(t, "2024.05.18_103015.MP4", removeChapterIndex("2024.05.18_103015_01.MP4"))
+}
+
+func TestConcatList(t *testing.T) {
+	dir := t.TempDir()
+
+	list := concatList([]string{filepath.Join(dir, "a.MP4"), filepath.Join(dir, "it's.MP4")})
+
+	assert.Equal(t, "file '"+filepath.ToSlash(dir)+"/a.MP4'\nfile '"+filepath.ToSlash(dir)+`/it'\''s.MP4'`+"\n", list)
+}
+
+func TestConcatArgs(t *testing.T) {
+	assert.Equal(t, []string{"-hide_banner", "-nostdin", "-loglevel", "error", "-f", "concat", "-safe", "0", "-i", "list.txt",
+		"-map", "0:v", "-map", "0:a?", "-map", "0:3", "-copy_unknown", "-tag:d", "gpmd", "-c", "copy", "-map_metadata", "0", "-n", "out.MP4"},
+		concatArgs("list.txt", 3, "out.MP4"))
+	assert.NotContains(t, concatArgs("list.txt", -1, "out.MP4"), "-copy_unknown")
+}
@@ -0,0 +1,236 @@
+package gpmf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func klv(key string, valueType byte, size int, repeat int, data []byte) []byte {
+	result := append([]byte(key), valueType, byte(size))
+	result = binary.BigEndian.AppendUint16(result, uint16(repeat))
+	result = append(result, data...)
+	for len(result)%4 != 0 {
+		result = append(result, 0)
+	}
+	return result
+}
+
+func nested(key string, items ...[]byte) []byte {
+	data := bytes.Join(items, nil)
+	return klv(key, 0, 4, len(data)/4, data)
+}
+
+func be(values ...any) []byte {
+	buffer := &bytes.Buffer{}
+	for _, value := range values {
+		binary.Write(buffer, binary.BigEndian, value)
+	}
+	return buffer.Bytes()
+}
+
+func box(boxType string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	return append(append(be(uint32(len(data)+8)), boxType...), data...)
+}
+
+// gpmfVideo builds MP4 file with a single chunk of GPMF samples. Every sample lasts one second
+func gpmfVideo(payloads ...[]byte) []byte {
+	ftyp := box("ftyp", []byte("mp41"))
+	mdat := box("mdat", payloads...)
+
+	sizes := be(uint32(0), uint32(0), uint32(len(payloads)))
+	for _, payload := range payloads {
+		sizes = append(sizes, be(uint32(len(payload)))...)
+	}
+	trak := box("trak", box("mdia",
+		box("mdhd", be(uint32(0), uint32(0), uint32(0), uint32(1000), uint32(0), uint32(0))),
+		box("hdlr", be(uint32(0), uint32(0)), []byte("meta"), make([]byte, 12)),
+		box("minf", box("stbl",
+			box("stsd", be(uint32(0), uint32(1), uint32(16)), []byte("gpmd")),
+			box("stts", be(uint32(0), uint32(1), uint32(len(payloads)), uint32(1000))),
+			box("stsz", sizes),
+			box("stsc", be(uint32(0), uint32(1), uint32(1), uint32(len(payloads)), uint32(1))),
+			box("stco", be(uint32(0), uint32(1), uint32(len(ftyp)+8))),
+		)),
+	))
+	video := box("trak", box("mdia", box("hdlr", be(uint32(0), uint32(0)), []byte("vide"), make([]byte, 12))))
+
+	return bytes.Join([][]byte{ftyp, mdat, box("moov", video, trak)}, nil)
+}
+
+func gps5Payload(gpsTime string, fix uint32, points ...[]int32) []byte {
+	data := make([]byte, 0)
+	for _, point := range points {
+		data = append(data, be(point)...)
+	}
+	return nested("DEVC",
+		klv("DVID", 'L', 4, 1, be(uint32(1))),
+		nested("STRM",
+			klv("GPSF", 'L', 4, 1, be(fix)),
+			klv("GPSU", 'U', 16, 1, []byte(gpsTime)),
+			klv("GPSP", 'S', 2, 1, be(uint16(150))),
+			klv("SCAL", 'l', 4, 5, be(int32(10000000), int32(10000000), int32(1000), int32(1000), int32(100))),
+			klv("GPS5", 'l', 20, len(points), data),
+		),
+		nested("STRM",
+			klv("SIUN", 'c', 1, len("m/s²"), []byte("m/s²")),
+			klv("SCAL", 's', 2, 1, be(int16(100))),
+			klv("ACCL", 's', 6, 2, be(int16(981), int16(-50), int16(20), int16(980), int16(-40), int16(10))),
+		),
+	)
+}
+
+func TestParseKLV(t *testing.T) {
+	r := require.New(t)
+
+	items, err := ParseKLV(nested("DEVC",
+		klv("DVNM", 'c', 1, 6, []byte("Camera")),
+		nested("STRM", klv("TYPE", 'c', 1, 9, []byte("lllllllSS"))),
+	))
+
+	r.NoError(err)
+	r.Len(items, 1)
+	r.Equal("DEVC", items[0].Key)
+	r.Equal("Camera", items[0].Find("DVNM").String())
+	r.Equal("lllllllSS", items[0].Find("STRM").Find("TYPE").String())
+	r.Nil(items[0].Find("GPS5"))
+}
+
+func TestParseKLV_Truncated(t *testing.T) {
+	data := klv("GPS5", 'l', 20, 2, make([]byte, 40))
+
+	_, err := ParseKLV(data[:30])
+
+	assert.EqualError(t, err, "'GPS5' value of 40 bytes is out of payload")
+}
+
+func TestItem_Values(t *testing.T) {
+	r := require.New(t)
+
+	complexItem := &Item{Key: "GPS9", Type: '?', Size: 6, Repeat: 2, Data: be(int32(-5), uint16(7), int32(6), uint16(8))}
+	values, err := complexItem.Values("lS")
+	r.NoError(err)
+	r.Equal([][]float64{{-5, 7}, {6, 8}}, values)
+
+	_, err = complexItem.Values("ll")
+	r.EqualError(err, "'GPS9' sample size 6 does not match 'll' types")
+
+	fixed := &Item{Key: "TMPC", Type: 'q', Size: 4, Repeat: 1, Data: be(int32(3 << 15))}
+	values, err = fixed.Values("")
+	r.NoError(err)
+	r.Equal([][]float64{{1.5}}, values)
+}
+
+func TestExtractFrom(t *testing.T) {
+	r := require.New(t)
+
+	video := gpmfVideo(
+		gps5Payload("240518103015.000", 3, []int32{504501234, 305234567, 180500, 1500, 160}, []int32{504501334, 305234667, 181000, 1600, 170}),
+		gps5Payload("240518103016.000", 0, []int32{0, 0, 0, 0, 0}),
+	)
+
+	telemetry, err := ExtractFrom(bytes.NewReader(video))
+
+	r.NoError(err)
+	r.Len(telemetry.GPS, 2)
+	r.InDelta(50.4501234, telemetry.GPS[0].Latitude, 1e-9)
+	r.InDelta(30.5234567, telemetry.GPS[0].Longitude, 1e-9)
+	r.InDelta(180.5, telemetry.GPS[0].Altitude, 1e-9)
+	r.Equal(3, telemetry.GPS[0].Fix)
+	r.InDelta(1.5, telemetry.GPS[0].Precision, 1e-9)
+	r.Equal(time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC), telemetry.GPS[0].Time)
+	r.Equal(time.Date(2024, 5, 18, 10, 30, 15, 500000000, time.UTC), telemetry.GPS[1].Time)
+
+	r.Len(telemetry.Sensors, 4)
+	r.Equal(SensorSample{Offset: 1500 * time.Millisecond, Sensor: "ACCL", Unit: "m/s²", Values: []float64{-0.4, 0.1, 9.8}}, telemetry.Sensors[3])
+}
+
+func TestExtractFrom_Orientation(t *testing.T) {
+	r := require.New(t)
+
+	video := gpmfVideo(nested("DEVC", nested("STRM",
+		klv("SIUN", 'c', 1, len("rad/s"), []byte("rad/s")),
+		klv("ORIN", 'c', 1, 3, []byte("YxZ")),
+		klv("GYRO", 's', 6, 1, be(int16(1), int16(2), int16(3))),
+	)))
+
+	telemetry, err := ExtractFrom(bytes.NewReader(video))
+
+	r.NoError(err)
+	r.Len(telemetry.Sensors, 1)
+	r.Equal([]float64{-2, 1, 3}, telemetry.Sensors[0].Values)
+}
+
+func TestToCameraAxes(t *testing.T) {
+	assert.Equal(t, []float64{2, 3, 1}, toCameraAxes([]float64{1, 2, 3}, ""))
+	assert.Equal(t, []float64{3, 1, -2}, toCameraAxes([]float64{1, 2, 3}, "YzX"))
+	assert.Equal(t, []float64{1, 2, 3}, toCameraAxes([]float64{1, 2, 3}, "XXY"))
+	assert.Equal(t, []float64{1, 2}, toCameraAxes([]float64{1, 2}, "ZXY"))
+}
+
+func TestExtractFrom_GPS9(t *testing.T) {
+	r := require.New(t)
+
+	point := be(int32(504501234), int32(305234567), int32(180500), int32(1500), int32(160), int32(8904), int32(37815500), uint16(150), uint16(3))
+	video := gpmfVideo(nested("DEVC", nested("STRM",
+		klv("SCAL", 'l', 4, 9, be(int32(10000000), int32(10000000), int32(1000), int32(1000), int32(100), int32(1), int32(1000), int32(100), int32(1))),
+		klv("TYPE", 'c', 1, 9, []byte("lllllllSS")),
+		klv("GPS9", '?', 32, 1, point),
+	)))
+
+	telemetry, err := ExtractFrom(bytes.NewReader(video))
+
+	r.NoError(err)
+	r.Len(telemetry.GPS, 1)
+	r.Equal(time.Date(2024, 5, 18, 10, 30, 15, 500000000, time.UTC), telemetry.GPS[0].Time)
+	r.InDelta(50.4501234, telemetry.GPS[0].Latitude, 1e-9)
+	r.Equal(3, telemetry.GPS[0].Fix)
+}
+
+func TestExtractFrom_NoTelemetry(t *testing.T) {
+	video := bytes.Join([][]byte{box("ftyp", []byte("mp41")), box("moov", box("mvhd", make([]byte, 100)))}, nil)
+
+	_, err := ExtractFrom(bytes.NewReader(video))
+
+	assert.ErrorIs(t, err, ErrNoTelemetry)
+}
+
+func TestWriteGPX(t *testing.T) {
+	output := &strings.Builder{}
+
+	err := WriteGPX(output, "VID_20240518_103015_01", []GPSPoint{
+		{Time: time.Date(2024, 5, 18, 10, 30, 15, 500000000, time.UTC), Latitude: 50.4501234, Longitude: 30.5234567, Altitude: 180.5, Fix: 3, Precision: 1.5},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="media-tool" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <name>VID_20240518_103015_01</name>
+    <trkseg>
+      <trkpt lat="50.4501234" lon="30.5234567">
+        <ele>180.500</ele>
+        <time>2024-05-18T10:30:15.5Z</time>
+        <fix>3d</fix>
+        <pdop>1.50</pdop>
+      </trkpt>
+    </trkseg>
+  </trk>
+</gpx>
+`, output.String())
+}
+
+func TestWriteCSV(t *testing.T) {
+	output := &strings.Builder{}
+
+	err := WriteCSV(output, []SensorSample{{Offset: 1500 * time.Millisecond, Sensor: "GYRO", Unit: "rad/s", Values: []float64{0.1, -0.2, 0.3}}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "seconds,sensor,unit,x,y,z\n1.500000,GYRO,rad/s,0.100000,-0.200000,0.300000\n", output.String())
+}
@@ -0,0 +1,154 @@
+package gpmf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// typeSizes are sizes of GPMF value types
+var typeSizes = map[byte]int{
+	'b': 1, 'B': 1, 'c': 1,
+	's': 2, 'S': 2,
+	'l': 4, 'L': 4, 'f': 4, 'q': 4, 'F': 4,
+	'd': 8, 'j': 8, 'J': 8, 'Q': 8,
+	'G': 16, 'U': 16,
+}
+
+// Item is a GPMF KLV entry: 4 character key, value type, size of a single sample and number of samples.
+// Items of 0 type are nested
+type Item struct {
+	Key      string
+	Type     byte
+	Size     int
+	Repeat   int
+	Data     []byte
+	Children []*Item
+}
+
+// ParseKLV parses GPMF payload
+func ParseKLV(data []byte) ([]*Item, error) {
+	result := make([]*Item, 0)
+	for offset := 0; offset+8 <= len(data); {
+		item := &Item{
+			Key:    string(data[offset : offset+4]),
+			Type:   data[offset+4],
+			Size:   int(data[offset+5]),
+			Repeat: int(binary.BigEndian.Uint16(data[offset+6:])),
+		}
+		offset += 8
+
+		length := item.Size * item.Repeat
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("'%v' value of %v bytes is out of payload", item.Key, length)
+		}
+		item.Data = data[offset : offset+length]
+		offset += (length + 3) &^ 3
+
+		if item.Key == "\x00\x00\x00\x00" {
+			continue
+		}
+		if item.Type == 0 {
+			children, err := ParseKLV(item.Data)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", item.Key, err)
+			}
+			item.Children = children
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// String returns value of 'c' item
+func (item *Item) String() string {
+	data := item.Data
+	for len(data) > 0 && data[len(data)-1] == 0 {
+		data = data[:len(data)-1]
+	}
+	return string(data)
+}
+
+// Values returns numeric values of every sample. Complex ('?') items are decoded according to types
+func (item *Item) Values(types string) ([][]float64, error) {
+	if item.Type != '?' {
+		typeSize, exists := typeSizes[item.Type]
+		if !exists {
+			return nil, fmt.Errorf("'%v' has unsupported '%c' type", item.Key, item.Type)
+		}
+		types = ""
+		for i := 0; i < item.Size/typeSize; i++ {
+			types += string(item.Type)
+		}
+	}
+
+	sampleSize := 0
+	for i := 0; i < len(types); i++ {
+		typeSize, exists := typeSizes[types[i]]
+		if !exists {
+			return nil, fmt.Errorf("'%v' has unsupported '%c' type", item.Key, types[i])
+		}
+		sampleSize += typeSize
+	}
+	if sampleSize == 0 || sampleSize != item.Size {
+		return nil, fmt.Errorf("'%v' sample size %v does not match '%v' types", item.Key, item.Size, types)
+	}
+
+	result := make([][]float64, item.Repeat)
+	for i := range result {
+		sample := item.Data[i*item.Size : (i+1)*item.Size]
+		values := make([]float64, len(types))
+		for j := 0; j < len(types); j++ {
+			values[j] = readNumber(types[j], sample)
+			sample = sample[typeSizes[types[j]]:]
+		}
+		result[i] = values
+	}
+	return result, nil
+}
+
+func readNumber(valueType byte, data []byte) float64 {
+	switch valueType {
+	case 'b':
+		return float64(int8(data[0]))
+	case 'B', 'c':
+		return float64(data[0])
+	case 's':
+		return float64(int16(binary.BigEndian.Uint16(data)))
+	case 'S':
+		return float64(binary.BigEndian.Uint16(data))
+	case 'l':
+		return float64(int32(binary.BigEndian.Uint32(data)))
+	case 'L', 'F':
+		return float64(binary.BigEndian.Uint32(data))
+	case 'f':
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 'q':
+		return float64(int32(binary.BigEndian.Uint32(data))) / (1 << 16)
+	case 'd':
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	case 'j':
+		return float64(int64(binary.BigEndian.Uint64(data)))
+	case 'J':
+		return float64(binary.BigEndian.Uint64(data))
+	case 'Q':
+		return float64(int64(binary.BigEndian.Uint64(data))) / (1 << 32)
+	}
+	return 0
+}
+
+// Time parses 'U' item, UTC date and time in 'yymmddhhmmss.sss' format
+func (item *Item) Time() (time.Time, error) {
+	return time.Parse("060102150405.000", item.String())
+}
+
+// Find returns the first child item by key
+func (item *Item) Find(key string) *Item {
+	for _, child := range item.Children {
+		if child.Key == key {
+			return child
+		}
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package gpmf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/redrathnure/media-tool/cmd/mp4"
+)
+
+// ErrNoTelemetry is returned for videos without GPMF track
+var ErrNoTelemetry = errors.New("no GPMF telemetry track")
+
+// minGPSFix is the lowest fix (2D) of GPS points which are extracted
+const minGPSFix = 2
+
+// gps9Epoch is the start of GPS9 days counter
+var gps9Epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// sensorKeys are streams of motion sensors data
+var sensorKeys = map[string]bool{"ACCL": true, "GYRO": true}
+
+// defaultOrientation is channel order of motion sensors of cameras which do not write ORIN (HERO5 - HERO7)
+const defaultOrientation = "ZXY"
+
+// GPSPoint is a single GPS fix
+type GPSPoint struct {
+	Time      time.Time
+	Latitude  float64
+	Longitude float64
+	// Altitude is in meters
+	Altitude float64
+	// Speed2D and Speed3D are in m/s
+	Speed2D float64
+	Speed3D float64
+	// Fix is 2 for 2D and 3 for 3D fix
+	Fix int
+	// Precision is dilution of precision (DOP)
+	Precision float64
+}
+
+// SensorSample is a single reading of accelerometer (ACCL) or gyroscope (GYRO). Values are x, y, z of the camera
+// frame, sensor channels are reordered according to the stream orientation (ORIN)
+type SensorSample struct {
+	// Offset is time from the video start
+	Offset time.Duration
+	Sensor string
+	Unit   string
+	Values []float64
+}
+
+// Telemetry is GPS track and motion sensors data of a video
+type Telemetry struct {
+	GPS     []GPSPoint
+	Sensors []SensorSample
+}
+
+// stream is a parsed STRM item with its sticky properties
+type stream struct {
+	scale       []float64
+	unit        string
+	types       string
+	orientation string
+	gpsTime     time.Time
+	gpsFix      int
+	precision   float64
+	data        *Item
+}
+
+// Extract reads GPMF telemetry of a GoPro video
+func Extract(videoPath string) (*Telemetry, error) {
+	f, err := os.Open(videoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ExtractFrom(f)
+}
+
+// ExtractFrom reads GPMF telemetry from MP4 stream
+func ExtractFrom(r io.ReadSeeker) (*Telemetry, error) {
+	movie, err := mp4.ReadMovie(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, track := range movie.Tracks() {
+		if track.HandlerType() != "meta" || track.SampleFormat() != "gpmd" {
+			continue
+		}
+
+		samples, err := track.Samples()
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPMF track: %w", err)
+		}
+		return readSamples(r, samples)
+	}
+	return nil, ErrNoTelemetry
+}
+
+func readSamples(r io.ReadSeeker, samples []mp4.Sample) (*Telemetry, error) {
+	result := &Telemetry{GPS: make([]GPSPoint, 0), Sensors: make([]SensorSample, 0)}
+	for i, sample := range samples {
+		payload := make([]byte, sample.Size)
+		if _, err := r.Seek(sample.Offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, fmt.Errorf("unable to read GPMF sample %v: %w", i, err)
+		}
+
+		items, err := ParseKLV(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPMF sample %v: %w", i, err)
+		}
+		if err := result.addPayload(items, sample); err != nil {
+			return nil, fmt.Errorf("invalid GPMF sample %v: %w", i, err)
+		}
+	}
+	return result, nil
+}
+
+func (telemetry *Telemetry) addPayload(items []*Item, sample mp4.Sample) error {
+	for _, devc := range items {
+		if devc.Key != "DEVC" {
+			continue
+		}
+		for _, strm := range devc.Children {
+			if strm.Key != "STRM" {
+				continue
+			}
+			stream, err := parseStream(strm)
+			if err != nil {
+				return err
+			}
+			if stream.data == nil {
+				continue
+			}
+			if err := telemetry.addStream(stream, sample); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func parseStream(strm *Item) (stream, error) {
+	result := stream{}
+	for _, item := range strm.Children {
+		switch item.Key {
+		case "SCAL":
+			values, err := item.Values("")
+			if err != nil {
+				return result, err
+			}
+			for _, value := range values {
+				result.scale = append(result.scale, value...)
+			}
+		case "SIUN", "UNIT":
+			result.unit = item.String()
+		case "TYPE":
+			result.types = item.String()
+		case "ORIN":
+			result.orientation = item.String()
+		case "GPSU":
+			gpsTime, err := item.Time()
+			if err != nil {
+				return result, fmt.Errorf("invalid GPSU: %w", err)
+			}
+			result.gpsTime = gpsTime
+		case "GPSF":
+			if values, err := item.Values(""); err == nil && len(values) > 0 {
+				result.gpsFix = int(values[0][0])
+			}
+		case "GPSP":
+			if values, err := item.Values(""); err == nil && len(values) > 0 {
+				result.precision = values[0][0] / 100
+			}
+		case "GPS5", "GPS9", "ACCL", "GYRO":
+			result.data = item
+		}
+	}
+	return result, nil
+}
+
+func (telemetry *Telemetry) addStream(stream stream, sample mp4.Sample) error {
+	values, err := stream.data.Values(stream.types)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	step := sample.Duration / time.Duration(len(values))
+
+	for i, value := range values {
+		value = applyScale(value, stream.scale)
+		offset := time.Duration(i) * step
+
+		switch {
+		case stream.data.Key == "GPS5" && len(value) >= 5:
+			if stream.gpsFix < minGPSFix {
+				continue
+			}
+			point := GPSPoint{Latitude: value[0], Longitude: value[1], Altitude: value[2], Speed2D: value[3], Speed3D: value[4], Fix: stream.gpsFix, Precision: stream.precision}
+			if !stream.gpsTime.IsZero() {
+				point.Time = stream.gpsTime.Add(offset)
+			}
+			telemetry.GPS = append(telemetry.GPS, point)
+		case stream.data.Key == "GPS9" && len(value) >= 9:
+			if int(value[8]) < minGPSFix {
+				continue
+			}
+			pointTime := gps9Epoch.AddDate(0, 0, int(value[5])).Add(time.Duration(value[6] * float64(time.Second)))
+			telemetry.GPS = append(telemetry.GPS, GPSPoint{Time: pointTime.Round(time.Millisecond), Latitude: value[0], Longitude: value[1], Altitude: value[2],
+				Speed2D: value[3], Speed3D: value[4], Precision: value[7], Fix: int(value[8])})
+		case sensorKeys[stream.data.Key]:
+			telemetry.Sensors = append(telemetry.Sensors, SensorSample{Offset: sample.Time + offset, Sensor: stream.data.Key, Unit: stream.unit, Values: toCameraAxes(value, stream.orientation)})
+		}
+	}
+	return nil
+}
+
+// toCameraAxes reorders sensor channels to x, y, z. Orientation names the axis of every channel, e.g. "YxZ",
+// lower case axis is inverted. Values are kept as is if orientation does not describe all 3 axes
+func toCameraAxes(values []float64, orientation string) []float64 {
+	if orientation == "" {
+		orientation = defaultOrientation
+	}
+	if len(values) != 3 || len(orientation) != 3 {
+		return values
+	}
+
+	result := make([]float64, 3)
+	found := 0
+	for i, axis := range orientation {
+		sign := 1.0
+		if axis >= 'x' {
+			sign = -1
+			axis -= 'x' - 'X'
+		}
+		index := int(axis - 'X')
+		if index < 0 || index > 2 || found&(1<<index) != 0 {
+			return values
+		}
+		found |= 1 << index
+		result[index] = sign * values[i]
+	}
+	return result
+}
+
+// applyScale divides values by SCAL. A single scale is used for all values
+func applyScale(values []float64, scale []float64) []float64 {
+	result := make([]float64, len(values))
+	for i, value := range values {
+		divider := 1.0
+		switch {
+		case len(scale) == 1:
+			divider = scale[0]
+		case i < len(scale):
+			divider = scale[i]
+		}
+		if divider == 0 {
+			divider = 1
+		}
+		result[i] = value / divider
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package gpmf
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type gpxDocument struct {
+	XMLName xml.Name `xml:"gpx"`
+	Version string   `xml:"version,attr"`
+	Creator string   `xml:"creator,attr"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Track   gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Name    string     `xml:"name"`
+	Segment gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Latitude  string `xml:"lat,attr"`
+	Longitude string `xml:"lon,attr"`
+	Elevation string `xml:"ele"`
+	Time      string `xml:"time,omitempty"`
+	Fix       string `xml:"fix,omitempty"`
+	PDOP      string `xml:"pdop,omitempty"`
+}
+
+// WriteGPX writes GPS points as GPX 1.1 track
+func WriteGPX(w io.Writer, name string, points []GPSPoint) error {
+	document := gpxDocument{
+		Version: "1.1",
+		Creator: "media-tool",
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+		Track:   gpxTrack{Name: name, Segment: gpxSegment{Points: make([]gpxPoint, 0, len(points))}},
+	}
+	for _, point := range points {
+		gpxPoint := gpxPoint{
+			Latitude:  formatFloat(point.Latitude, 7),
+			Longitude: formatFloat(point.Longitude, 7),
+			Elevation: formatFloat(point.Altitude, 3),
+			Fix:       fmt.Sprintf("%vd", point.Fix),
+		}
+		if !point.Time.IsZero() {
+			gpxPoint.Time = point.Time.UTC().Format(time.RFC3339Nano)
+		}
+		if point.Precision > 0 {
+			gpxPoint.PDOP = formatFloat(point.Precision, 2)
+		}
+		document.Track.Segment.Points = append(document.Track.Segment.Points, gpxPoint)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteCSV writes motion sensors data, one reading per line. Values are in camera x, y, z axes order
+func WriteCSV(w io.Writer, samples []SensorSample) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"seconds", "sensor", "unit", "x", "y", "z"}); err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		record := []string{formatFloat(sample.Offset.Seconds(), 6), sample.Sensor, sample.Unit}
+		for _, value := range sample.Values {
+			record = append(record, formatFloat(value, 6))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatFloat(value float64, precision int) string {
+	return strconv.FormatFloat(value, 'f', precision, 64)
+}
@@ -18,7 +18,35 @@ limitations under the License.
 package cmd
 
 import (
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+const (
+	cfgImportSource    = "import.source"
+	cfgImportMountDirs = "import.mountDirs"
+
+	cfgImportVerifyChecksum = "import.verifyChecksum"
+	cfgImportKeepSource     = "import.keepSource"
+
+	cfgImportLedgerEnabled = "import.ledger.enabled"
+	cfgImportLedgerPath    = "import.ledger.path"
+
+	cfgImportFreeSpaceMargin = "import.freeSpaceMargin"
+
+	cfgImportCopyAttempts   = "import.copyAttempts"
+	cfgImportCopyRetryDelay = "import.copyRetryDelay"
+
+	cfgImportIgnore = "import.ignore"
+	cfgImportJunk   = "import.junk"
+
+	cfgImportNativeDates = "import.nativeDates"
 )
 
 // importCmd represents the import command
@@ -31,8 +59,110 @@ var importCmd = &cobra.Command{
 //DryRun just test instead real file manimupations
 var DryRun bool
 
+var reimport bool
+
 func init() {
 	rootCmd.AddCommand(importCmd)
 
-	importCmd.PersistentFlags().BoolVarP(&DryRun, "dry", "d", false, "Dry run")
+	importCmd.PersistentFlags().BoolVarP(&DryRun, "dry", "d", false, "Dry run: report files and names they would get without copying anything")
+	importCmd.PersistentFlags().Bool("keep-source", false, "Import files but do not delete them from devices")
+	viper.BindPFlag(cfgImportKeepSource, importCmd.PersistentFlags().Lookup("keep-source"))
+	importCmd.PersistentFlags().String("source", mtp.SourceAuto, "Devices source: 'wpd' (Windows Portable Devices), 'fs' (mounted volumes) or 'auto'")
+	viper.BindPFlag(cfgImportSource, importCmd.PersistentFlags().Lookup("source"))
+	importCmd.PersistentFlags().Bool("verify", false, "Compare SHA-256 checksums of copied files with device files before deleting them")
+	viper.BindPFlag(cfgImportVerifyChecksum, importCmd.PersistentFlags().Lookup("verify"))
+	importCmd.PersistentFlags().BoolVar(&reimport, "reimport", false, "Import files even if they were imported earlier (according to the import ledger)")
+
+	viper.SetDefault(cfgImportSource, mtp.SourceAuto)
+	viper.SetDefault(cfgImportMountDirs, mtp.DefaultMountDirs)
+	viper.SetDefault(cfgImportVerifyChecksum, false)
+	viper.SetDefault(cfgImportKeepSource, false)
+	viper.SetDefault(cfgImportLedgerEnabled, true)
+	viper.SetDefault(cfgImportLedgerPath, defaultLedgerPath())
+	viper.SetDefault(cfgImportFreeSpaceMargin, "1G")
+	viper.SetDefault(cfgImportCopyAttempts, 3)
+	viper.SetDefault(cfgImportCopyRetryDelay, "1s")
+	viper.SetDefault(cfgImportIgnore, mtp.DefaultIgnorePatterns)
+	viper.SetDefault(cfgImportJunk, []string{})
+	viper.SetDefault(cfgImportNativeDates, true)
+}
+
+func defaultLedgerPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".media-tool", "ledger.jsonl")
+}
+
+// openLedger opens import ledger according to 'import.ledger.*' configuration. Returns nil if ledger is disabled
+func openLedger() *mtp.ImportLedger {
+	ledgerPath := os.ExpandEnv(viper.GetString(cfgImportLedgerPath))
+	if !viper.GetBool(cfgImportLedgerEnabled) || ledgerPath == "" {
+		log.Debugf("import ledger is disabled")
+		return nil
+	}
+
+	ledger, err := mtp.OpenLedger(ledgerPath)
+	if err != nil {
+		log.Errorf("Unable to open '%s' import ledger: %v", ledgerPath, err)
+		os.Exit(1)
+	}
+	log.Debugf("import ledger: '%s' (%v entries)", ledgerPath, ledger.GetEntriesCount())
+	return ledger
+}
+
+// getMediaSource creates devices source according to 'import.source' and 'import.mountDirs' configuration
+func getMediaSource() mtp.Source {
+	sourceType := viper.GetString(cfgImportSource)
+	mountDirs := viper.GetStringSlice(cfgImportMountDirs)
+	log.Debugf("devices source: '%s', mount dirs: %v", sourceType, mountDirs)
+
+	source, err := mtp.NewSource(sourceType, mountDirs)
+	if err != nil {
+		log.Errorf("Unable to init devices source: %v", err)
+		os.Exit(1)
+	}
+	return source
+}
+
+// getImportOptions builds device import options from args and configuration
+func getImportOptions() mtp.ImportOptions {
+	freeSpaceMargin, err := mtp.ParseSize(viper.GetString(cfgImportFreeSpaceMargin))
+	if err != nil {
+		log.Errorf("Invalid '%s' configuration: %v", cfgImportFreeSpaceMargin, err)
+		os.Exit(1)
+	}
+
+	copyRetryDelay, err := time.ParseDuration(viper.GetString(cfgImportCopyRetryDelay))
+	if err != nil {
+		log.Errorf("Invalid '%s' configuration: %v", cfgImportCopyRetryDelay, err)
+		os.Exit(1)
+	}
+
+	files, err := buildFileFilter(importFilter, time.Now())
+	if err != nil {
+		log.Errorf("%v", err)
+		os.Exit(1)
+	}
+
+	options := mtp.ImportOptions{
+		KeepSource:      viper.GetBool(cfgImportKeepSource),
+		VerifyChecksum:  viper.GetBool(cfgImportVerifyChecksum),
+		Ledger:          openLedger(),
+		Reimport:        reimport,
+		FreeSpaceMargin: freeSpaceMargin,
+		Files:           files,
+		CopyAttempts:    viper.GetInt(cfgImportCopyAttempts),
+		CopyRetryDelay:  copyRetryDelay,
+	}
+	log.Infof("keep source: %v", options.KeepSource)
+	log.Infof("verify checksum: %v", options.VerifyChecksum)
+	log.Infof("reimport: %v", options.Reimport)
+	log.Infof("free space margin: %v", mtp.SizeToLabel(options.FreeSpaceMargin))
+	log.Debugf("copy attempts: %v, retry delay: %v", options.CopyAttempts, options.CopyRetryDelay)
+	if !files.IsEmpty() {
+		log.Infof("files filter: %+v", files)
+	}
+	return options
 }
@@ -0,0 +1,96 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+// importApplyCmd represents the import apply command
+var importApplyCmd = &cobra.Command{
+	Use:   "apply plan.json",
+	Short: "Import media according to saved import plan",
+	Long: `Copy files listed in the import plan (see '--plan-out' flag) and move them 
+	to the plan destinations. The plan is refused if any of listed files 
+	is missing or was changed on the device since the plan was made`,
+	Args: cobra.ExactArgs(1),
+	Run:  exitOnError(runImportApply),
+}
+
+func runImportApply(cmd *cobra.Command, args []string) error {
+	printCommandArgs(cmd, args)
+
+	plan, err := mtp.ReadImportPlan(args[0])
+	if err != nil {
+		return fmt.Errorf("unable to read import plan: %w", err)
+	}
+
+	profile, err := getImportProfile(plan.Profile)
+	if err != nil {
+		return err
+	}
+	deviceProfile, err := profile.toDeviceProfile()
+	if err != nil {
+		return fmt.Errorf("invalid '%s' profile: %w", profile.Name, err)
+	}
+	log.Infof("src: '%s' media, %v file(s)", plan.Profile, len(plan.Files))
+	log.Infof("dst: '%s'", plan.TargetDir)
+
+	log.Infof("dry ryn: %v", DryRun)
+
+	options := getImportOptions()
+	defer options.Close()
+
+	if DryRun {
+		if err := mtp.CheckImportPlan(getMediaSource(), deviceProfile, plan); err != nil {
+			return fmt.Errorf("unable to apply import plan: %w", err)
+		}
+		log.Infof("Dry run: %v file(s) would be imported", len(plan.Files))
+		reportPlannedFiles(plan)
+		return nil
+	}
+
+	var placeErr error
+	options.Place = func(src string) {
+		log.Infof("Files were downloaded to: %v. Moving to target folder...", src)
+		result, err := profile.placePlannedFiles(plan)
+		placeErr = result.report(err)
+	}
+
+	src, err := mtp.ApplyImportPlan(getMediaSource(), deviceProfile, plan, options)
+	if err != nil {
+		return fmt.Errorf("unable to apply import plan: %w", err)
+	}
+	removeImportDir(src, false)
+	if plan.TempDir != "" && plan.TempDir != src {
+		// copies left in the plan temp dir after successful placement are files excluded from the plan
+		removeImportDir(plan.TempDir, placeErr == nil)
+	}
+	if placeErr != nil {
+		return fmt.Errorf("unable to move '%s' files to target folder: %w", plan.Profile, placeErr)
+	}
+	return nil
+}
+
+func init() {
+	importCmd.AddCommand(importApplyCmd)
+}
@@ -18,8 +18,6 @@ limitations under the License.
 package cmd
 
 import (
-	"os"
-
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -41,53 +39,9 @@ var camVideoCmd = &cobra.Command{
 	'import.camvideo.default.targetDir' configuration property`,
 	Args:    cobra.RangeArgs(0, 1),
 	Aliases: []string{"camvideo", "CamVideo"},
-	Run: func(cmd *cobra.Command, args []string) {
-		printCommandArgs(cmd, args)
-
-		log.Infof("src: 'CamSD' media")
-
-		dstDir := extractPath(args, 0, "")
-		if dstDir == "" {
-			log.Infof("No args for targetDir was specified. Reading '%s' configuration", cfgImportCamVideoDefaultDst)
-			dstDir = viper.GetString(cfgImportCamVideoDefaultDst)
-			if dstDir == "" {
-				log.Errorf("No target dir was specified")
-				os.Exit(1)
-			}
-		}
-		log.Infof("dst: '%s'", dstDir)
-
-		log.Infof("dry ryn: %v", DryRun)
-
-		src, err := mtp.LoadCamVideos(dstDir, DryRun)
-		if err != nil {
-			log.Errorf("Unable to copy camcoder files: %v", err)
-			os.Exit(1)
-		}
-		defer removeDir(src, DryRun)
-		log.Infof("Files were downloaded to: %v. Moving to target folder...", src)
-
-		tagName := "FileName"
-		if DryRun {
-			tagName = "TestName"
-		}
-
-		//Video
-		exifTool := getExifTool()
-
-		//Images
-		videoArgs := exifTool.newArgs()
-		if !DryRun {
-			videoArgs.changeFileDate("DateTimeOriginal")
-		}
-		videoArgs.changeTag(tagName, "DateTimeOriginal")
-		videoArgs.forDateFormat(dstDir + "\\%Y.%m.%d\\VID_%Y%m%d_%H%M%S%%-c.%%e")
-		videoArgs.forVideoAvchd()
-		videoArgs.recursively()
-		videoArgs.src(src)
-
-		exifTool.exec()
-	},
+	Run: exitOnError(func(cmd *cobra.Command, args []string) error {
+		return runProfileImport(cmd, mtp.CamVideoProfile.Name, args)
+	}),
 }
 
 func init() {
@@ -0,0 +1,248 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+// dateFormatPattern matches strftime codes of exiftool date format, e.g. '%Y' or escaped '%%'
+var dateFormatPattern = regexp.MustCompile(`%.`)
+
+// fileNamePattern matches exiftool file name codes, e.g. '%f', '%e' or '%-c'
+var fileNamePattern = regexp.MustCompile(`%([-+]?)([dfec])`)
+
+// reportDryImport lists device files matched by the profile and names they would get in the target dir.
+// Nothing is copied and nothing is deleted from devices
+func reportDryImport(profile importProfile, deviceProfile mtp.DeviceProfile, dstDir string, options mtp.ImportOptions) error {
+	files, err := mtp.ScanAllWpd(getMediaSource(), deviceProfile, options)
+	if err != nil {
+		return fmt.Errorf("unable to scan '%s' devices: %w", profile.Name, err)
+	}
+
+	plan := mtp.NewImportPlan(profile.Name, dstDir)
+	plan.Files = files
+	profile.predictDestinations(plan)
+
+	log.Infof("Dry run: %v file(s) would be imported. Dates are taken from device modification times, actual names may differ", len(plan.Files))
+	reportPlannedFiles(plan)
+	return nil
+}
+
+// reportPlannedFiles prints device files and their destinations
+func reportPlannedFiles(plan *mtp.ImportPlan) {
+	for _, file := range plan.Files {
+		log.Infof("%v: '%v' --> '%v'", file.Device, file.Path, plan.GetDestinationPath(file))
+	}
+}
+
+// predictDestinations sets destinations of planned files according to profile rules. Device modification
+// time is used instead of the rule date tag. Files which are not moved by any rule are excluded from the plan
+func (profile importProfile) predictDestinations(plan *mtp.ImportPlan) {
+	used := make(map[string]bool)
+	chapters := profile.predictChapterDestinations(plan, used)
+
+	files := make([]*mtp.PlannedFile, 0, len(plan.Files))
+	for _, file := range plan.Files {
+		if destination, exists := chapters[file.Path]; exists {
+			file.Destination = destination
+			files = append(files, file)
+			continue
+		}
+
+		rule, exists := profile.findRule(file.Path)
+		if !exists {
+			log.Infof("%v: '%v' is not moved by profile rules and would be kept in the temp dir", file.Device, file.Path)
+			continue
+		}
+
+		destination, exists := predictDestination(plan.TargetDir, rule.Naming, time.Unix(file.ModTime, 0), file.Path, used)
+		if !exists {
+			log.Warningf("%v: '%v' destination '%v' already exists, the file would be kept in the temp dir", file.Device, file.Path, destination)
+			continue
+		}
+
+		file.Destination = destination
+		files = append(files, file)
+	}
+	plan.Files = files
+}
+
+// predictChapterDestinations renders names of GoPro chapters using device modification time of the first chapter
+func (profile importProfile) predictChapterDestinations(plan *mtp.ImportPlan, used map[string]bool) map[string]string {
+	if !profile.hasChapterRules() {
+		return map[string]string{}
+	}
+
+	paths := make([]string, 0, len(plan.Files))
+	dates := make(map[string]time.Time, len(plan.Files))
+	for _, file := range plan.Files {
+		paths = append(paths, file.Path)
+		dates[file.Path] = time.Unix(file.ModTime, 0)
+	}
+	return chapterDestinations(profile.groupGoProRecordings(paths), dates, plan.TargetDir, used)
+}
+
+// findRule returns the first rule which moves the file. Exiftool runs rules one by one, so later rules do not see moved files
+func (profile importProfile) findRule(filePath string) (importRule, bool) {
+	for _, rule := range profile.Rules {
+		if rule.matches(filePath) {
+			return rule, true
+		}
+	}
+	return importRule{}, false
+}
+
+// matches checks whether the file extension is one of the rule media types
+func (rule importRule) matches(filePath string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
+	for _, media := range rule.Media {
+		for _, mediaExt := range mediaExtensions(media) {
+			if ext == mediaExt {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesAny checks whether any of files matches the rule
+func (rule importRule) matchesAny(files []string) bool {
+	for _, file := range files {
+		if rule.matches(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// predictDestination renders slash separated destination relative to the target dir. Copy number ('%c') is increased
+// while the name is used by another file or exists in the target dir. Returns false if the name is taken and naming has no copy number
+func predictDestination(targetDir string, naming string, date time.Time, filePath string, used map[string]bool) (string, bool) {
+	name := formatNamingDate(naming, date)
+	for copyNumber := 0; ; copyNumber++ {
+		destination := renderFileCodes(name, filePath, copyNumber)
+		_, err := os.Stat(filepath.Join(targetDir, filepath.FromSlash(destination)))
+		if !used[strings.ToLower(destination)] && os.IsNotExist(err) {
+			used[strings.ToLower(destination)] = true
+			return destination, true
+		}
+		if destination == renderFileCodes(name, filePath, copyNumber+1) {
+			return destination, false
+		}
+	}
+}
+
+// renderNaming renders exiftool naming: date format codes first ('%Y', '%m' etc.), then file name codes ('%%f', '%%e', '%%-c')
+func renderNaming(naming string, date time.Time, filePath string, copyNumber int) string {
+	return renderFileCodes(formatNamingDate(naming, date), filePath, copyNumber)
+}
+
+// isNativeNaming checks whether all date format codes of the naming are rendered natively
+func isNativeNaming(naming string) bool {
+	for _, code := range dateFormatPattern.FindAllString(naming, -1) {
+		if _, supported := formatDateCode(code[1], time.Time{}); !supported {
+			return false
+		}
+	}
+	return true
+}
+
+// formatNamingDate renders date format codes of the naming. Namings with codes which are not rendered natively are
+// formatted by exiftool, so names are the same as exiftool gives
+func formatNamingDate(naming string, date time.Time) string {
+	naming = filepath.ToSlash(naming)
+	if !isNativeNaming(naming) {
+		name, err := getExifTool().formatDate(naming, date)
+		if err == nil {
+			return name
+		}
+		log.Warningf("Unable to format '%s' naming by exiftool: %v", naming, err)
+	}
+
+	return dateFormatPattern.ReplaceAllStringFunc(naming, func(code string) string {
+		value, _ := formatDateCode(code[1], date)
+		return value
+	})
+}
+
+// renderFileCodes renders file name codes ('%f', '%e', '%-c') of the name with rendered date codes
+func renderFileCodes(name string, filePath string, copyNumber int) string {
+	ext := filepath.Ext(filePath)
+	return fileNamePattern.ReplaceAllStringFunc(name, func(code string) string {
+		match := fileNamePattern.FindStringSubmatch(code)
+		switch match[2] {
+		case "d":
+			return strings.TrimPrefix(filepath.ToSlash(filepath.Dir(filePath)), "/")
+		case "f":
+			return strings.TrimSuffix(filepath.Base(filePath), ext)
+		case "e":
+			return strings.TrimPrefix(ext, ".")
+		}
+		if copyNumber == 0 {
+			return ""
+		}
+		switch match[1] {
+		case "-":
+			return fmt.Sprintf("-%v", copyNumber)
+		case "+":
+			return fmt.Sprintf("_%v", copyNumber)
+		}
+		return fmt.Sprint(copyNumber)
+	})
+}
+
+// formatDateCode renders strftime code. Returns false for codes which are not rendered natively
+func formatDateCode(code byte, date time.Time) (string, bool) {
+	switch code {
+	case '%':
+		return "%", true
+	case 'Y':
+		return date.Format("2006"), true
+	case 'y':
+		return date.Format("06"), true
+	case 'm':
+		return date.Format("01"), true
+	case 'd':
+		return date.Format("02"), true
+	case 'H':
+		return date.Format("15"), true
+	case 'M':
+		return date.Format("04"), true
+	case 'S':
+		return date.Format("05"), true
+	case 'j':
+		return fmt.Sprintf("%03d", date.YearDay()), true
+	case 'b':
+		return date.Format("Jan"), true
+	case 'B':
+		return date.Format("January"), true
+	case 'a':
+		return date.Format("Mon"), true
+	case 'A':
+		return date.Format("Monday"), true
+	}
+	return "%" + string(code), false
+}
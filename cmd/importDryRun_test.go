@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+func TestRenderNaming(t *testing.T) {
+	date := time.Date(2024, 5, 18, 10, 30, 15, 0, time.Local)
+
+	tests := []struct {
+		naming     string
+		copyNumber int
+		expected   string
+	}{
+		{"%Y.%m.%d/src/VID_%Y%m%d_%H%M%S%%-c.%%e", 0, "2024.05.18/src/VID_20240518_103015.MP4"},
+		{"%Y.%m.%d/src/VID_%Y%m%d_%H%M%S%%-c.%%e", 2, "2024.05.18/src/VID_20240518_103015-2.MP4"},
+		{"%Y.%m.%d/src/VID_%Y%m%d_%H%M%S%%-c.preview.mp4", 0, "2024.05.18/src/VID_20240518_103015.preview.mp4"},
+		{"%Y.%m.%d/%%f%%-c.%%e", 1, "2024.05.18/GX010001-1.MP4"},
+		{"%y/%j/%%f%%+c.%%e", 1, "24/139/GX010001_1.MP4"},
+		{"100%%/%%f%%c.%%e", 3, "100%/GX0100013.MP4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, renderNaming(tt.naming, date, "/DCIM/100GOPRO/GX010001.MP4", tt.copyNumber))
+		})
+	}
+}
+
+func TestPredictDestinations(t *testing.T) {
+	r := require.New(t)
+
+	targetDir := t.TempDir()
+	r.NoError(os.MkdirAll(filepath.Join(targetDir, "2024.05.18", "src"), 0755))
+	r.NoError(os.WriteFile(filepath.Join(targetDir, "2024.05.18", "src", "VID_20240518_103015_01.MP4"), []byte("existing"), 0644))
+
+	modTime := time.Date(2024, 5, 18, 10, 30, 15, 0, time.Local).Unix()
+	plan := mtp.NewImportPlan("gopro", targetDir)
+	plan.Files = []*mtp.PlannedFile{
+		{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GX010001.MP4", ModTime: modTime},
+		{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GX020001.MP4", ModTime: modTime + 3600},
+		{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GL010001.LRV", ModTime: modTime},
+		{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GX010002.MP4", ModTime: modTime},
+		{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GOPR0003.JPG", ModTime: modTime},
+		{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GX010001.WAV", ModTime: modTime},
+	}
+
+	builtInImportProfiles["gopro"].predictDestinations(plan)
+
+	r.Len(plan.Files, 5)
+	assert.Equal(t, "2024.05.18/src/VID_20240518_103015-1_01.MP4", plan.Files[0].Destination)
+	assert.Equal(t, "2024.05.18/src/VID_20240518_103015-1_02.MP4", plan.Files[1].Destination)
+	assert.Equal(t, "2024.05.18/src/VID_20240518_103015-1_01.preview.mp4", plan.Files[2].Destination)
+	assert.Equal(t, "2024.05.18/src/VID_20240518_103015.MP4", plan.Files[3].Destination)
+	assert.Equal(t, "2024.05.18/src/IMG_20240518_103015.JPG", plan.Files[4].Destination)
+	r.NoFileExists(filepath.Join(targetDir, "2024.05.18", "src", "VID_20240518_103015-1_01.MP4"))
+}
+
+func TestPredictDestinations_NameIsTaken(t *testing.T) {
+	modTime := time.Date(2024, 5, 18, 10, 30, 15, 0, time.Local).Unix()
+	profile := importProfile{Rules: []importRule{{Media: []string{"images"}, Naming: "%Y/IMG_%Y%m%d.%%e"}}}
+	plan := mtp.NewImportPlan("photos", t.TempDir())
+	plan.Files = []*mtp.PlannedFile{
+		{Device: "D750 (Nikon DSC)", Path: "/DCIM/DSC_0001.NEF", ModTime: modTime},
+		{Device: "D750 (Nikon DSC)", Path: "/DCIM/DSC_0002.NEF", ModTime: modTime},
+	}
+
+	profile.predictDestinations(plan)
+
+	require.Len(t, plan.Files, 1)
+	assert.Equal(t, "2024/IMG_20240518.NEF", plan.Files[0].Destination)
+}
+
+func TestIsNativeNaming(t *testing.T) {
+	assert.True(t, isNativeNaming("%Y.%m.%d/src/VID_%Y%m%d_%H%M%S%%-c.%%e"))
+	assert.True(t, isNativeNaming("%y/%j/%b_%a/100%%"))
+	assert.False(t, isNativeNaming("%Y/week_%U/%%f.%%e"))
+	assert.False(t, isNativeNaming("%Y%m%d_%H%M%S%z.%%e"))
+}
+
+func TestRenderNaming_UnsupportedCodesAreFormattedByExifTool(t *testing.T) {
+	tool := &exifToolWrapper{
+		cmd: "test-exiftool",
+		execCommand: func(name string, args ...string) *exec.Cmd {
+			return exec.Command("sh", "-c", stayOpenExifToolScript(`
+      echo "2024/week_20/%f.%e"`))
+		},
+	}
+	exifToolObj = tool
+	defer func() {
+		tool.close()
+		exifToolObj = nil
+	}()
+
+	destination := renderNaming("%Y/week_%U/%%f.%%e", time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC), "/DCIM/100GOPRO/GX010001.MP4", 0)
+
+	assert.Equal(t, "2024/week_20/GX010001.MP4", destination)
+	assert.Equal(t, []string{"-s3", "-d", "%Y/week_%U/%%f.%%e", "-FileModifyDate"}, tool.args.args[:4])
+}
@@ -0,0 +1,119 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+// importFilterArgs holds file filter args of import commands
+type importFilterArgs struct {
+	since        string
+	until        string
+	last         string
+	ext          []string
+	excludeExt   []string
+	minSize      string
+	maxSize      string
+	includePaths []string
+	excludePaths []string
+}
+
+var importFilter importFilterArgs
+
+var dateLayouts = []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02 15:04:05", "2006-01-02T15:04:05", time.RFC3339}
+
+func init() {
+	flags := importCmd.PersistentFlags()
+	flags.StringVar(&importFilter.since, "since", "", "Import files modified since the date, e.g. '2024-05-18' or '2024-05-18 10:30'")
+	flags.StringVar(&importFilter.until, "until", "", "Import files modified before the date. Date without time includes the whole day")
+	flags.StringVar(&importFilter.last, "last", "", "Import files modified during the last period, e.g. '12h', '3d' or '1w'")
+	flags.StringSliceVar(&importFilter.ext, "ext", nil, "Import files with the extensions only, e.g. 'mp4,jpg'")
+	flags.StringSliceVar(&importFilter.excludeExt, "excludeExt", nil, "Do not import files with the extensions, e.g. 'lrv,thm'")
+	flags.StringVar(&importFilter.minSize, "minSize", "", "Import files not smaller than the size, e.g. '100K' or '1.5G'")
+	flags.StringVar(&importFilter.maxSize, "maxSize", "", "Import files not bigger than the size, e.g. '4G'")
+	flags.StringSliceVar(&importFilter.includePaths, "include", nil, "Import files matched by the globs (relative to device dir), e.g. '100GOPRO/*.MP4' or '**/*.JPG'")
+	flags.StringSliceVar(&importFilter.excludePaths, "exclude", nil, "Do not import files matched by the globs (relative to device dir)")
+}
+
+// buildFileFilter converts import filter args into device file filter
+func buildFileFilter(args importFilterArgs, now time.Time) (mtp.FileFilter, error) {
+	result := mtp.FileFilter{
+		IncludeExt:   args.ext,
+		ExcludeExt:   args.excludeExt,
+		IncludePaths: args.includePaths,
+		ExcludePaths: args.excludePaths,
+	}
+
+	var err error
+	if args.last != "" {
+		if args.since != "" {
+			return result, fmt.Errorf("--last and --since args can not be used together")
+		}
+		period, err := parseDuration(args.last)
+		if err != nil || period <= 0 {
+			return result, fmt.Errorf("invalid --last '%s' period", args.last)
+		}
+		result.Since = now.Add(-period)
+	}
+	if args.since != "" {
+		if result.Since, err = parseDate(args.since, false); err != nil {
+			return result, fmt.Errorf("invalid --since date: %w", err)
+		}
+	}
+	if args.until != "" {
+		if result.Until, err = parseDate(args.until, true); err != nil {
+			return result, fmt.Errorf("invalid --until date: %w", err)
+		}
+	}
+	if !result.Since.IsZero() && !result.Until.IsZero() && !result.Since.Before(result.Until) {
+		return result, fmt.Errorf("--since date should be before --until date")
+	}
+
+	if args.minSize != "" {
+		if result.MinSize, err = mtp.ParseSize(args.minSize); err != nil {
+			return result, fmt.Errorf("invalid --minSize: %w", err)
+		}
+	}
+	if args.maxSize != "" {
+		if result.MaxSize, err = mtp.ParseSize(args.maxSize); err != nil {
+			return result, fmt.Errorf("invalid --maxSize: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// parseDate parses local date with optional time. If endOfDay is true date without time means the next day start
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for i, layout := range dateLayouts {
+		date, err := time.ParseInLocation(layout, value, time.Local)
+		if err != nil {
+			continue
+		}
+		if i == 0 && endOfDay {
+			date = date.AddDate(0, 0, 1)
+		}
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("unknown '%s' date format, 'YYYY-MM-DD' or 'YYYY-MM-DD hh:mm' is expected", value)
+}
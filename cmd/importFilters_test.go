@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildFileFilter(t *testing.T) {
+	r := require.New(t)
+	now := time.Date(2024, 5, 20, 12, 0, 0, 0, time.Local)
+
+	filter, err := buildFileFilter(importFilterArgs{}, now)
+	r.NoError(err)
+	r.True(filter.IsEmpty())
+
+	filter, err = buildFileFilter(importFilterArgs{
+		since:        "2024-05-18",
+		until:        "2024-05-19",
+		ext:          []string{"mp4"},
+		excludeExt:   []string{"lrv"},
+		minSize:      "1K",
+		maxSize:      "4G",
+		includePaths: []string{"100GOPRO/*"},
+		excludePaths: []string{"**/*.THM"},
+	}, now)
+	r.NoError(err)
+	r.Equal(time.Date(2024, 5, 18, 0, 0, 0, 0, time.Local), filter.Since)
+	r.Equal(time.Date(2024, 5, 20, 0, 0, 0, 0, time.Local), filter.Until)
+	r.Equal([]string{"mp4"}, filter.IncludeExt)
+	r.Equal([]string{"lrv"}, filter.ExcludeExt)
+	r.Equal(int64(1024), filter.MinSize)
+	r.Equal(int64(4<<30), filter.MaxSize)
+	r.Equal([]string{"100GOPRO/*"}, filter.IncludePaths)
+	r.Equal([]string{"**/*.THM"}, filter.ExcludePaths)
+
+	filter, err = buildFileFilter(importFilterArgs{last: "3d", until: "2024-05-20 10:30"}, now)
+	r.NoError(err)
+	r.Equal(time.Date(2024, 5, 17, 12, 0, 0, 0, time.Local), filter.Since)
+	r.Equal(time.Date(2024, 5, 20, 10, 30, 0, 0, time.Local), filter.Until)
+}
+
+func TestBuildFileFilter_Errors(t *testing.T) {
+	now := time.Now()
+
+	for name, args := range map[string]importFilterArgs{
+		"last and since": {last: "3d", since: "2024-05-18"},
+		"bad last":       {last: "soon"},
+		"bad since":      {since: "18.05.2024"},
+		"bad until":      {until: "tomorrow"},
+		"empty range":    {since: "2024-05-19", until: "2024-05-18"},
+		"bad min size":   {minSize: "big"},
+		"bad max size":   {maxSize: "-1G"},
+	} {
+		_, err := buildFileFilter(args, now)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestImportCmd_FilterFlags(t *testing.T) {
+	for _, name := range []string{"since", "until", "last", "ext", "excludeExt", "minSize", "maxSize", "include", "exclude"} {
+		assert.NotNil(t, goproCmd.InheritedFlags().Lookup(name), "'%s' flag should be available for import commands", name)
+	}
+}
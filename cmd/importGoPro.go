@@ -18,8 +18,6 @@ limitations under the License.
 package cmd
 
 import (
-	"os"
-
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -28,6 +26,8 @@ import (
 
 const (
 	cfgImportGoProDefaultDst = "import.gopro.default.targetDir"
+	cfgImportGoProTelemetry  = "import.gopro.default.telemetry"
+	cfgImportGoProMerge      = "import.gopro.default.mergeChapters"
 )
 
 // goproCmd represents the gopro command
@@ -41,85 +41,18 @@ var goproCmd = &cobra.Command{
 	'import.gopro.default.targetDir' configuration property`,
 	Args:    cobra.RangeArgs(0, 1),
 	Aliases: []string{"GoPro"},
-	Run: func(cmd *cobra.Command, args []string) {
-		printCommandArgs(cmd, args)
-
-		log.Infof("src: 'GoPro' media")
-
-		dstDir := extractPath(args, 0, "")
-		if dstDir == "" {
-			log.Infof("No args for targetDir was specified. Reading '%s' configuration", cfgImportGoProDefaultDst)
-			dstDir = viper.GetString(cfgImportGoProDefaultDst)
-			if dstDir == "" {
-				log.Errorf("No target dir was specified")
-				os.Exit(1)
-			}
-		}
-		log.Infof("dst: '%s'", dstDir)
-
-		log.Infof("dry ryn: %v", DryRun)
-
-		src, err := mtp.LoadGoProVideos(dstDir, DryRun)
-		if err != nil {
-			log.Errorf("Unable to copy GoPro files: %v", err)
-			os.Exit(1)
-		}
-		defer removeDir(src, DryRun)
-		log.Infof("Files were downloaded to: %v. Moving to target folder...", src)
-
-		tagName := "FileName"
-		if DryRun {
-			tagName = "TestName"
-		}
-
-		exifTool := getExifTool()
-
-		//Images
-		imgArgs := exifTool.newArgs()
-		if !DryRun {
-			imgArgs.changeFileDate("CreateDate")
-		}
-		imgArgs.changeTag(tagName, "CreateDate")
-		imgArgs.forDateFormat(dstDir + "\\%Y.%m.%d\\src\\IMG_%Y%m%d_%H%M%S%%-c.%%e")
-		imgArgs.forImages()
-		imgArgs.recursively()
-		imgArgs.src(src)
-
-		exifTool.exec()
-
-		//Video
-		vidArgs := exifTool.newArgs()
-		if !DryRun {
-			vidArgs.changeFileDate("CreateDate")
-		}
-		vidArgs.changeTag(tagName, "CreateDate")
-		vidArgs.forDateFormat(dstDir + "\\%Y.%m.%d\\src\\VID_%Y%m%d_%H%M%S%%-c.%%e")
-		vidArgs.forVideoMp4()
-		vidArgs.recursively()
-		vidArgs.src(src)
-
-		exifTool.exec()
-
-		//Video Preview
-		vidPreviewArgs := exifTool.newArgs()
-		if !DryRun {
-			vidPreviewArgs.changeFileDate("CreateDate")
-		}
-		vidPreviewArgs.changeTag(tagName, "CreateDate")
-		vidPreviewArgs.forDateFormat(dstDir + "\\%Y.%m.%d\\src\\VID_%Y%m%d_%H%M%S%%-c.preview.mp4")
-		vidPreviewArgs.forVideoLrv()
-		vidPreviewArgs.recursively()
-		vidPreviewArgs.src(src)
-
-		exifTool.exec()
-
-		removeFiles(src, "leinfo.sav")
-		removeFiles(src, "**\\*.THM")
-	},
+	Run: exitOnError(func(cmd *cobra.Command, args []string) error {
+		return runProfileImport(cmd, mtp.GoProProfile.Name, args)
+	}),
 }
 
 func init() {
 	importCmd.AddCommand(goproCmd)
 
 	viper.SetDefault(cfgImportGoProDefaultDst, "")
+
+	goproCmd.Flags().StringSlice("telemetry", nil, "Write telemetry of imported videos next to them, 'gpx' and/or 'csv' formats")
+	viper.BindPFlag(cfgImportGoProTelemetry, goproCmd.Flags().Lookup("telemetry"))
+	goproCmd.Flags().Bool("mergeChapters", false, "Join chapters of each recording into a single video using ffmpeg")
+	viper.BindPFlag(cfgImportGoProMerge, goproCmd.Flags().Lookup("mergeChapters"))
 }
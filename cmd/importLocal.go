@@ -18,6 +18,7 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
 	"path"
 
 	"github.com/spf13/cobra"
@@ -35,7 +36,7 @@ var importLocal = &cobra.Command{
 	By default creates subdirectories by dates and  keep original file name.
 	Combination of -f . -r flags and same src and dst dirs may be used to corrent file names and creation date`,
 	Args: cobra.RangeArgs(1, 2),
-	Run: func(cmd *cobra.Command, args []string) {
+	Run: exitOnError(func(cmd *cobra.Command, args []string) error {
 		printCommandArgs(cmd, args)
 
 		src := extractPath(args, 0, ".")
@@ -74,11 +75,13 @@ var importLocal = &cobra.Command{
 		}
 		imgArgs.changeTag(tagName, "CreateDate")
 		imgArgs.forDateFormat(path.Join(dstDir, dstSubDir, imgFileName))
-		imgArgs.forImages()
+		if err := imgArgs.forMedia(mediaImage, mediaRaw); err != nil {
+			return err
+		}
 		imgArgs.recursively()
 		imgArgs.src(src)
 
-		exifTool.exec()
+		result, err := exifTool.exec()
 
 		//Video
 		vidArgs := exifTool.newArgs()
@@ -87,12 +90,16 @@ var importLocal = &cobra.Command{
 		}
 		vidArgs.changeTag(tagName, "CreateDate")
 		vidArgs.forDateFormat(path.Join(dstDir, dstSubDir, vidFileName))
-		vidArgs.forVideoMp4()
+		if err := vidArgs.forMedia(mediaVideo); err != nil {
+			return err
+		}
 		vidArgs.recursively()
 		vidArgs.src(src)
 
-		exifTool.exec()
-	},
+		vidResult, vidErr := exifTool.exec()
+		result.add(vidResult)
+		return result.report(errors.Join(err, vidErr))
+	}),
 }
 
 func init() {
@@ -0,0 +1,143 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/redrathnure/media-tool/cmd/metadata"
+)
+
+// nativeFile is a downloaded file which date is read without exiftool
+type nativeFile struct {
+	destination string
+	date        metadata.Date
+}
+
+// readNativeDate reads date tag of JPEG, TIFF based RAW and MP4/MOV files without exiftool. Returns false
+// if native dates are disabled, the file format is not supported or the file has no such tag
+func readNativeDate(filePath string, dateTag string) (metadata.Date, bool) {
+	if !viper.GetBool(cfgImportNativeDates) || !metadata.IsSupported(filePath) {
+		return metadata.Date{}, false
+	}
+
+	date, err := metadata.ReadDate(filePath, dateTag)
+	if err != nil {
+		log.Debugf("Unable to read '%v' of '%v', exiftool is used: %v", dateTag, filePath, err)
+		return metadata.Date{}, false
+	}
+	return date, true
+}
+
+// nativeDestinations calculates absolute destinations of downloaded files which rule dates are read natively.
+// Files of skip map (e.g. GoPro chapters) are excluded, their destinations are treated as used. Rules with
+// directory naming ('%%d') or date codes which are not rendered natively and files which names are taken are left for exiftool
+func (profile importProfile) nativeDestinations(src string, dstDir string, skip map[string]string) map[string]nativeFile {
+	used := make(map[string]bool, len(skip))
+	for _, destination := range skip {
+		if relPath, err := filepath.Rel(dstDir, destination); err == nil {
+			used[strings.ToLower(filepath.ToSlash(relPath))] = true
+		}
+	}
+
+	result := make(map[string]nativeFile)
+	for _, path := range listFiles(src) {
+		if _, exists := skip[path]; exists {
+			continue
+		}
+		rule, exists := profile.findRule(path)
+		if !exists || strings.Contains(rule.Naming, "%%d") || !isNativeNaming(rule.Naming) {
+			continue
+		}
+		date, exists := readNativeDate(path, rule.dateTag())
+		if !exists {
+			continue
+		}
+
+		destination, exists := predictDestination(dstDir, rule.Naming, date.Time, path, used)
+		if !exists {
+			continue
+		}
+		result[path] = nativeFile{destination: filepath.Join(dstDir, filepath.FromSlash(destination)), date: date}
+	}
+	return result
+}
+
+// moveNatively moves files to their native destinations and sets file dates like exiftool 'FileModifyDate' tag does
+func moveNatively(files map[string]nativeFile) error {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	errs := make([]error, 0)
+	for _, path := range paths {
+		if err := placeNatively(path, files[path].destination, files[path].date.Instant()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(paths) > 0 {
+		log.Infof("%v file(s) were moved using native date reader", len(paths)-len(errs))
+	}
+	return errors.Join(errs...)
+}
+
+// placeNatively renames the file to the destination (which should not exist) and updates its modification and
+// creation (where supported) times like exiftool 'FileModifyDate' and 'FileCreateDate' tags do
+func placeNatively(path string, destination string, date time.Time) error {
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		return fmt.Errorf("unable to move '%v': '%v' already exists", path, destination)
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return fmt.Errorf("unable to move '%v': %w", path, err)
+	}
+	if err := os.Rename(path, destination); err != nil {
+		return fmt.Errorf("unable to move '%v': %w", path, err)
+	}
+	log.Debugf("'%v' --> '%v'", path, destination)
+
+	if err := os.Chtimes(destination, date, date); err != nil {
+		log.Warningf("Unable to update '%v' file dates: %v", destination, err)
+	}
+	if err := setFileCreateTime(destination, date); err != nil {
+		log.Warningf("Unable to update '%v' file creation date: %v", destination, err)
+	}
+	return nil
+}
+
+// listFiles returns sorted paths of all files in the dir and its subdirs
+func listFiles(dir string) []string {
+	result := make([]string, 0)
+	filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			result = append(result, filepath.Clean(path))
+		}
+		return nil
+	})
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDatedMovie builds MP4 fixture with 'mvhd' creation time
+func testDatedMovie(created time.Time) []byte {
+	movie := testMovie(time.Second)
+	seconds := created.Sub(time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)) / time.Second
+	binary.BigEndian.PutUint32(movie[8+12:], uint32(seconds))
+	return movie
+}
+
+// enableNativeDates sets native dates config which may be reset by other tests
+func enableNativeDates(t *testing.T) {
+	viper.Set(cfgImportNativeDates, true)
+	t.Cleanup(func() { viper.Set(cfgImportNativeDates, true) })
+}
+
+func TestImportProfile_MoveNatively(t *testing.T) {
+	r := require.New(t)
+	enableNativeDates(t)
+	src, dstDir := t.TempDir(), t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(src, "GOPR0001.MP4"), testDatedMovie(time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC)), 0644))
+	r.NoError(os.WriteFile(filepath.Join(src, "GOPR0002.MP4"), testDatedMovie(time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC)), 0644))
+
+	testTool := newTestExifTool()
+	defer testTool.clear()
+
+	profile := importProfile{
+		Rules: []importRule{
+			{Media: []string{"mp4"}, Naming: "%Y.%m.%d/VID_%Y%m%d_%H%M%S%%-c.%%e"},
+		},
+	}
+	_, err := profile.move(src, dstDir)
+
+	r.NoError(err)
+	assert.False(t, testTool.execCalled)
+	assert.NoFileExists(t, filepath.Join(src, "GOPR0001.MP4"))
+	date := time.Date(2024, 5, 18, 10, 30, 15, 0, time.Local)
+	info, err := os.Stat(filepath.Join(dstDir, "2024.05.18", "VID_20240518_103015.MP4"))
+	r.NoError(err)
+	assert.True(t, date.Equal(info.ModTime()))
+	assert.FileExists(t, filepath.Join(dstDir, "2024.05.18", "VID_20240518_103015-1.MP4"))
+}
+
+func TestImportProfile_MoveNativelyFallsBackToExifTool(t *testing.T) {
+	r := require.New(t)
+	src, dstDir := t.TempDir(), t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(src, "GOPR0001.MP4"), testMovie(time.Second), 0644))
+
+	testTool := newTestExifTool()
+	defer testTool.clear()
+
+	profile := importProfile{
+		Rules: []importRule{
+			{Media: []string{"mp4"}, Naming: "%Y.%m.%d/VID_%Y%m%d_%H%M%S%%-c.%%e"},
+		},
+	}
+	profile.move(src, dstDir)
+
+	assert.True(t, testTool.execCalled)
+	assert.Contains(t, testTool.args.args, "-FileName<CreateDate")
+	assert.FileExists(t, filepath.Join(src, "GOPR0001.MP4"))
+}
+
+func TestImportProfile_NativeDestinations(t *testing.T) {
+	r := require.New(t)
+	enableNativeDates(t)
+	src, dstDir := t.TempDir(), t.TempDir()
+	created := time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC)
+	for _, name := range []string{"GOPR0001.MP4", "GOPR0002.MP4", "00001.MTS"} {
+		r.NoError(os.WriteFile(filepath.Join(src, name), testDatedMovie(created), 0644))
+	}
+	chapter := filepath.Join(src, "GOPR0002.MP4")
+
+	profile := importProfile{
+		Rules: []importRule{
+			{Media: []string{"mp4", "avchd"}, Naming: "VID_%Y%m%d_%H%M%S%%-c.%%e"},
+		},
+	}
+	result := profile.nativeDestinations(src, dstDir, map[string]string{chapter: filepath.Join(dstDir, "VID_20240518_103015.MP4")})
+
+	r.Len(result, 1)
+	assert.Equal(t, filepath.Join(dstDir, "VID_20240518_103015-1.MP4"), result[filepath.Join(src, "GOPR0001.MP4")].destination)
+
+	viper.Set(cfgImportNativeDates, false)
+	assert.Empty(t, profile.nativeDestinations(src, dstDir, nil))
+}
+
+func TestImportProfile_NativeDestinationsSkipUnsupportedDateCodes(t *testing.T) {
+	r := require.New(t)
+	enableNativeDates(t)
+	src, dstDir := t.TempDir(), t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(src, "GOPR0001.MP4"), testDatedMovie(time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC)), 0644))
+
+	profile := importProfile{
+		Rules: []importRule{
+			{Media: []string{"mp4"}, Naming: "%Y/week_%U/VID_%Y%m%d_%H%M%S%%-c.%%e"},
+		},
+	}
+
+	assert.Empty(t, profile.nativeDestinations(src, dstDir, nil))
+}
@@ -0,0 +1,177 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+// planOut is path of JSON plan document. Files are not imported if it is set
+var planOut string
+
+// testNamePattern matches exiftool TestName output, e.g. "'/tmp/GX010001.MP4' --> '/video/VID_20240518_103015.MP4'"
+var testNamePattern = regexp.MustCompile(`^'(.+)' --> '(.+)'$`)
+
+// exportImportPlan downloads matched files to a temp dir (keeping them on devices), calculates their
+// destinations according to profile rules and saves the result as JSON plan document. The temp dir is recorded
+// in the plan and kept, so 'import apply' places the downloaded copies instead of reading devices again
+func exportImportPlan(profile importProfile, deviceProfile mtp.DeviceProfile, dstDir string, options mtp.ImportOptions, planPath string) error {
+	plan := mtp.NewImportPlan(profile.Name, dstDir)
+	options.KeepSource = true
+	options.Plan = plan
+
+	src, err := mtp.LoadFromAllWpd(getMediaSource(), deviceProfile, dstDir, options)
+	if err != nil {
+		return fmt.Errorf("unable to copy '%s' files: %w", profile.Name, err)
+	}
+	log.Infof("Files were downloaded to: %v. Calculating destinations...", src)
+
+	destinations := profile.testNames(src, dstDir)
+	setPlanDestinations(plan, destinations)
+
+	if err := plan.Write(planPath); err != nil {
+		removeImportDir(src, true)
+		return fmt.Errorf("unable to write '%s' plan: %w", planPath, err)
+	}
+	log.Infof("Import plan with %v file(s) was written to '%s'. Review it and run 'import apply %s'", len(plan.Files), planPath, planPath)
+	log.Infof("Downloaded files are kept in '%s' until the plan is applied. Remove the directory if the plan is discarded", src)
+	return nil
+}
+
+// testNames runs profile rules in test mode and returns new names of downloaded files
+func (profile importProfile) testNames(src string, dstDir string) map[string]string {
+	result := make(map[string]string)
+	if profile.hasChapterRules() {
+		result = profile.goProChapterDestinations(src, dstDir)
+	}
+	for path, file := range profile.nativeDestinations(src, dstDir, result) {
+		result[path] = file.destination
+	}
+
+	exifTool := getExifTool()
+	for _, rule := range profile.Rules {
+		profile.addRuleArgs(exifTool, rule, "TestName", src, dstDir)
+		output, err := exifTool.execOutput()
+		if err != nil {
+			log.Warningf("ExifTool exec error: '%s'", err)
+		}
+		for localPath, destination := range parseTestNames(output) {
+			if _, exists := result[localPath]; !exists {
+				result[localPath] = destination
+			}
+		}
+	}
+	return result
+}
+
+// parseTestNames extracts old and new file names from exiftool TestName output
+func parseTestNames(output string) map[string]string {
+	result := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		match := testNamePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		result[filepath.Clean(filepath.FromSlash(match[1]))] = filepath.Clean(filepath.FromSlash(match[2]))
+	}
+	return result
+}
+
+// setPlanDestinations sets destinations relative to the plan target dir. Files without destination
+// are not moved by any rule and are excluded from the plan. Duplicated names get '-N' suffix
+func setPlanDestinations(plan *mtp.ImportPlan, destinations map[string]string) {
+	used := make(map[string]bool)
+	files := make([]*mtp.PlannedFile, 0, len(plan.Files))
+	for _, file := range plan.Files {
+		destination, exists := destinations[filepath.Clean(file.LocalPath)]
+		if !exists {
+			log.Infof("'%v' is not moved by profile rules and was excluded from the plan", file.Path)
+			os.Remove(file.LocalPath)
+			continue
+		}
+
+		relPath, err := filepath.Rel(plan.TargetDir, destination)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			log.Warningf("'%v' destination '%v' is outside of the target dir and was excluded from the plan", file.Path, destination)
+			os.Remove(file.LocalPath)
+			continue
+		}
+
+		file.Destination = uniqueDestination(filepath.ToSlash(relPath), used)
+		files = append(files, file)
+	}
+	plan.Files = files
+}
+
+func uniqueDestination(destination string, used map[string]bool) string {
+	ext := filepath.Ext(destination)
+	base := strings.TrimSuffix(destination, ext)
+	result := destination
+	for i := 1; used[strings.ToLower(result)]; i++ {
+		result = fmt.Sprintf("%s-%v%s", base, i, ext)
+	}
+	used[strings.ToLower(result)] = true
+	return result
+}
+
+// placePlannedFiles moves downloaded files to their plan destinations the same way as import does: file dates are
+// updated by the rule date tag, GoPro chapters are merged and their telemetry is written according to the profile
+func (profile importProfile) placePlannedFiles(plan *mtp.ImportPlan) (exifToolResult, error) {
+	errs := make([]error, 0)
+	destinations := make(map[string]string, len(plan.Files))
+	for _, file := range plan.Files {
+		if file.LocalPath == "" {
+			errs = append(errs, fmt.Errorf("'%v' was not downloaded", file.Path))
+			continue
+		}
+		destinations[file.LocalPath] = plan.GetDestinationPath(file)
+	}
+
+	placed, result, err := profile.placeFiles(destinations)
+	errs = append(errs, err)
+	if missing := len(destinations) - len(placed); missing > 0 {
+		errs = append(errs, fmt.Errorf("%v file(s) were not placed to the target dir", missing))
+	}
+
+	if profile.hasChapterRules() {
+		chapters := make(map[string]string)
+		for path, destination := range placed {
+			if rule, exists := profile.findRule(path); exists && rule.Chapters {
+				chapters[path] = destination
+			}
+		}
+
+		files := placedFiles(chapters)
+		if profile.MergeChapters {
+			files = profile.mergeChapters(destinations, chapters)
+		}
+		profile.writeTelemetry(files)
+	}
+	return result, errors.Join(errs...)
+}
+
+func init() {
+	importCmd.PersistentFlags().StringVar(&planOut, "plan-out", "", "Do not import, save import plan (files and their destinations) to JSON file instead")
+}
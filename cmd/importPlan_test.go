@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+func TestImportApplyCmd_CommandStructure(t *testing.T) {
+	assert.Equal(t, "apply", importApplyCmd.Name())
+	assert.Equal(t, "media-tool import apply", importApplyCmd.CommandPath())
+	assert.Error(t, importApplyCmd.Args(importApplyCmd, []string{}))
+	assert.NoError(t, importApplyCmd.Args(importApplyCmd, []string{"plan.json"}))
+	assert.NotNil(t, importCmd.PersistentFlags().Lookup("plan-out"))
+}
+
+func TestParseTestNames(t *testing.T) {
+	output := "======== /tmp/import/0/GX010001.MP4 [1/2]\n" +
+		"'/tmp/import/0/GX010001.MP4' --> '/video/2024.05.18/src/VID_20240518_103015.MP4'\n" +
+		"'/tmp/import/0/GX010002.MP4' --> '/video/2024.05.18/src/VID_20240518_113015.MP4'\r\n" +
+		"    2 image files updated\n"
+
+	result := parseTestNames(output)
+
+	assert.Equal(t, map[string]string{
+		filepath.FromSlash("/tmp/import/0/GX010001.MP4"): filepath.FromSlash("/video/2024.05.18/src/VID_20240518_103015.MP4"),
+		filepath.FromSlash("/tmp/import/0/GX010002.MP4"): filepath.FromSlash("/video/2024.05.18/src/VID_20240518_113015.MP4"),
+	}, result)
+}
+
+func TestSetPlanDestinations(t *testing.T) {
+	targetDir := filepath.FromSlash("/video")
+	plan := mtp.NewImportPlan("gopro", targetDir)
+	plan.Files = []*mtp.PlannedFile{
+		{Path: "/DCIM/100GOPRO/GX010001.MP4", LocalPath: filepath.FromSlash("/tmp/0/GX010001.MP4")},
+		{Path: "/DCIM/100GOPRO/GX010002.MP4", LocalPath: filepath.FromSlash("/tmp/0/GX010002.MP4")},
+		{Path: "/DCIM/100GOPRO/GX010001.THM", LocalPath: filepath.FromSlash("/tmp/0/GX010001.THM")},
+		{Path: "/DCIM/100GOPRO/GX010003.MP4", LocalPath: filepath.FromSlash("/tmp/0/GX010003.MP4")},
+	}
+	destinations := map[string]string{
+		filepath.FromSlash("/tmp/0/GX010001.MP4"): filepath.FromSlash("/video/2024.05.18/VID_20240518_103015.MP4"),
+		filepath.FromSlash("/tmp/0/GX010002.MP4"): filepath.FromSlash("/video/2024.05.18/VID_20240518_103015.MP4"),
+		filepath.FromSlash("/tmp/0/GX010003.MP4"): filepath.FromSlash("/other/VID_20240518_103015.MP4"),
+	}
+
+	setPlanDestinations(plan, destinations)
+
+	require.Len(t, plan.Files, 2)
+	assert.Equal(t, "2024.05.18/VID_20240518_103015.MP4", plan.Files[0].Destination)
+	assert.Equal(t, "2024.05.18/VID_20240518_103015-1.MP4", plan.Files[1].Destination)
+}
+
+func TestPlacePlannedFiles(t *testing.T) {
+	r := require.New(t)
+	enableNativeDates(t)
+
+	created := time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC)
+	tempDir := t.TempDir()
+	targetDir := t.TempDir()
+	r.NoError(os.WriteFile(filepath.Join(tempDir, "GX010001.MP4"), testDatedMovie(created), 0644))
+	r.NoError(os.WriteFile(filepath.Join(tempDir, "GX010002.MP4"), testDatedMovie(created), 0644))
+	r.NoError(os.WriteFile(filepath.Join(targetDir, "existing.MP4"), []byte("existing"), 0644))
+
+	plan := mtp.NewImportPlan("gopro", targetDir)
+	plan.Files = []*mtp.PlannedFile{
+		{Path: "/DCIM/GX010001.MP4", LocalPath: filepath.Join(tempDir, "GX010001.MP4"), Destination: "2024.05.18/VID_1.MP4"},
+		{Path: "/DCIM/GX010002.MP4", LocalPath: filepath.Join(tempDir, "GX010002.MP4"), Destination: "existing.MP4"},
+		{Path: "/DCIM/GX010003.MP4", Destination: "2024.05.18/VID_3.MP4"},
+	}
+
+	testTool := newTestExifTool()
+	defer testTool.clear()
+	_, err := builtInImportProfiles["gopro"].placePlannedFiles(plan)
+
+	r.ErrorContains(err, "'/DCIM/GX010003.MP4' was not downloaded")
+	r.ErrorContains(err, "1 file(s) were not placed to the target dir")
+	r.False(testTool.execCalled)
+	info, err := os.Stat(filepath.Join(targetDir, "2024.05.18", "VID_1.MP4"))
+	r.NoError(err)
+	r.True(time.Date(2024, 5, 18, 10, 30, 15, 0, time.Local).Equal(info.ModTime()), "file date is updated as by import")
+	r.NoFileExists(filepath.Join(tempDir, "GX010001.MP4"))
+	r.FileExists(filepath.Join(tempDir, "GX010002.MP4"))
+	content, _ := os.ReadFile(filepath.Join(targetDir, "existing.MP4"))
+	r.Equal("existing", string(content))
+}
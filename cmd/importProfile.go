@@ -0,0 +1,429 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+const (
+	cfgImportProfiles = "import.profiles"
+
+	defaultImportDateTag = "CreateDate"
+)
+
+// importProfile describes which devices to import from, which device dirs to copy
+// and how downloaded files should be renamed and moved to the target dir
+type importProfile struct {
+	Name       string
+	Filter     interface{} `mapstructure:"filter"`
+	SourceDirs []string    `mapstructure:"sourceDirs"`
+	// Storages are names or indexes of device storages to import from. All storages are imported by default
+	Storages   []string     `mapstructure:"storages"`
+	TargetDir  string       `mapstructure:"targetDir"`
+	KeepSource bool         `mapstructure:"keepSource"`
+	Rules      []importRule `mapstructure:"rules"`
+	// Ignore and Junk are device file rules added to 'import.ignore' and 'import.junk' configuration
+	Ignore []string `mapstructure:"ignore"`
+	Junk   []string `mapstructure:"junk"`
+	// Telemetry are formats ('gpx', 'csv') of GoPro telemetry files written next to imported chapters
+	Telemetry []string `mapstructure:"telemetry"`
+	// MergeChapters joins chapters of GoPro recordings into a single video using ffmpeg
+	MergeChapters bool `mapstructure:"mergeChapters"`
+
+	// targetDirKey is configuration key with default target dir
+	targetDirKey string
+}
+
+// importRule renames and moves files of given media types according to date tag. Naming is exiftool
+// date format relative to target dir, e.g. '%Y.%m.%d/IMG_%Y%m%d_%H%M%S%%-c.%%e'
+type importRule struct {
+	Media   []string `mapstructure:"media"`
+	DateTag string   `mapstructure:"dateTag"`
+	Naming  string   `mapstructure:"naming"`
+	// Chapters names GoPro chapters by the date of the first chapter and adds '_NN' chapter index, e.g. 'VID_20240518_103015_02.MP4'
+	Chapters bool `mapstructure:"chapters"`
+}
+
+var builtInImportProfiles = map[string]importProfile{
+	mtp.GoProProfile.Name: {
+		Name:       mtp.GoProProfile.Name,
+		Filter:     mtp.GoProProfile.Name,
+		SourceDirs: mtp.GoProProfile.DeviceDirs,
+		Rules: []importRule{
+			{Media: []string{"images"}, Naming: "%Y.%m.%d/src/IMG_%Y%m%d_%H%M%S%%-c.%%e"},
+			{Media: []string{"mp4"}, Naming: "%Y.%m.%d/src/VID_%Y%m%d_%H%M%S%%-c.%%e", Chapters: true},
+			{Media: []string{"lrv"}, Naming: "%Y.%m.%d/src/VID_%Y%m%d_%H%M%S%%-c.preview.mp4", Chapters: true},
+		},
+		Junk:         []string{"leinfo.sav", "*.THM"},
+		targetDirKey: cfgImportGoProDefaultDst,
+	},
+	mtp.SdPhotosProfile.Name: {
+		Name:       mtp.SdPhotosProfile.Name,
+		Filter:     mtp.SdPhotosProfile.Name,
+		SourceDirs: mtp.SdPhotosProfile.DeviceDirs,
+		Rules: []importRule{
+			{Media: []string{"images", mediaVideo}, Naming: "%Y.%m.%d/%%f%%-c.%%e"},
+		},
+		targetDirKey: cfgImportSdPhotosDefaultDst,
+	},
+	mtp.CamVideoProfile.Name: {
+		Name:       mtp.CamVideoProfile.Name,
+		Filter:     mtp.CamVideoProfile.Name,
+		SourceDirs: mtp.CamVideoProfile.DeviceDirs,
+		Rules: []importRule{
+			{Media: []string{"avchd"}, DateTag: "DateTimeOriginal", Naming: "%Y.%m.%d/VID_%Y%m%d_%H%M%S%%-c.%%e"},
+		},
+		targetDirKey: cfgImportCamVideoDefaultDst,
+	},
+}
+
+// getImportProfile finds import profile by name. Profiles from 'import.profiles' configuration override built-in ones
+func getImportProfile(name string) (importProfile, error) {
+	name = strings.ToLower(name)
+	key := cfgImportProfiles + "." + name
+
+	if !viper.IsSet(key) {
+		profile, exists := builtInImportProfiles[name]
+		if !exists {
+			return importProfile{}, fmt.Errorf("unknown '%s' import profile", name)
+		}
+		profile.Storages = viper.GetStringSlice(profile.defaultKey("storages"))
+		profile.Telemetry = viper.GetStringSlice(profile.defaultKey("telemetry"))
+		profile.MergeChapters = viper.GetBool(profile.defaultKey("mergeChapters"))
+		if err := validateTelemetryFormats(profile.Telemetry); err != nil {
+			return importProfile{}, fmt.Errorf("invalid '%s' configuration: %w", profile.defaultKey("telemetry"), err)
+		}
+		return profile, nil
+	}
+
+	profile := importProfile{}
+	if err := viper.UnmarshalKey(key, &profile); err != nil {
+		return importProfile{}, fmt.Errorf("unable to read '%s' configuration: %w", key, err)
+	}
+	profile.Name = name
+	profile.targetDirKey = key + ".targetDir"
+	if len(profile.SourceDirs) == 0 {
+		profile.SourceDirs = []string{mtp.DCIM_DIR}
+	}
+
+	if err := profile.validate(); err != nil {
+		if _, isFilterErr := err.(*mtp.FilterError); isFilterErr {
+			return importProfile{}, fmt.Errorf("invalid filter: %w", err)
+		}
+		return importProfile{}, fmt.Errorf("invalid '%s' configuration: %w", key, err)
+	}
+	return profile, nil
+}
+
+// getImportProfileNames returns names of built-in and configured import profiles
+func getImportProfileNames() []string {
+	names := make(map[string]bool)
+	for name := range builtInImportProfiles {
+		names[name] = true
+	}
+	for name := range viper.GetStringMap(cfgImportProfiles) {
+		names[strings.ToLower(name)] = true
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (profile importProfile) validate() error {
+	if profile.Filter == nil {
+		return fmt.Errorf("filter is required")
+	}
+	if _, err := mtp.ParseDeviceFilter(profile.filterKey(), profile.Filter); err != nil {
+		return err
+	}
+	if len(profile.Rules) == 0 {
+		return fmt.Errorf("at least one rule is required")
+	}
+	if _, err := mtp.ParseScanRules(profile.Ignore, profile.Junk); err != nil {
+		return err
+	}
+	if err := validateTelemetryFormats(profile.Telemetry); err != nil {
+		return err
+	}
+	for i, rule := range profile.Rules {
+		if rule.Naming == "" {
+			return fmt.Errorf("rules[%v]: naming is required", i)
+		}
+		if len(rule.Media) == 0 {
+			return fmt.Errorf("rules[%v]: media is required", i)
+		}
+		for _, media := range rule.Media {
+			if _, err := getMediaType(media); err != nil {
+				return fmt.Errorf("rules[%v]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// defaultKey is configuration key with a property of built-in profile, e.g. 'import.gopro.default.storages'
+func (profile importProfile) defaultKey(property string) string {
+	return strings.TrimSuffix(profile.targetDirKey, "targetDir") + property
+}
+
+func (profile importProfile) filterKey() string {
+	return cfgImportProfiles + "." + profile.Name + ".filter"
+}
+
+// toDeviceProfile converts import profile into device profile used for files downloading
+func (profile importProfile) toDeviceProfile() (mtp.DeviceProfile, error) {
+	filter, err := mtp.ParseDeviceFilter(profile.filterKey(), profile.Filter)
+	if err != nil {
+		return mtp.DeviceProfile{}, err
+	}
+
+	scanRules, err := profile.getScanRules()
+	if err != nil {
+		return mtp.DeviceProfile{}, err
+	}
+
+	deviceDirs := make([]string, 0, len(profile.SourceDirs))
+	for _, dir := range profile.SourceDirs {
+		deviceDirs = append(deviceDirs, filepath.FromSlash(strings.Trim(dir, "/")))
+	}
+
+	return mtp.DeviceProfile{
+		Name:         profile.Name,
+		DeviceFilter: filter,
+		DeviceDirs:   deviceDirs,
+		KeepSource:   profile.KeepSource,
+		Scan:         scanRules,
+		Storages:     profile.Storages,
+	}, nil
+}
+
+// getScanRules combines global and profile ignore and junk rules
+func (profile importProfile) getScanRules() (mtp.ScanRules, error) {
+	global, err := mtp.ParseScanRules(viper.GetStringSlice(cfgImportIgnore), viper.GetStringSlice(cfgImportJunk))
+	if err != nil {
+		return mtp.ScanRules{}, fmt.Errorf("invalid 'import' configuration: %w", err)
+	}
+	own, err := mtp.ParseScanRules(profile.Ignore, profile.Junk)
+	if err != nil {
+		return mtp.ScanRules{}, err
+	}
+
+	return mtp.ScanRules{
+		Ignore: append(global.Ignore, own.Ignore...),
+		Junk:   append(global.Junk, own.Junk...),
+	}, nil
+}
+
+// getTargetDir returns target dir from args or profile configuration
+func (profile importProfile) getTargetDir(args []string) string {
+	dstDir := extractPath(args, 0, "")
+	if dstDir != "" {
+		return dstDir
+	}
+
+	if profile.targetDirKey != "" {
+		log.Infof("No args for targetDir was specified. Reading '%s' configuration", profile.targetDirKey)
+		dstDir = viper.GetString(profile.targetDirKey)
+	}
+	if dstDir == "" {
+		dstDir = profile.TargetDir
+	}
+	return dstDir
+}
+
+// move renames downloaded files according to profile rules and moves them to the target dir. GoPro chapters
+// of chapter rules are moved first, then files which dates are read natively. Remaining files are renamed
+// by exiftool rules one by one
+func (profile importProfile) move(src string, dstDir string) (exifToolResult, error) {
+	result := exifToolResult{}
+	errs := make([]error, 0)
+
+	if profile.hasChapterRules() {
+		destinations := profile.goProChapterDestinations(src, dstDir)
+		placed, chaptersResult, err := profile.placeFiles(destinations)
+		result.add(chaptersResult)
+		errs = append(errs, err)
+
+		files := placedFiles(placed)
+		if profile.MergeChapters {
+			files = profile.mergeChapters(destinations, placed)
+		}
+		profile.writeTelemetry(files)
+	}
+
+	errs = append(errs, moveNatively(profile.nativeDestinations(src, dstDir, nil)))
+
+	exifTool := getExifTool()
+	remaining := listFiles(src)
+	for _, rule := range profile.Rules {
+		if !rule.matchesAny(remaining) {
+			continue
+		}
+		profile.addRuleArgs(exifTool, rule, "FileName", src, dstDir)
+		ruleResult, err := exifTool.exec()
+		result.add(ruleResult)
+		errs = append(errs, err)
+		remaining = listFiles(src)
+	}
+	return result, errors.Join(errs...)
+}
+
+// placeFiles renames downloaded files (e.g. GoPro chapters) to their destinations, file dates are updated by the rule
+// date tag. Files which dates are read natively are moved without exiftool. Returns destinations of files which were moved
+func (profile importProfile) placeFiles(destinations map[string]string) (map[string]string, exifToolResult, error) {
+	if len(destinations) == 0 {
+		return destinations, exifToolResult{}, nil
+	}
+
+	paths := make([]string, 0, len(destinations))
+	for path := range destinations {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	errs := make([]error, 0)
+	result := exifToolResult{}
+	exifTool := getExifTool()
+	for _, path := range paths {
+		rule, _ := profile.findRule(path)
+		if date, exists := readNativeDate(path, rule.dateTag()); exists {
+			errs = append(errs, placeNatively(path, destinations[path], date.Instant()))
+			continue
+		}
+
+		// every file is a separate command, so a failure of any of them is reported by its status
+		toolArgs := exifTool.newArgs()
+		toolArgs.changeFileDate(rule.dateTag())
+		toolArgs.setTag("FileName", destinations[path])
+		toolArgs.src(path)
+		fileResult, err := exifTool.exec()
+		result.add(fileResult)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("'%s': %w", path, err))
+		}
+	}
+
+	placed := make(map[string]string, len(destinations))
+	for path, destination := range destinations {
+		if _, err := os.Stat(destination); err == nil {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				placed[path] = destination
+			}
+		}
+	}
+	return placed, result, errors.Join(errs...)
+}
+
+// dateTag returns the rule date tag or the default one
+func (rule importRule) dateTag() string {
+	if rule.DateTag != "" {
+		return rule.DateTag
+	}
+	return defaultImportDateTag
+}
+
+func (profile importProfile) hasChapterRules() bool {
+	for _, rule := range profile.Rules {
+		if rule.Chapters {
+			return true
+		}
+	}
+	return false
+}
+
+// addRuleArgs prepares exiftool args which rename files of the rule media types. FileName tag
+// also updates file dates, TestName only prints new file names
+func (profile importProfile) addRuleArgs(exifTool *exifToolWrapper, rule importRule, tagName string, src string, dstDir string) {
+	dateTag := rule.dateTag()
+
+	ruleArgs := exifTool.newArgs()
+	if tagName == "FileName" {
+		ruleArgs.changeFileDate(dateTag)
+	}
+	ruleArgs.changeTag(tagName, dateTag)
+	ruleArgs.forDateFormat(filepath.Join(dstDir, filepath.FromSlash(rule.Naming)))
+	ruleArgs.forMedia(rule.Media...)
+	ruleArgs.recursively()
+	ruleArgs.src(src)
+}
+
+// runProfileImport downloads files from devices matched by profile and moves them to the target dir
+func runProfileImport(cmd *cobra.Command, profileName string, args []string) error {
+	printCommandArgs(cmd, args)
+
+	profile, err := getImportProfile(profileName)
+	if err != nil {
+		return err
+	}
+	log.Infof("src: '%s' media", profile.Name)
+
+	dstDir := profile.getTargetDir(args)
+	if dstDir == "" {
+		return errors.New("no target dir was specified")
+	}
+	log.Infof("dst: '%s'", dstDir)
+
+	log.Infof("dry ryn: %v", DryRun)
+
+	deviceProfile, err := profile.toDeviceProfile()
+	if err != nil {
+		return fmt.Errorf("invalid '%s' profile: %w", profile.Name, err)
+	}
+
+	options := getImportOptions()
+	defer options.Close()
+
+	if DryRun {
+		return reportDryImport(profile, deviceProfile, dstDir, options)
+	}
+	if planOut != "" {
+		return exportImportPlan(profile, deviceProfile, dstDir, options, planOut)
+	}
+
+	var moveErr error
+	options.Place = func(src string) {
+		log.Infof("Files were downloaded to: %v. Moving to target folder...", src)
+		result, err := profile.move(src, dstDir)
+		moveErr = result.report(err)
+	}
+
+	src, err := mtp.LoadFromAllWpd(getMediaSource(), deviceProfile, dstDir, options)
+	if err != nil {
+		return fmt.Errorf("unable to copy '%s' files: %w", profile.Name, err)
+	}
+	removeImportDir(src, false)
+	if moveErr != nil {
+		return fmt.Errorf("unable to move '%s' files to target folder: %w", profile.Name, moveErr)
+	}
+	return nil
+}
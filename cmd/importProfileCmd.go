@@ -0,0 +1,47 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// importProfileCmd represents the import profile command
+var importProfileCmd = &cobra.Command{
+	Use:   "profile name [targetDir]",
+	Short: "Import media using device profile",
+	Long: `Copy media from devices matched by the profile to disk. 
+	Profiles are defined in 'import.profiles.<name>' configuration, 
+	built-in profiles are 'gopro', 'sdphotos' and 'camvideo'. 
+	If no targetDir was specified application will try to read 
+	profile 'targetDir' configuration property`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: exitOnError(func(cmd *cobra.Command, args []string) error {
+		return runProfileImport(cmd, args[0], args[1:])
+	}),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return getImportProfileNames(), cobra.ShellCompDirectiveNoFileComp
+	},
+}
+
+func init() {
+	importCmd.AddCommand(importProfileCmd)
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+func TestGetImportProfile_BuiltIn(t *testing.T) {
+	r := require.New(t)
+
+	for _, deviceProfile := range []mtp.DeviceProfile{mtp.GoProProfile, mtp.SdPhotosProfile, mtp.CamVideoProfile} {
+		profile, err := getImportProfile(deviceProfile.Name)
+		r.NoError(err)
+		r.NoError(profile.validate())
+
+		result, err := profile.toDeviceProfile()
+		r.NoError(err)
+		r.Equal(deviceProfile.Name, result.Name)
+		r.Equal(deviceProfile.DeviceFilter, result.DeviceFilter)
+		r.False(result.KeepSource)
+	}
+
+	_, err := getImportProfile("unknown")
+	r.Error(err)
+}
+
+func TestGetImportProfile_Config(t *testing.T) {
+	r := require.New(t)
+	setProfileConfig(t, "dji", map[string]interface{}{
+		"filter":     map[string]interface{}{"any": []interface{}{map[string]interface{}{"name": "DJI"}}},
+		"sourceDirs": []interface{}{"DCIM/100MEDIA"},
+		"targetDir":  "/video/dji",
+		"keepSource": true,
+		"rules": []interface{}{
+			map[string]interface{}{"media": []interface{}{"mp4"}, "naming": "%Y.%m.%d/DJI_%Y%m%d_%H%M%S%%-c.%%e"},
+		},
+		"ignore":   []interface{}{"re:^\\."},
+		"junk":     []interface{}{"*.SRT"},
+		"storages": []interface{}{"SD2", "0"},
+	})
+
+	profile, err := getImportProfile("DJI")
+	r.NoError(err)
+	r.Equal("dji", profile.Name)
+	r.Equal("/video/dji", profile.getTargetDir(nil))
+	r.Equal("/other", profile.getTargetDir([]string{"/other"}))
+	r.Len(profile.Rules, 1)
+	r.Equal([]string{"mp4"}, profile.Rules[0].Media)
+
+	deviceProfile, err := profile.toDeviceProfile()
+	r.NoError(err)
+	r.Equal([]string{filepath.Join("DCIM", "100MEDIA")}, deviceProfile.DeviceDirs)
+	r.True(deviceProfile.KeepSource)
+	r.Equal(`re:^\.`, deviceProfile.Scan.Ignore[len(deviceProfile.Scan.Ignore)-1].String())
+	r.Equal("*.SRT", deviceProfile.Scan.Junk[0].String())
+	r.Equal([]string{"SD2", "0"}, deviceProfile.Storages)
+
+	r.Contains(getImportProfileNames(), "dji")
+}
+
+func TestGetImportProfile_BuiltInStorages(t *testing.T) {
+	r := require.New(t)
+	viper.Set("import.gopro.default.storages", []string{"SD2"})
+	defer viper.Set("import.gopro.default.storages", nil)
+
+	profile, err := getImportProfile("gopro")
+	r.NoError(err)
+	deviceProfile, err := profile.toDeviceProfile()
+
+	r.NoError(err)
+	r.Equal([]string{"SD2"}, deviceProfile.Storages)
+}
+
+func TestGetImportProfile_ConfigOverridesBuiltIn(t *testing.T) {
+	r := require.New(t)
+	setProfileConfig(t, "gopro", map[string]interface{}{
+		"filter": "gopro",
+		"rules": []interface{}{
+			map[string]interface{}{"media": []interface{}{"mp4"}, "naming": "%Y/%%f.%%e"},
+		},
+	})
+
+	profile, err := getImportProfile("gopro")
+
+	r.NoError(err)
+	r.Len(profile.Rules, 1)
+	r.Equal([]string{mtp.DCIM_DIR}, profile.SourceDirs)
+}
+
+func TestGetImportProfile_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile map[string]interface{}
+		err     string
+	}{
+		{"no filter", map[string]interface{}{"rules": []interface{}{}}, "filter is required"},
+		{"bad filter", map[string]interface{}{"filter": map[string]interface{}{"size": 1}}, "'import.profiles.broken.filter.size': unknown filter"},
+		{"no rules", map[string]interface{}{"filter": "gopro"}, "at least one rule is required"},
+		{"unknown media", map[string]interface{}{"filter": "gopro", "rules": []interface{}{
+			map[string]interface{}{"media": []interface{}{"hologram"}, "naming": "%%f.%%e"},
+		}}, "rules[0]: unknown 'hologram' media type"},
+		{"bad junk", map[string]interface{}{"filter": "gopro", "junk": []interface{}{"re:("}, "rules": []interface{}{
+			map[string]interface{}{"media": []interface{}{"mp4"}, "naming": "%%f.%%e"},
+		}}, "junk[0]: invalid '(' regexp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setProfileConfig(t, "broken", tt.profile)
+
+			_, err := getImportProfile("broken")
+
+			assert.ErrorContains(t, err, "invalid")
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestImportProfile_Move(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "00001.MTS"), []byte("video"), 0644))
+
+	testTool := newTestExifTool()
+	defer testTool.clear()
+
+	profile := importProfile{
+		Rules: []importRule{
+			{Media: []string{"avchd"}, DateTag: "DateTimeOriginal", Naming: "%Y/VID_%%f.%%e"},
+		},
+	}
+	profile.move(src, "dst")
+
+	args := testTool.args.args
+	assert.True(t, testTool.execCalled)
+	assert.Contains(t, args, "-FileName<DateTimeOriginal")
+	assert.Contains(t, args, "-FileModifyDate<DateTimeOriginal")
+	assert.Contains(t, args, filepath.Join("dst", "%Y", "VID_%%f.%%e"))
+	assert.Contains(t, args, "mts")
+	assert.Contains(t, args, src)
+}
+
+func TestImportProfileCmd_ArgValidation(t *testing.T) {
+	assert.Equal(t, "media-tool import profile", importProfileCmd.CommandPath())
+	assert.Error(t, importProfileCmd.Args(importProfileCmd, []string{}))
+	assert.NoError(t, importProfileCmd.Args(importProfileCmd, []string{"dji"}))
+	assert.NoError(t, importProfileCmd.Args(importProfileCmd, []string{"dji", "dst"}))
+	assert.Error(t, importProfileCmd.Args(importProfileCmd, []string{"dji", "dst", "other"}))
+}
+
+func setProfileConfig(t *testing.T, name string, profile map[string]interface{}) {
+	key := cfgImportProfiles + "." + name
+	viper.Set(key, profile)
+	t.Cleanup(func() {
+		viper.Set(key, nil)
+	})
+}
@@ -0,0 +1,90 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+// importResumeCmd represents the import resume command
+var importResumeCmd = &cobra.Command{
+	Use:   "resume tempDir",
+	Short: "Resume interrupted import",
+	Long: `Continue interrupted device import using journal of the temp directory. 
+	Already copied and verified files are not downloaded again. 
+	Remaining files are copied, deleted from device and moved to the target folder`,
+	Args: cobra.RangeArgs(1, 1),
+	Run:  exitOnError(runImportResume),
+}
+
+func runImportResume(cmd *cobra.Command, args []string) error {
+	printCommandArgs(cmd, args)
+
+	tempDir := extractPath(args, 0, "")
+	log.Infof("temp dir: '%s'", tempDir)
+
+	run, err := mtp.ReadJournalRun(tempDir)
+	if err != nil {
+		return fmt.Errorf("unable to read import journal: %w", err)
+	}
+
+	profile, err := getImportProfile(run.Profile)
+	if err != nil {
+		return err
+	}
+	deviceProfile, err := profile.toDeviceProfile()
+	if err != nil {
+		return fmt.Errorf("invalid '%s' profile: %w", profile.Name, err)
+	}
+	log.Infof("src: '%s' media", run.Profile)
+	log.Infof("dst: '%s'", run.TargetDir)
+
+	log.Infof("dry ryn: %v", DryRun)
+
+	options := getImportOptions()
+	defer options.Close()
+
+	if DryRun {
+		return reportDryImport(profile, deviceProfile, run.TargetDir, options)
+	}
+
+	var moveErr error
+	options.Place = func(src string) {
+		log.Infof("Files were downloaded to: %v. Moving to target folder...", src)
+		result, err := profile.move(src, run.TargetDir)
+		moveErr = result.report(err)
+	}
+
+	src, err := mtp.ResumeFromAllWpd(getMediaSource(), deviceProfile, tempDir, options)
+	if err != nil {
+		return fmt.Errorf("unable to copy files: %w", err)
+	}
+	removeImportDir(src, false)
+	if moveErr != nil {
+		return fmt.Errorf("unable to move files to target folder: %w", moveErr)
+	}
+	return nil
+}
+
+func init() {
+	importCmd.AddCommand(importResumeCmd)
+}
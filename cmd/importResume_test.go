@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+func TestImportResumeCmd_CommandStructure(t *testing.T) {
+	assert.Equal(t, "resume", importResumeCmd.Name())
+	assert.Equal(t, "media-tool import resume", importResumeCmd.CommandPath())
+}
+
+func TestImportResumeCmd_ArgValidation(t *testing.T) {
+	assert.Error(t, importResumeCmd.Args(importResumeCmd, []string{}))
+	assert.NoError(t, importResumeCmd.Args(importResumeCmd, []string{"tmp"}))
+	assert.Error(t, importResumeCmd.Args(importResumeCmd, []string{"tmp1", "tmp2"}))
+}
+
+func TestRunImportResume_PlacesInterruptedImport(t *testing.T) {
+	r := require.New(t)
+	enableNativeDates(t)
+	viper.Set(cfgImportLedgerEnabled, false)
+	viper.Set(cfgImportSource, mtp.SourceFs)
+	mountDir, targetDir := t.TempDir(), t.TempDir()
+	viper.Set(cfgImportMountDirs, []string{filepath.Join(mountDir, "*")})
+	t.Cleanup(func() {
+		viper.Set(cfgImportLedgerEnabled, true)
+		viper.Set(cfgImportSource, mtp.SourceAuto)
+		viper.Set(cfgImportMountDirs, mtp.DefaultMountDirs)
+	})
+
+	deviceFile := filepath.Join(mountDir, "HERO8", "DCIM", "100GOPRO", "GX010001.MP4")
+	r.NoError(os.MkdirAll(filepath.Dir(deviceFile), 0755))
+	r.NoError(os.WriteFile(deviceFile, testDatedMovie(time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC)), 0644))
+
+	profile, err := getImportProfile("gopro")
+	r.NoError(err)
+	deviceProfile, err := profile.toDeviceProfile()
+	r.NoError(err)
+
+	// the first run is interrupted before files are placed
+	tempDir, err := mtp.LoadFromAllWpd(getMediaSource(), deviceProfile, targetDir, mtp.ImportOptions{Place: func(string) {}})
+	r.NoError(err)
+	r.FileExists(filepath.Join(tempDir, "0", "GX010001.MP4"))
+	r.FileExists(deviceFile)
+
+	r.NoError(runImportResume(importResumeCmd, []string{tempDir}))
+
+	placed := listFiles(targetDir)
+	r.Len(placed, 1)
+	assert.Regexp(t, `VID_20240518_\d{6}\.MP4$`, placed[0])
+	assert.NoFileExists(t, deviceFile)
+	assert.NoDirExists(t, tempDir)
+}
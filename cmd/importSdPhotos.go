@@ -18,8 +18,6 @@ limitations under the License.
 package cmd
 
 import (
-	"os"
-
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -41,53 +39,9 @@ var sdPhotos = &cobra.Command{
 	'import.sdPhotos.default.targetDir' configuration property`,
 	Args:    cobra.RangeArgs(0, 1),
 	Aliases: []string{"sd", "sdPhotos"},
-	Run: func(cmd *cobra.Command, args []string) {
-		printCommandArgs(cmd, args)
-
-		log.Infof("src: USB flash with 'DCIM' dir")
-
-		dstDir := extractPath(args, 0, "")
-		if dstDir == "" {
-			log.Infof("No args for targetDir was specified. Reading '%s' configuration", cfgImportSdPhotosDefaultDst)
-			dstDir = viper.GetString(cfgImportSdPhotosDefaultDst)
-			if dstDir == "" {
-				log.Errorf("No target dir was specified")
-				os.Exit(1)
-			}
-		}
-		log.Infof("dst: '%s'", dstDir)
-
-		log.Infof("dry ryn: %v", DryRun)
-
-		src, err := mtp.LoadSdPhotos(dstDir, DryRun)
-		if err != nil {
-			log.Errorf("Unable to copy photos files: %v", err)
-			os.Exit(1)
-		}
-		defer removeDir(src, DryRun)
-		log.Infof("Files were downloaded to: %v. Moving to target folder...", src)
-
-		tagName := "FileName"
-		if DryRun {
-			tagName = "TestName"
-		}
-
-		exifTool := getExifTool()
-
-		//Images and video
-		imgArgs := exifTool.newArgs()
-		if !DryRun {
-			imgArgs.changeFileDate("CreateDate")
-		}
-		imgArgs.changeTag(tagName, "CreateDate")
-		imgArgs.forDateFormat(dstDir + "\\%Y.%m.%d\\%%f%%-c.%%e")
-		imgArgs.forImages()
-		imgArgs.forVideoMp4()
-		imgArgs.recursively()
-		imgArgs.src(src)
-
-		exifTool.exec()
-	},
+	Run: exitOnError(func(cmd *cobra.Command, args []string) error {
+		return runProfileImport(cmd, mtp.SdPhotosProfile.Name, args)
+	}),
 }
 
 func init() {
@@ -0,0 +1,121 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+var ledgerPruneOlderThan string
+var ledgerPruneDevice string
+var ledgerPruneAll bool
+
+// ledgerPruneCmd represents the ledger prune command
+var ledgerPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove entries from import ledger",
+	Long: `Remove entries from import ledger, so related device files will be imported again. 
+	Entries may be selected by import age (--olderThan), by device name (--device) or all together (--all)`,
+	Args: cobra.NoArgs,
+	Run:  runLedgerPrune,
+}
+
+func runLedgerPrune(cmd *cobra.Command, args []string) {
+	printCommandArgs(cmd, args)
+
+	log.Infof("older than: '%s'", ledgerPruneOlderThan)
+	log.Infof("device: '%s'", ledgerPruneDevice)
+	log.Infof("all: %v", ledgerPruneAll)
+	log.Infof("dry ryn: %v", DryRun)
+
+	remove, err := buildLedgerPruneFilter(ledgerPruneOlderThan, ledgerPruneDevice, ledgerPruneAll, time.Now())
+	if err != nil {
+		log.Errorf("%v", err)
+		os.Exit(1)
+	}
+
+	ledger := openLedger()
+	if ledger == nil {
+		log.Errorf("Import ledger is disabled")
+		os.Exit(1)
+	}
+	defer ledger.Close()
+
+	if DryRun {
+		count := 0
+		for _, entry := range ledger.GetEntries() {
+			if remove(entry) {
+				log.Infof("'%s' from '%s' will be removed", entry.Path, entry.Device)
+				count++
+			}
+		}
+		log.Infof("%v of %v entries will be removed", count, ledger.GetEntriesCount())
+		return
+	}
+
+	total := ledger.GetEntriesCount()
+	removed, err := ledger.Prune(remove)
+	if err != nil {
+		log.Errorf("Unable to prune import ledger: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("%v of %v entries were removed", removed, total)
+}
+
+func buildLedgerPruneFilter(olderThan string, device string, all bool, now time.Time) (func(entry mtp.LedgerEntry) bool, error) {
+	if all {
+		return func(entry mtp.LedgerEntry) bool { return true }, nil
+	}
+	if olderThan == "" && device == "" {
+		return nil, fmt.Errorf("one of --olderThan, --device or --all args should be specified")
+	}
+
+	var before time.Time
+	if olderThan != "" {
+		age, err := parseDuration(olderThan)
+		if err != nil {
+			return nil, err
+		}
+		before = now.Add(-age)
+	}
+
+	return func(entry mtp.LedgerEntry) bool {
+		if olderThan != "" && !entry.Imported.Before(before) {
+			return false
+		}
+		if device != "" && !strings.Contains(entry.Device, device) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+func init() {
+	ledgerCmd.AddCommand(ledgerPruneCmd)
+
+	ledgerPruneCmd.Flags().StringVarP(&ledgerPruneOlderThan, "olderThan", "o", "", "Remove entries imported earlier than specified time ago (e.g. '12h', '30d', '2w')")
+	ledgerPruneCmd.Flags().StringVar(&ledgerPruneDevice, "device", "", "Remove entries of devices which name contains specified text")
+	ledgerPruneCmd.Flags().BoolVarP(&ledgerPruneAll, "all", "a", false, "Remove all entries")
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
+)
+
+func TestLedgerPruneCmd_CommandStructure(t *testing.T) {
+	assert.Equal(t, "prune", ledgerPruneCmd.Name())
+	assert.Equal(t, "media-tool ledger prune", ledgerPruneCmd.CommandPath())
+
+	dryRunFlag := ledgerCmd.PersistentFlags().Lookup("dry")
+	assert.NotNil(t, dryRunFlag)
+	assert.Equal(t, "d", dryRunFlag.Shorthand)
+}
+
+func TestLedgerPruneCmd_Flags(t *testing.T) {
+	olderThanFlag := ledgerPruneCmd.Flags().Lookup("olderThan")
+	assert.NotNil(t, olderThanFlag)
+	assert.Equal(t, "o", olderThanFlag.Shorthand)
+	assert.Equal(t, "", olderThanFlag.DefValue)
+
+	allFlag := ledgerPruneCmd.Flags().Lookup("all")
+	assert.NotNil(t, allFlag)
+	assert.Equal(t, "false", allFlag.DefValue)
+}
+
+func TestBuildLedgerPruneFilter(t *testing.T) {
+	now := time.Date(2024, 5, 18, 10, 0, 0, 0, time.UTC)
+	oldGoPro := mtp.LedgerEntry{Device: "HERO8 Black (GoPro)", Imported: now.Add(-40 * 24 * time.Hour)}
+	newGoPro := mtp.LedgerEntry{Device: "HERO8 Black (GoPro)", Imported: now.Add(-time.Hour)}
+	oldCam := mtp.LedgerEntry{Device: "CAM_SD (Panasonic)", Imported: now.Add(-40 * 24 * time.Hour)}
+
+	tests := []struct {
+		name      string
+		olderThan string
+		device    string
+		all       bool
+		expected  []bool
+	}{
+		{name: "all", all: true, expected: []bool{true, true, true}},
+		{name: "older than", olderThan: "30d", expected: []bool{true, false, true}},
+		{name: "device", device: "HERO", expected: []bool{true, true, false}},
+		{name: "older than and device", olderThan: "30d", device: "HERO", expected: []bool{true, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+
+			sut, err := buildLedgerPruneFilter(tt.olderThan, tt.device, tt.all, now)
+			r.NoError(err)
+
+			r.Equal(tt.expected, []bool{sut(oldGoPro), sut(newGoPro), sut(oldCam)})
+		})
+	}
+}
+
+func TestBuildLedgerPruneFilter_Errors(t *testing.T) {
+	_, err := buildLedgerPruneFilter("", "", false, time.Now())
+	assert.Error(t, err)
+
+	_, err = buildLedgerPruneFilter("xd", "", false, time.Now())
+	assert.Error(t, err)
+}
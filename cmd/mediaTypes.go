@@ -0,0 +1,212 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	cfgMediaTypes = "media.types"
+
+	mediaImage   = "image"
+	mediaRaw     = "raw"
+	mediaVideo   = "video"
+	mediaPreview = "preview"
+	mediaSidecar = "sidecar"
+	mediaAudio   = "audio"
+)
+
+// mediaCategories are media types used to classify files, the first matching category wins
+var mediaCategories = []string{mediaImage, mediaRaw, mediaVideo, mediaPreview, mediaSidecar, mediaAudio}
+
+// mediaType maps media type name to file extensions and MIME types
+type mediaType struct {
+	Extensions []string `mapstructure:"extensions"`
+	MimeTypes  []string `mapstructure:"mimeTypes"`
+	// Types are other media types included into this one, e.g. 'images' includes 'image' and 'raw' types
+	Types []string `mapstructure:"types"`
+}
+
+// builtInMediaTypes cover common camera formats. 'images', 'mp4', 'lrv' and 'avchd' are names used by import rules
+var builtInMediaTypes = map[string]mediaType{
+	mediaImage: {
+		Extensions: []string{"jpg", "jpeg", "heic", "heif", "png", "tif", "tiff"},
+		MimeTypes:  []string{"image/jpeg", "image/heic", "image/heif", "image/png", "image/tiff"},
+	},
+	mediaRaw: {
+		Extensions: []string{"nef", "nrw", "cr2", "cr3", "arw", "dng", "gpr", "raf", "orf", "rw2", "pef", "srw"},
+		MimeTypes: []string{"image/x-nikon-nef", "image/x-nikon-nrw", "image/x-canon-cr2", "image/x-canon-cr3", "image/x-sony-arw",
+			"image/x-adobe-dng", "image/x-fujifilm-raf", "image/x-olympus-orf", "image/x-panasonic-rw2", "image/x-pentax-pef", "image/x-samsung-srw"},
+	},
+	mediaVideo: {
+		Extensions: []string{"mp4", "mov", "m4v", "mts", "m2ts", "3gp", "avi", "360", "insv"},
+		MimeTypes:  []string{"video/mp4", "video/quicktime", "video/x-m4v", "video/m2ts", "video/3gpp", "video/x-msvideo"},
+	},
+	mediaPreview: {
+		Extensions: []string{"lrv", "lrf", "thm"},
+	},
+	mediaSidecar: {
+		Extensions: []string{"xmp", "aae", "srt"},
+		MimeTypes:  []string{"application/rdf+xml"},
+	},
+	mediaAudio: {
+		Extensions: []string{"wav", "mp3", "m4a", "aac", "flac"},
+		MimeTypes:  []string{"audio/x-wav", "audio/mpeg", "audio/mp4", "audio/aac", "audio/flac"},
+	},
+	"images": {Types: []string{mediaImage, mediaRaw}},
+	"mp4":    {Extensions: []string{"mp4"}},
+	"lrv":    {Extensions: []string{"lrv"}},
+	"avchd":  {Extensions: []string{"mts", "m2ts"}},
+}
+
+// getMediaType finds media type by name and resolves its included types. Types from 'media.types' configuration
+// override built-in ones. Extensions of the result are lower case and without leading dot
+func getMediaType(name string) (mediaType, error) {
+	return resolveMediaType(strings.ToLower(name), make(map[string]bool))
+}
+
+func resolveMediaType(name string, visited map[string]bool) (mediaType, error) {
+	if visited[name] {
+		return mediaType{}, fmt.Errorf("'%s' media type includes itself", name)
+	}
+	visited[name] = true
+	defer delete(visited, name)
+
+	configured, err := findMediaType(name)
+	if err != nil {
+		return mediaType{}, err
+	}
+
+	result := mediaType{}
+	for _, ext := range configured.Extensions {
+		result.Extensions = appendUnique(result.Extensions, strings.ToLower(strings.TrimPrefix(ext, ".")))
+	}
+	for _, mimeType := range configured.MimeTypes {
+		result.MimeTypes = appendUnique(result.MimeTypes, strings.ToLower(mimeType))
+	}
+	for _, typeName := range configured.Types {
+		included, err := resolveMediaType(strings.ToLower(typeName), visited)
+		if err != nil {
+			return mediaType{}, err
+		}
+		for _, ext := range included.Extensions {
+			result.Extensions = appendUnique(result.Extensions, ext)
+		}
+		for _, mimeType := range included.MimeTypes {
+			result.MimeTypes = appendUnique(result.MimeTypes, mimeType)
+		}
+	}
+	return result, nil
+}
+
+// findMediaType returns configured or built-in media type without resolving its included types
+func findMediaType(name string) (mediaType, error) {
+	key := cfgMediaTypes + "." + name
+	if !viper.IsSet(key) {
+		result, exists := builtInMediaTypes[name]
+		if !exists {
+			return mediaType{}, fmt.Errorf("unknown '%s' media type", name)
+		}
+		return result, nil
+	}
+
+	result := mediaType{}
+	if err := viper.UnmarshalKey(key, &result); err != nil {
+		return mediaType{}, fmt.Errorf("unable to parse '%s' media type: %w", name, err)
+	}
+	return result, nil
+}
+
+// getMediaTypeNames returns sorted names of built-in and configured media types
+func getMediaTypeNames() []string {
+	names := make(map[string]bool)
+	for name := range builtInMediaTypes {
+		names[name] = true
+	}
+	for name := range viper.GetStringMap(cfgMediaTypes) {
+		if viper.IsSet(cfgMediaTypes + "." + name) {
+			names[strings.ToLower(name)] = true
+		}
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// mediaExtensions returns lower case file extensions of the media type
+func mediaExtensions(media string) []string {
+	result, err := getMediaType(media)
+	if err != nil {
+		return nil
+	}
+	return result.Extensions
+}
+
+// classifyMedia returns media category (image, raw, video etc.) of the file by its extension or, if the extension
+// is unknown, by MIME type. Returns empty string if the file does not match any category
+func classifyMedia(filePath string, mimeType string) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
+	mimeType = strings.ToLower(mimeType)
+
+	categories := make([]mediaType, 0, len(mediaCategories))
+	for _, name := range mediaCategories {
+		category, err := getMediaType(name)
+		if err != nil {
+			log.Warningf("%v", err)
+		}
+		categories = append(categories, category)
+	}
+
+	for i, category := range categories {
+		if ext != "" && contains(category.Extensions, ext) {
+			return mediaCategories[i]
+		}
+	}
+	for i, category := range categories {
+		if mimeType != "" && contains(category.MimeTypes, mimeType) {
+			return mediaCategories[i]
+		}
+	}
+	return ""
+}
+
+func appendUnique(values []string, value string) []string {
+	if contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
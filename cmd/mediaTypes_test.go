@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setMediaTypeConfig(t *testing.T, name string, media map[string]interface{}) {
+	key := cfgMediaTypes + "." + name
+	viper.Set(key, media)
+	t.Cleanup(func() {
+		viper.Set(key, nil)
+	})
+}
+
+func TestGetMediaType_BuiltIn(t *testing.T) {
+	r := require.New(t)
+
+	images, err := getMediaType("Images")
+
+	r.NoError(err)
+	r.Contains(images.Extensions, "jpg")
+	r.Contains(images.Extensions, "heic")
+	r.Contains(images.Extensions, "raf")
+	r.Contains(images.MimeTypes, "image/x-sony-arw")
+}
+
+func TestGetMediaType_Configured(t *testing.T) {
+	r := require.New(t)
+	setMediaTypeConfig(t, "video", map[string]interface{}{"extensions": []interface{}{".MP4", "tsd"}})
+	setMediaTypeConfig(t, "drone", map[string]interface{}{
+		"extensions": []interface{}{"dng", "mp4"},
+		"types":      []interface{}{"video", "sidecar"},
+	})
+
+	drone, err := getMediaType("drone")
+
+	r.NoError(err)
+	r.Equal([]string{"dng", "mp4", "tsd", "xmp", "aae", "srt"}, drone.Extensions)
+	r.Equal([]string{"application/rdf+xml"}, drone.MimeTypes)
+	r.Contains(getMediaTypeNames(), "drone")
+}
+
+func TestGetMediaType_Errors(t *testing.T) {
+	setMediaTypeConfig(t, "first", map[string]interface{}{"types": []interface{}{"second"}})
+	setMediaTypeConfig(t, "second", map[string]interface{}{"types": []interface{}{"first"}})
+	setMediaTypeConfig(t, "broken", map[string]interface{}{"types": []interface{}{"image", "unknown"}})
+
+	_, err := getMediaType("first")
+	assert.EqualError(t, err, "'first' media type includes itself")
+
+	_, err = getMediaType("broken")
+	assert.EqualError(t, err, "unknown 'unknown' media type")
+}
+
+func TestClassifyMedia(t *testing.T) {
+	tests := []struct {
+		filePath string
+		mimeType string
+		result   string
+	}{
+		{"IMG_0001.HEIC", "", mediaImage},
+		{"DSCF0001.RAF", "image/x-fujifilm-raf", mediaRaw},
+		{"GX010001.LRV", "video/mp4", mediaPreview},
+		{"GX010001.MP4", "video/mp4", mediaVideo},
+		{"IMG_0001.XMP", "", mediaSidecar},
+		{"REC_0001.WAV", "", mediaAudio},
+		{"video.bin", "video/quicktime", mediaVideo},
+		{"notes.txt", "text/plain", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.filePath, func(t *testing.T) {
+			assert.Equal(t, test.result, classifyMedia(test.filePath, test.mimeType))
+		})
+	}
+}
@@ -0,0 +1,326 @@
+/*
+Package cmd provides command handlers
+
+Copyright © 2020 Maksym Medvedev <redrathnure@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metadataBatchSize is the max number of files read by a single exiftool command
+const metadataBatchSize = 200
+
+// mediaMetadata is typed subset of file tags read by exiftool
+type mediaMetadata struct {
+	SourceFile string
+	MIMEType   string
+	// MediaType is media category (image, raw, video etc.) of the file, see classifyMedia
+	MediaType string
+
+	Make         string
+	Model        string
+	SerialNumber string
+
+	DateTimeOriginal metadataDate
+	CreateDate       metadataDate
+	ModifyDate       metadataDate
+
+	// GPS is nil for files without position
+	GPS *gpsPosition
+	// Duration of video and audio files
+	Duration time.Duration
+	Width    int
+	Height   int
+
+	// Tags are all file tags by 'Group:Tag' names, numeric values are json.Number
+	Tags map[string]any
+}
+
+// metadataDate is a tag date with sub-seconds. Time is in UTC offset of the tag or in local time zone if the tag has no offset.
+// HasOffset is also set for tags which are stored in UTC (QuickTime dates)
+type metadataDate struct {
+	Time      time.Time
+	HasOffset bool
+}
+
+// gpsPosition is position in signed decimal degrees, altitude is in meters
+type gpsPosition struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// dateSource describes date tag with optional sub-seconds and offset tags. Dates of utc tags without offset are in UTC
+type dateSource struct {
+	tag    string
+	subSec string
+	offset string
+	utc    bool
+}
+
+var (
+	dateTimeOriginalSources = []dateSource{
+		{tag: "Composite:SubSecDateTimeOriginal"},
+		{tag: "ExifIFD:DateTimeOriginal", subSec: "ExifIFD:SubSecTimeOriginal", offset: "ExifIFD:OffsetTimeOriginal"},
+		{tag: "XMP-exif:DateTimeOriginal"},
+		{tag: "DateTimeOriginal"},
+	}
+	createDateSources = []dateSource{
+		{tag: "Composite:SubSecCreateDate"},
+		{tag: "ExifIFD:CreateDate", subSec: "ExifIFD:SubSecTimeDigitized", offset: "ExifIFD:OffsetTimeDigitized"},
+		{tag: "Keys:CreationDate"},
+		{tag: "QuickTime:CreateDate", utc: true},
+		{tag: "CreateDate"},
+	}
+	modifyDateSources = []dateSource{
+		{tag: "Composite:SubSecModifyDate"},
+		{tag: "IFD0:ModifyDate", subSec: "ExifIFD:SubSecTime", offset: "ExifIFD:OffsetTime"},
+		{tag: "QuickTime:ModifyDate", utc: true},
+		{tag: "ModifyDate"},
+	}
+)
+
+// newReadArgs prepares args without default options, so output may be parsed
+func (tool *exifToolWrapper) newReadArgs() *exifToolArgs {
+	tool.args = exifToolArgs{args: make([]string, 0)}
+	return &tool.args
+}
+
+// readMetadata reads tags of files by batches. Metadata of read files is returned even if some files failed
+func (tool *exifToolWrapper) readMetadata(files []string) ([]mediaMetadata, error) {
+	result := make([]mediaMetadata, 0, len(files))
+	var errs []error
+	failed := 0
+	for _, batch := range splitBatches(files, metadataBatchSize) {
+		toolArgs := tool.newReadArgs()
+		toolArgs.add("-json", "-n", "-G1")
+		for _, file := range batch {
+			toolArgs.src(file)
+		}
+
+		output, execErr := tool.execOutput()
+		records, err := parseMetadata(output)
+		if err != nil {
+			// other batches are still read
+			errs = append(errs, err)
+			failed += len(batch)
+			continue
+		}
+		if execErr != nil {
+			errs = append(errs, execErr)
+			failed += max(len(batch)-len(records), 0)
+		}
+		result = append(result, records...)
+	}
+
+	if len(errs) > 0 {
+		return result, fmt.Errorf("unable to read metadata of %v file(s): %w", failed, errs[0])
+	}
+	return result, nil
+}
+
+// date returns the rule date tag of the file
+func (record mediaMetadata) date(dateTag string) (metadataDate, bool) {
+	var date metadataDate
+	switch dateTag {
+	case "DateTimeOriginal":
+		date = record.DateTimeOriginal
+	case "CreateDate":
+		date = record.CreateDate
+	case "ModifyDate":
+		date = record.ModifyDate
+	default:
+		date = tagDate(record.Tags, []dateSource{{tag: dateTag}})
+	}
+	return date, !date.Time.IsZero()
+}
+
+func splitBatches(files []string, size int) [][]string {
+	result := make([][]string, 0, (len(files)+size-1)/size)
+	for start := 0; start < len(files); start += size {
+		result = append(result, files[start:min(start+size, len(files))])
+	}
+	return result
+}
+
+// parseMetadata parses exiftool '-json -n -G1' output
+func parseMetadata(output string) ([]mediaMetadata, error) {
+	if strings.TrimSpace(output) == "" {
+		return []mediaMetadata{}, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(output))
+	decoder.UseNumber()
+	records := make([]map[string]any, 0)
+	if err := decoder.Decode(&records); err != nil {
+		return nil, fmt.Errorf("unable to parse exiftool output: %w", err)
+	}
+
+	result := make([]mediaMetadata, 0, len(records))
+	for _, tags := range records {
+		result = append(result, newMediaMetadata(tags))
+	}
+	return result, nil
+}
+
+func newMediaMetadata(tags map[string]any) mediaMetadata {
+	result := mediaMetadata{
+		SourceFile:       filepath.Clean(filepath.FromSlash(tagString(tags, "SourceFile"))),
+		MIMEType:         tagString(tags, "File:MIMEType"),
+		Make:             tagString(tags, "IFD0:Make", "Make"),
+		Model:            tagString(tags, "IFD0:Model", "Model"),
+		SerialNumber:     tagString(tags, "ExifIFD:SerialNumber", "SerialNumber"),
+		DateTimeOriginal: tagDate(tags, dateTimeOriginalSources),
+		CreateDate:       tagDate(tags, createDateSources),
+		ModifyDate:       tagDate(tags, modifyDateSources),
+		Width:            int(tagNumber(tags, "File:ImageWidth", "ExifIFD:ExifImageWidth", "ImageWidth")),
+		Height:           int(tagNumber(tags, "File:ImageHeight", "ExifIFD:ExifImageHeight", "ImageHeight")),
+		Tags:             tags,
+	}
+
+	result.MediaType = classifyMedia(result.SourceFile, result.MIMEType)
+
+	if seconds := tagNumber(tags, "QuickTime:Duration", "Composite:Duration", "Duration"); seconds > 0 {
+		result.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	_, hasLatitude := findTag(tags, "Composite:GPSLatitude")
+	_, hasLongitude := findTag(tags, "Composite:GPSLongitude")
+	if hasLatitude && hasLongitude {
+		result.GPS = &gpsPosition{
+			Latitude:  tagNumber(tags, "Composite:GPSLatitude"),
+			Longitude: tagNumber(tags, "Composite:GPSLongitude"),
+			Altitude:  tagNumber(tags, "Composite:GPSAltitude"),
+		}
+	}
+	return result
+}
+
+// findTag finds the first existing tag. Name without group matches the tag of any group (the first group in alphabetical order)
+func findTag(tags map[string]any, names ...string) (any, bool) {
+	for _, name := range names {
+		if value, exists := tags[name]; exists {
+			return value, true
+		}
+		if strings.Contains(name, ":") {
+			continue
+		}
+
+		keys := make([]string, 0)
+		for key := range tags {
+			if strings.HasSuffix(key, ":"+name) {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			sort.Strings(keys)
+			return tags[keys[0]], true
+		}
+	}
+	return nil, false
+}
+
+func tagString(tags map[string]any, names ...string) string {
+	value, exists := findTag(tags, names...)
+	if !exists {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+func tagNumber(tags map[string]any, names ...string) float64 {
+	for _, name := range names {
+		value, exists := findTag(tags, name)
+		if !exists {
+			continue
+		}
+		if number, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(value)), 64); err == nil {
+			return number
+		}
+	}
+	return 0
+}
+
+func tagDate(tags map[string]any, sources []dateSource) metadataDate {
+	for _, source := range sources {
+		value := tagString(tags, source.tag)
+		if value == "" {
+			continue
+		}
+		if source.subSec != "" {
+			if subSec := tagString(tags, source.subSec); subSec != "" && !strings.Contains(value, ".") {
+				value += "." + subSec
+			}
+		}
+		if source.offset != "" {
+			if offset := tagString(tags, source.offset); offset != "" {
+				value += offset
+			}
+		}
+
+		location := time.Local
+		if source.utc {
+			location = time.UTC
+		}
+		if date, err := parseMetadataDate(value, location); err == nil {
+			return date
+		}
+	}
+	return metadataDate{}
+}
+
+// parseMetadataDate parses exiftool date with optional sub-seconds and offset, e.g. '2024:05:18 10:30:15.123+02:00'.
+// Date without offset is in the location
+func parseMetadataDate(value string, location *time.Location) (metadataDate, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 19 {
+		return metadataDate{}, fmt.Errorf("invalid '%s' date", value)
+	}
+
+	dateTime, rest := value[:19], value[19:]
+	fraction := ""
+	if strings.HasPrefix(rest, ".") {
+		end := 1
+		for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+			end++
+		}
+		fraction, rest = rest[:end], rest[end:]
+	}
+
+	layout := "2006:01:02 15:04:05"
+	if len(fraction) > 1 {
+		layout += "." + strings.Repeat("0", len(fraction)-1)
+	}
+
+	switch {
+	case rest == "":
+		date, err := time.ParseInLocation(layout, dateTime+fraction, location)
+		return metadataDate{Time: date, HasOffset: location == time.UTC}, err
+	case rest == "Z":
+		date, err := time.Parse(layout+"Z07:00", dateTime+fraction+rest)
+		return metadataDate{Time: date, HasOffset: true}, err
+	default:
+		date, err := time.Parse(layout+"-07:00", dateTime+fraction+rest)
+		return metadataDate{Time: date, HasOffset: true}, err
+	}
+}
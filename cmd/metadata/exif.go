@@ -0,0 +1,225 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// TIFF (EXIF) tags
+const (
+	tagModifyDate          = 0x0132
+	tagExifIFD             = 0x8769
+	tagDateTimeOriginal    = 0x9003
+	tagCreateDate          = 0x9004
+	tagOffsetTime          = 0x9010
+	tagOffsetTimeOriginal  = 0x9011
+	tagOffsetTimeDigitized = 0x9012
+	tagSubSecTime          = 0x9290
+	tagSubSecTimeOriginal  = 0x9291
+	tagSubSecTimeDigitized = 0x9292
+)
+
+const (
+	tiffTypeAscii = 2
+	tiffTypeLong  = 4
+	// maxIFDEntries limits IFD size of broken files
+	maxIFDEntries = 1000
+	// maxAsciiSize limits size of read text values, dates are 20 bytes long
+	maxAsciiSize = 64
+)
+
+// exifDateTags are EXIF date tags with their sub-seconds and offset tags
+var exifDateTags = []struct {
+	name   string
+	date   uint16
+	subSec uint16
+	offset uint16
+}{
+	{DateTimeOriginal, tagDateTimeOriginal, tagSubSecTimeOriginal, tagOffsetTimeOriginal},
+	{CreateDate, tagCreateDate, tagSubSecTimeDigitized, tagOffsetTimeDigitized},
+	{ModifyDate, tagModifyDate, tagSubSecTime, tagOffsetTime},
+}
+
+// readJpegDates finds EXIF (APP1) segment of JPEG file and reads its dates
+func readJpegDates(r io.Reader) (map[string]Date, error) {
+	reader := bufio.NewReader(r)
+	marker := make([]byte, 2)
+	if _, err := io.ReadFull(reader, marker); err != nil || marker[0] != 0xFF || marker[1] != 0xD8 {
+		return nil, fmt.Errorf("not a JPEG file")
+	}
+
+	for {
+		if _, err := io.ReadFull(reader, marker); err != nil {
+			return nil, fmt.Errorf("%w: no EXIF segment", ErrNoDate)
+		}
+		if marker[0] != 0xFF {
+			return nil, fmt.Errorf("invalid JPEG marker %X", marker)
+		}
+		switch {
+		case marker[1] == 0xFF:
+			// fill byte
+			reader.UnreadByte()
+			continue
+		case marker[1] == 0x01 || (marker[1] >= 0xD0 && marker[1] <= 0xD7):
+			continue
+		case marker[1] == 0xD9 || marker[1] == 0xDA:
+			// image data starts, EXIF is always before it
+			return nil, fmt.Errorf("%w: no EXIF segment", ErrNoDate)
+		}
+
+		sizeBytes := make([]byte, 2)
+		if _, err := io.ReadFull(reader, sizeBytes); err != nil {
+			return nil, err
+		}
+		size := int(binary.BigEndian.Uint16(sizeBytes)) - 2
+		if size < 0 {
+			return nil, fmt.Errorf("invalid JPEG segment size")
+		}
+		segment := make([]byte, size)
+		if _, err := io.ReadFull(reader, segment); err != nil {
+			return nil, err
+		}
+
+		if marker[1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return readTiffDates(bytes.NewReader(segment[6:]))
+		}
+	}
+}
+
+// tiffReader reads TIFF structures of EXIF segment or TIFF based RAW file
+type tiffReader struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+}
+
+// ifdEntry is a directory entry, value is the offset of the value or the value itself if it fits 4 bytes
+type ifdEntry struct {
+	tag       uint16
+	valueType uint16
+	count     uint32
+	value     []byte
+}
+
+// readTiffDates reads IFD0 and EXIF IFD dates
+func readTiffDates(r io.ReaderAt) (map[string]Date, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("not a TIFF file: %w", err)
+	}
+
+	tiff := tiffReader{r: r}
+	switch string(header[:2]) {
+	case "II":
+		tiff.order = binary.LittleEndian
+	case "MM":
+		tiff.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("not a TIFF file")
+	}
+	if tiff.order.Uint16(header[2:]) != 42 {
+		return nil, fmt.Errorf("not a TIFF file")
+	}
+
+	values := make(map[uint16]string)
+	ifd0, err := tiff.readIFD(int64(tiff.order.Uint32(header[4:])))
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range ifd0 {
+		switch {
+		case entry.tag == tagModifyDate:
+			values[entry.tag] = tiff.readAscii(entry)
+		case entry.tag == tagExifIFD && entry.valueType == tiffTypeLong:
+			exifIFD, err := tiff.readIFD(int64(tiff.order.Uint32(entry.value)))
+			if err != nil {
+				return nil, err
+			}
+			for _, exifEntry := range exifIFD {
+				values[exifEntry.tag] = tiff.readAscii(exifEntry)
+			}
+		}
+	}
+
+	result := make(map[string]Date)
+	for _, tag := range exifDateTags {
+		date, err := parseExifDate(values[tag.date], values[tag.subSec], values[tag.offset])
+		if err == nil {
+			result[tag.name] = date
+		}
+	}
+	return result, nil
+}
+
+func (tiff tiffReader) readIFD(offset int64) ([]ifdEntry, error) {
+	countBytes := make([]byte, 2)
+	if _, err := tiff.r.ReadAt(countBytes, offset); err != nil {
+		return nil, fmt.Errorf("unable to read IFD at %v: %w", offset, err)
+	}
+	count := int(tiff.order.Uint16(countBytes))
+	if count > maxIFDEntries {
+		return nil, fmt.Errorf("IFD at %v has too many entries: %v", offset, count)
+	}
+
+	data := make([]byte, count*12)
+	if _, err := tiff.r.ReadAt(data, offset+2); err != nil {
+		return nil, fmt.Errorf("unable to read IFD at %v: %w", offset, err)
+	}
+
+	result := make([]ifdEntry, 0, count)
+	for i := 0; i < count; i++ {
+		entry := data[i*12 : (i+1)*12]
+		result = append(result, ifdEntry{
+			tag:       tiff.order.Uint16(entry),
+			valueType: tiff.order.Uint16(entry[2:]),
+			count:     tiff.order.Uint32(entry[4:]),
+			value:     entry[8:12],
+		})
+	}
+	return result, nil
+}
+
+// readAscii reads text value, values of other types and too long values are returned as empty strings
+func (tiff tiffReader) readAscii(entry ifdEntry) string {
+	if entry.valueType != tiffTypeAscii || entry.count > maxAsciiSize {
+		return ""
+	}
+
+	value := entry.value[:min(entry.count, 4)]
+	if entry.count > 4 {
+		value = make([]byte, entry.count)
+		if _, err := tiff.r.ReadAt(value, int64(tiff.order.Uint32(entry.value))); err != nil {
+			return ""
+		}
+	}
+	return strings.TrimSpace(strings.TrimRight(string(value), "\x00"))
+}
+
+// parseExifDate parses EXIF date ('2024:05:18 10:30:15'), sub-seconds ('045') and offset ('+02:00') values
+func parseExifDate(value string, subSec string, offset string) (Date, error) {
+	date, err := time.Parse("2006:01:02 15:04:05", value)
+	if err != nil {
+		return Date{}, err
+	}
+
+	if digits := strings.TrimSpace(subSec); digits != "" {
+		if fraction, err := time.ParseDuration("0." + digits + "s"); err == nil && fraction < time.Second {
+			date = date.Add(fraction)
+		}
+	}
+
+	if offset != "" {
+		withOffset, err := time.Parse("2006:01:02 15:04:05-07:00", value+offset)
+		if err == nil {
+			_, zoneOffset := withOffset.Zone()
+			date = time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), date.Nanosecond(),
+				time.FixedZone(offset, zoneOffset))
+			return Date{Time: date, HasOffset: true}, nil
+		}
+	}
+	return Date{Time: date}, nil
+}
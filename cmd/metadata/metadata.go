@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned for file formats which are not read natively
+var ErrUnsupported = errors.New("unsupported file format")
+
+// ErrNoDate is returned if the file has no such date tag
+var ErrNoDate = errors.New("date tag was not found")
+
+// Date is a date tag value. Time keeps the wall clock of the tag, it is in the tag offset if the file has
+// it (HasOffset) or in UTC otherwise, so wall clocks which do not exist in the local time zone (DST gap) are kept as is
+type Date struct {
+	Time      time.Time
+	HasOffset bool
+}
+
+// Instant returns the moment of the date. Wall clock of the date without offset is taken in the local time zone
+func (date Date) Instant() time.Time {
+	if date.HasOffset {
+		return date.Time
+	}
+	t := date.Time
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
+
+// Date tags, names are the same as exiftool ones
+const (
+	DateTimeOriginal = "DateTimeOriginal"
+	CreateDate       = "CreateDate"
+	ModifyDate       = "ModifyDate"
+	TrackCreateDate  = "TrackCreateDate"
+	TrackModifyDate  = "TrackModifyDate"
+	MediaCreateDate  = "MediaCreateDate"
+	MediaModifyDate  = "MediaModifyDate"
+)
+
+// dateTagAliases are exiftool composite tags which are the same as native dates (sub-seconds and offset are always read)
+var dateTagAliases = map[string]string{
+	"SubSecDateTimeOriginal": DateTimeOriginal,
+	"SubSecCreateDate":       CreateDate,
+	"SubSecModifyDate":       ModifyDate,
+}
+
+var (
+	jpegExtensions      = []string{".jpg", ".jpeg"}
+	tiffExtensions      = []string{".tif", ".tiff", ".nef", ".cr2", ".dng"}
+	quickTimeExtensions = []string{".mp4", ".mov", ".m4v", ".lrv", ".3gp"}
+)
+
+// ReadDate reads date tag of JPEG, TIFF based RAW or MP4/MOV file
+func ReadDate(filePath string, tag string) (Date, error) {
+	if alias, exists := dateTagAliases[tag]; exists {
+		tag = alias
+	}
+
+	dates, err := ReadDates(filePath)
+	if err != nil {
+		return Date{}, err
+	}
+	date, exists := dates[tag]
+	if !exists {
+		return Date{}, fmt.Errorf("%w: '%s'", ErrNoDate, tag)
+	}
+	return date, nil
+}
+
+// ReadDates reads all supported date tags of the file
+func ReadDates(filePath string) (map[string]Date, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+
+	var read func(f *os.File) (map[string]Date, error)
+	switch {
+	case contains(jpegExtensions, ext):
+		read = func(f *os.File) (map[string]Date, error) { return readJpegDates(f) }
+	case contains(tiffExtensions, ext):
+		read = func(f *os.File) (map[string]Date, error) { return readTiffDates(f) }
+	case contains(quickTimeExtensions, ext):
+		read = func(f *os.File) (map[string]Date, error) { return readQuickTimeDates(f) }
+	default:
+		return nil, fmt.Errorf("%w: '%s'", ErrUnsupported, ext)
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return read(f)
+}
+
+// IsSupported checks whether dates of the file are read natively
+func IsSupported(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return contains(jpegExtensions, ext) || contains(tiffExtensions, ext) || contains(quickTimeExtensions, ext)
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tiffTag is ASCII tag of synthetic TIFF files
+type tiffTag struct {
+	tag   uint16
+	value string
+}
+
+// buildTiff builds TIFF with IFD0 and EXIF IFD. Values are stored after IFDs
+func buildTiff(order binary.AppendByteOrder, ifd0 []tiffTag, exif []tiffTag) []byte {
+	ifd0Offset := 8
+	ifd0Size := 2 + (len(ifd0)+1)*12 + 4
+	exifOffset := ifd0Offset + ifd0Size
+	valuesOffset := exifOffset + 2 + len(exif)*12 + 4
+
+	values := make([]byte, 0)
+	writeIFD := func(tags []tiffTag, pointer bool) []byte {
+		count := len(tags)
+		if pointer {
+			count++
+		}
+		data := order.AppendUint16(nil, uint16(count))
+		for _, tag := range tags {
+			value := append([]byte(tag.value), 0)
+			data = order.AppendUint16(data, tag.tag)
+			data = order.AppendUint16(data, tiffTypeAscii)
+			data = order.AppendUint32(data, uint32(len(value)))
+			if len(value) <= 4 {
+				data = append(data, append(value, make([]byte, 4-len(value))...)...)
+			} else {
+				data = order.AppendUint32(data, uint32(valuesOffset+len(values)))
+				values = append(values, value...)
+			}
+		}
+		if pointer {
+			data = order.AppendUint16(data, tagExifIFD)
+			data = order.AppendUint16(data, tiffTypeLong)
+			data = order.AppendUint32(data, 1)
+			data = order.AppendUint32(data, uint32(exifOffset))
+		}
+		return order.AppendUint32(data, 0)
+	}
+
+	result := []byte("II")
+	if order.String() == binary.BigEndian.String() {
+		result = []byte("MM")
+	}
+	result = order.AppendUint16(result, 42)
+	result = order.AppendUint32(result, uint32(ifd0Offset))
+	result = append(result, writeIFD(ifd0, true)...)
+	result = append(result, writeIFD(exif, false)...)
+	return append(result, values...)
+}
+
+func buildJpeg(tiff []byte) []byte {
+	exif := append([]byte("Exif\x00\x00"), tiff...)
+	result := []byte{0xFF, 0xD8}
+	// APP0 (JFIF) goes before EXIF in some files
+	result = append(result, 0xFF, 0xE0, 0x00, 0x07, 'J', 'F', 'I', 'F', 0x00)
+	result = append(result, 0xFF, 0xE1)
+	result = binary.BigEndian.AppendUint16(result, uint16(len(exif)+2))
+	result = append(result, exif...)
+	return append(result, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9)
+}
+
+func box(boxType string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	return append(append(binary.BigEndian.AppendUint32(nil, uint32(len(data)+8)), boxType...), data...)
+}
+
+// quickTimeSeconds converts time to seconds since 1904
+func quickTimeSeconds(value time.Time) uint32 {
+	return uint32(value.Sub(time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)) / time.Second)
+}
+
+// buildMovie builds MP4 with version 0 'mvhd', 'tkhd' and 'mdhd' boxes
+func buildMovie(created time.Time, modified time.Time) []byte {
+	times := binary.BigEndian.AppendUint32(nil, 0)
+	times = binary.BigEndian.AppendUint32(times, quickTimeSeconds(created))
+	times = binary.BigEndian.AppendUint32(times, quickTimeSeconds(modified))
+	return bytes.Join([][]byte{
+		box("ftyp", []byte("mp41")),
+		box("moov",
+			box("mvhd", times, make([]byte, 88)),
+			box("trak",
+				box("tkhd", times, make([]byte, 72)),
+				box("mdia", box("mdhd", times, make([]byte, 12))),
+			),
+		),
+	}, nil)
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+var exifTags = []tiffTag{
+	{tagDateTimeOriginal, "2024:05:18 10:30:15"},
+	{tagSubSecTimeOriginal, "045"},
+	{tagOffsetTimeOriginal, "+02:00"},
+	{tagCreateDate, "2024:05:18 10:30:16"},
+}
+
+func TestReadDate_Jpeg(t *testing.T) {
+	r := require.New(t)
+
+	path := writeFile(t, "IMG_0001.JPG", buildJpeg(buildTiff(binary.LittleEndian, []tiffTag{{tagModifyDate, "2024:06:01 08:00:00"}}, exifTags)))
+
+	date, err := ReadDate(path, DateTimeOriginal)
+	r.NoError(err)
+	r.True(date.HasOffset)
+	r.Equal("2024-05-18T10:30:15.045+02:00", date.Time.Format(time.RFC3339Nano))
+
+	date, err = ReadDate(path, "SubSecCreateDate")
+	r.NoError(err)
+	r.False(date.HasOffset)
+	r.Equal(time.Date(2024, 5, 18, 10, 30, 16, 0, time.UTC), date.Time)
+
+	date, err = ReadDate(path, ModifyDate)
+	r.NoError(err)
+	r.Equal(time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC), date.Time)
+}
+
+func TestReadDate_TiffRaw(t *testing.T) {
+	r := require.New(t)
+
+	path := writeFile(t, "DSC_0001.NEF", buildTiff(binary.BigEndian, nil, exifTags[:1]))
+
+	date, err := ReadDate(path, DateTimeOriginal)
+	r.NoError(err)
+	r.Equal(time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC), date.Time)
+
+	_, err = ReadDate(path, CreateDate)
+	r.ErrorIs(err, ErrNoDate)
+}
+
+func TestReadDate_QuickTime(t *testing.T) {
+	r := require.New(t)
+
+	created := time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC)
+	path := writeFile(t, "GX010001.MP4", buildMovie(created, created.Add(time.Minute)))
+
+	dates, err := ReadDates(path)
+
+	r.NoError(err)
+	for _, tag := range []string{CreateDate, TrackCreateDate, MediaCreateDate} {
+		r.Equal(created, dates[tag].Time, tag)
+	}
+	r.Equal(created.Add(time.Minute), dates[ModifyDate].Time)
+	r.False(dates[CreateDate].HasOffset)
+	r.Equal(time.Date(2024, 5, 18, 10, 30, 15, 0, time.Local), dates[CreateDate].Instant())
+}
+
+func TestReadDate_DaylightSavingGap(t *testing.T) {
+	r := require.New(t)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database is not available: %v", err)
+	}
+	local := time.Local
+	time.Local = berlin
+	t.Cleanup(func() { time.Local = local })
+
+	// 02:30 does not exist in Berlin on 2024-03-31, clocks jump from 02:00 to 03:00
+	path := writeFile(t, "IMG_0001.JPG", buildJpeg(buildTiff(binary.LittleEndian, nil, []tiffTag{{tagDateTimeOriginal, "2024:03:31 02:30:00"}})))
+	date, err := ReadDate(path, DateTimeOriginal)
+	r.NoError(err)
+	r.Equal("2024-03-31 02:30:00", date.Time.Format(time.DateTime))
+
+	gap := time.Date(2024, 3, 31, 2, 30, 0, 0, time.UTC)
+	path = writeFile(t, "GX010001.MP4", buildMovie(gap, gap))
+	date, err = ReadDate(path, CreateDate)
+	r.NoError(err)
+	r.Equal("2024-03-31 02:30:00", date.Time.Format(time.DateTime))
+}
+
+func TestReadDate_QuickTimeZeroDate(t *testing.T) {
+	path := writeFile(t, "GX010001.MP4", buildMovie(time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	_, err := ReadDate(path, CreateDate)
+
+	assert.ErrorIs(t, err, ErrNoDate)
+}
+
+func TestReadDate_Errors(t *testing.T) {
+	_, err := ReadDate(writeFile(t, "00001.MTS", []byte("video")), CreateDate)
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	_, err = ReadDate(writeFile(t, "IMG_0001.JPG", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02}), CreateDate)
+	assert.ErrorIs(t, err, ErrNoDate)
+
+	_, err = ReadDate(writeFile(t, "IMG_0001.JPG", []byte("not a jpeg")), CreateDate)
+	assert.EqualError(t, err, "not a JPEG file")
+}
+
+func TestParseExifDate(t *testing.T) {
+	tests := []struct {
+		value  string
+		subSec string
+		offset string
+		result string
+		err    bool
+	}{
+		{"2024:05:18 10:30:15", "", "", "2024-05-18T10:30:15", false},
+		{"2024:05:18 10:30:15", "5", "-05:00", "2024-05-18T10:30:15.5-05:00", false},
+		{"2024:05:18 10:30:15", "abc", "bad", "2024-05-18T10:30:15", false},
+		{"0000:00:00 00:00:00", "", "", "", true},
+		{"", "", "", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.value+test.offset, func(t *testing.T) {
+			date, err := parseExifDate(test.value, test.subSec, test.offset)
+
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			format := "2006-01-02T15:04:05.999999999"
+			if date.HasOffset {
+				format += "Z07:00"
+			}
+			require.Equal(t, test.result, date.Time.Format(format))
+		})
+	}
+}
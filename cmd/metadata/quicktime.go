@@ -0,0 +1,44 @@
+package metadata
+
+import (
+	"io"
+	"time"
+
+	"github.com/redrathnure/media-tool/cmd/mp4"
+)
+
+// readQuickTimeDates reads creation and modification times of the movie ('mvhd'), the first track ('tkhd')
+// and its media ('mdhd'). QuickTime times have no offset, they are returned as the wall clock like exiftool does
+func readQuickTimeDates(r io.ReadSeeker) (map[string]Date, error) {
+	movie, err := mp4.ReadMovie(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]Date)
+	addTimes := func(box *mp4.Box, createTag string, modifyTag string) {
+		if box == nil {
+			return
+		}
+		created, modified, err := box.Times()
+		if err != nil {
+			return
+		}
+		addQuickTimeDate(result, createTag, created)
+		addQuickTimeDate(result, modifyTag, modified)
+	}
+
+	addTimes(movie.Find("mvhd"), CreateDate, ModifyDate)
+	if tracks := movie.Tracks(); len(tracks) > 0 {
+		addTimes(tracks[0].Header(), TrackCreateDate, TrackModifyDate)
+		addTimes(tracks[0].MediaHeader(), MediaCreateDate, MediaModifyDate)
+	}
+	return result, nil
+}
+
+func addQuickTimeDate(dates map[string]Date, tag string, value time.Time) {
+	if value.IsZero() {
+		return
+	}
+	dates[tag] = Date{Time: value.UTC().Truncate(time.Second)}
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMetadataOutput = `[{
+  "SourceFile": "photos/DSC_0001.JPG",
+  "File:MIMEType": "image/jpeg",
+  "File:ImageWidth": 6016,
+  "File:ImageHeight": 4016,
+  "IFD0:Make": "NIKON CORPORATION",
+  "IFD0:Model": "NIKON D750",
+  "ExifIFD:SerialNumber": "0012345",
+  "ExifIFD:DateTimeOriginal": "2024:05:18 10:30:15",
+  "ExifIFD:SubSecTimeOriginal": "045",
+  "ExifIFD:OffsetTimeOriginal": "+02:00",
+  "IFD0:ModifyDate": "0000:00:00 00:00:00",
+  "Composite:GPSLatitude": 48.8584,
+  "Composite:GPSLongitude": -2.2945,
+  "Composite:GPSAltitude": 35.5
+},{
+  "SourceFile": "video/GX010001.MP4",
+  "File:MIMEType": "video/mp4",
+  "QuickTime:CreateDate": "2024:05:18 08:30:15",
+  "QuickTime:Duration": 90.5,
+  "Track1:ImageWidth": 3840,
+  "Track1:ImageHeight": 2160,
+  "GoPro:SerialNumber": "C3441325"
+}]`
+
+func TestParseMetadata(t *testing.T) {
+	r := require.New(t)
+
+	records, err := parseMetadata(testMetadataOutput)
+
+	r.NoError(err)
+	r.Len(records, 2)
+
+	photo := records[0]
+	r.Equal("image/jpeg", photo.MIMEType)
+	r.Equal(mediaImage, photo.MediaType)
+	r.Equal("NIKON CORPORATION", photo.Make)
+	r.Equal("NIKON D750", photo.Model)
+	r.Equal("0012345", photo.SerialNumber)
+	r.Equal(6016, photo.Width)
+	r.Equal(4016, photo.Height)
+	r.True(photo.DateTimeOriginal.HasOffset)
+	r.Equal(time.Date(2024, 5, 18, 8, 30, 15, 45_000_000, time.UTC), photo.DateTimeOriginal.Time.UTC())
+	r.True(photo.ModifyDate.Time.IsZero())
+	r.Equal(&gpsPosition{Latitude: 48.8584, Longitude: -2.2945, Altitude: 35.5}, photo.GPS)
+
+	video := records[1]
+	r.Equal("video/mp4", video.MIMEType)
+	r.Equal(mediaVideo, video.MediaType)
+	r.Equal("C3441325", video.SerialNumber)
+	r.Equal(3840, video.Width)
+	r.Equal(2160, video.Height)
+	r.Equal(90500*time.Millisecond, video.Duration)
+	r.True(video.CreateDate.HasOffset)
+	r.Equal(time.Date(2024, 5, 18, 8, 30, 15, 0, time.UTC), video.CreateDate.Time)
+	r.Nil(video.GPS)
+	r.Equal("3840", tagString(video.Tags, "Track1:ImageWidth"))
+}
+
+// fakeJsonExifToolScript prints source file records of each command, output of commands with 'BROKEN' file is not JSON,
+// 'MISSING' files are not read
+var fakeJsonExifToolScript = stayOpenExifToolScript(`
+      files=""
+      for arg in $args; do
+        case "$arg" in
+          -*) ;;
+          MISSING*) status=1; echo "Error: File not found - $arg" >&2 ;;
+          *) [ -n "$files" ] && files="$files,"; files="$files{\"SourceFile\": \"$arg\"}" ;;
+        esac
+      done
+      case "$args" in
+        *BROKEN*) echo "Error: unexpected output" ;;
+        *) echo "[$files]" ;;
+      esac`)
+
+func TestReadMetadata_Batches(t *testing.T) {
+	r := require.New(t)
+	tool := &exifToolWrapper{
+		cmd: "test-exiftool",
+		execCommand: func(name string, args ...string) *exec.Cmd {
+			return exec.Command("sh", "-c", fakeJsonExifToolScript)
+		},
+	}
+	defer tool.close()
+
+	files := make([]string, 0)
+	for i := 0; i < metadataBatchSize*2+1; i++ {
+		files = append(files, fmt.Sprintf("IMG_%04d.JPG", i))
+	}
+
+	records, err := tool.readMetadata(files)
+
+	r.NoError(err)
+	r.Len(records, len(files))
+	r.Equal("IMG_0000.JPG", records[0].SourceFile)
+	r.Equal(files[len(files)-1], records[len(records)-1].SourceFile)
+	r.Equal([]string{"-json", "-n", "-G1", files[len(files)-1]}, tool.args.args)
+	r.Equal(3, tool.process.counter)
+}
+
+func TestReadMetadata_ContinuesAfterBrokenBatch(t *testing.T) {
+	r := require.New(t)
+	tool := &exifToolWrapper{
+		cmd: "test-exiftool",
+		execCommand: func(name string, args ...string) *exec.Cmd {
+			return exec.Command("sh", "-c", fakeJsonExifToolScript)
+		},
+	}
+	defer tool.close()
+
+	files := make([]string, 0)
+	for i := 0; i < metadataBatchSize*2; i++ {
+		files = append(files, fmt.Sprintf("IMG_%04d.JPG", i))
+	}
+	files[0] = "BROKEN.JPG"
+
+	records, err := tool.readMetadata(files)
+
+	r.ErrorContains(err, fmt.Sprintf("unable to read metadata of %v file(s)", metadataBatchSize))
+	r.Len(records, metadataBatchSize)
+	r.Equal(files[metadataBatchSize], records[0].SourceFile)
+}
+
+func TestReadMetadata_CountsFailedFiles(t *testing.T) {
+	r := require.New(t)
+	tool := &exifToolWrapper{
+		cmd: "test-exiftool",
+		execCommand: func(name string, args ...string) *exec.Cmd {
+			return exec.Command("sh", "-c", fakeJsonExifToolScript)
+		},
+	}
+	defer tool.close()
+
+	files := make([]string, 0)
+	for i := 0; i < metadataBatchSize*3; i++ {
+		files = append(files, fmt.Sprintf("IMG_%04d.JPG", i))
+	}
+	files[0] = "MISSING.JPG"
+	files[metadataBatchSize*2] = "BROKEN.JPG"
+
+	records, err := tool.readMetadata(files)
+
+	r.ErrorContains(err, fmt.Sprintf("unable to read metadata of %v file(s)", metadataBatchSize+1))
+	r.Len(records, metadataBatchSize*2-1)
+}
+
+func TestMediaMetadata_Date(t *testing.T) {
+	record := newMediaMetadata(map[string]any{
+		"SourceFile":           "/tmp/GX010001.MP4",
+		"QuickTime:CreateDate": "2024:05:18 08:30:15",
+		"XMP-xmp:MetadataDate": "2024:05:19 10:00:00",
+	})
+
+	date, exists := record.date("CreateDate")
+	assert.True(t, exists)
+	assert.Equal(t, time.Date(2024, 5, 18, 8, 30, 15, 0, time.UTC), date.Time)
+
+	date, exists = record.date("MetadataDate")
+	assert.True(t, exists)
+	assert.Equal(t, time.Date(2024, 5, 19, 10, 0, 0, 0, time.Local), date.Time)
+
+	_, exists = record.date("DateTimeOriginal")
+	assert.False(t, exists)
+}
+
+func TestParseMetadata_Empty(t *testing.T) {
+	records, err := parseMetadata("")
+
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	_, err = parseMetadata("Error: File not found")
+	assert.Error(t, err)
+}
+
+func TestParseMetadataDate(t *testing.T) {
+	tests := []struct {
+		value     string
+		location  *time.Location
+		expected  time.Time
+		hasOffset bool
+	}{
+		{"2024:05:18 10:30:15", time.Local, time.Date(2024, 5, 18, 10, 30, 15, 0, time.Local), false},
+		{"2024:05:18 10:30:15.12", time.Local, time.Date(2024, 5, 18, 10, 30, 15, 120_000_000, time.Local), false},
+		{"2024:05:18 10:30:15", time.UTC, time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC), true},
+		{"2024:05:18 10:30:15Z", time.Local, time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC), true},
+		{"2024:05:18 10:30:15.5-05:00", time.UTC, time.Date(2024, 5, 18, 15, 30, 15, 500_000_000, time.UTC), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			date, err := parseMetadataDate(tt.value, tt.location)
+
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(date.Time), "%v != %v", tt.expected, date.Time)
+			assert.Equal(t, tt.hasOffset, date.HasOffset)
+		})
+	}
+
+	_, err := parseMetadataDate("0000:00:00 00:00:00", time.Local)
+	assert.Error(t, err)
+	_, err = parseMetadataDate("2024:05", time.Local)
+	assert.Error(t, err)
+}
+
+func TestSplitBatches(t *testing.T) {
+	assert.Empty(t, splitBatches(nil, 2))
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, splitBatches([]string{"a", "b", "c"}, 2))
+}
+
+func TestFindTag(t *testing.T) {
+	tags := map[string]any{"XMP-exif:Make": "xmp", "IFD0:Make": "exif", "Sony:Model": "model"}
+
+	value, _ := findTag(tags, "IFD0:Make")
+	assert.Equal(t, "exif", value)
+	value, _ = findTag(tags, "QuickTime:Model", "Model")
+	assert.Equal(t, "model", value)
+	_, exists := findTag(tags, "Software")
+	assert.False(t, exists)
+}
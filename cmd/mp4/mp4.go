@@ -0,0 +1,196 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// containerBoxes are boxes which payload is a list of child boxes
+var containerBoxes = map[string]bool{
+	"moov": true,
+	"trak": true,
+	"mdia": true,
+	"minf": true,
+	"stbl": true,
+	"edts": true,
+	"udta": true,
+}
+
+// quickTimeEpoch is the start of QuickTime times
+var quickTimeEpoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Box is ISO base media (MP4, MOV) box
+type Box struct {
+	Type     string
+	Data     []byte
+	Children []*Box
+}
+
+// ReadMovie finds top level 'moov' box and reads it with all children. Media data is not read
+func ReadMovie(r io.ReadSeeker) (*Box, error) {
+	offset := int64(0)
+	for {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		boxType, headerSize, size, err := readBoxHeader(r)
+		if err == io.EOF {
+			return nil, fmt.Errorf("'moov' box was not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if boxType == "moov" {
+			if size == 0 || size-headerSize > 1<<30 {
+				return nil, fmt.Errorf("unsupported 'moov' box size %v", size)
+			}
+			data := make([]byte, size-headerSize)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, fmt.Errorf("unable to read 'moov' box: %w", err)
+			}
+			return parseBox(boxType, data)
+		}
+
+		if size == 0 {
+			return nil, fmt.Errorf("'moov' box was not found")
+		}
+		offset += size
+	}
+}
+
+// readBoxHeader reads box type, header size and full box size. Size is 0 for the last box of the file
+func readBoxHeader(r io.Reader) (string, int64, int64, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return "", 0, 0, err
+	}
+
+	boxType := string(header[4:8])
+	size := int64(binary.BigEndian.Uint32(header))
+	if size != 1 {
+		if size != 0 && size < 8 {
+			return "", 0, 0, fmt.Errorf("invalid '%v' box size %v", boxType, size)
+		}
+		return boxType, 8, size, nil
+	}
+
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", 0, 0, err
+	}
+	size = int64(binary.BigEndian.Uint64(header))
+	if size < 16 {
+		return "", 0, 0, fmt.Errorf("invalid '%v' box size %v", boxType, size)
+	}
+	return boxType, 16, size, nil
+}
+
+func parseBox(boxType string, data []byte) (*Box, error) {
+	box := &Box{Type: boxType, Data: data}
+	if !containerBoxes[boxType] {
+		return box, nil
+	}
+
+	for offset := 0; offset+8 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[offset:]))
+		childType := string(data[offset+4 : offset+8])
+		headerSize := 8
+		switch {
+		case size == 1 && offset+16 <= len(data):
+			size = int(binary.BigEndian.Uint64(data[offset+8:]))
+			headerSize = 16
+		case size == 0:
+			size = len(data) - offset
+		}
+		if size < headerSize || offset+size > len(data) {
+			return nil, fmt.Errorf("invalid '%v' box size %v inside '%v'", childType, size, boxType)
+		}
+
+		child, err := parseBox(childType, data[offset+headerSize:offset+size])
+		if err != nil {
+			return nil, err
+		}
+		box.Children = append(box.Children, child)
+		offset += size
+	}
+	return box, nil
+}
+
+// Find returns the first descendant box by path of types, e.g. Find("mdia", "minf", "stbl")
+func (box *Box) Find(path ...string) *Box {
+	current := box
+	for _, boxType := range path {
+		var next *Box
+		for _, child := range current.Children {
+			if child.Type == boxType {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// FindAll returns child boxes of the type
+func (box *Box) FindAll(boxType string) []*Box {
+	result := make([]*Box, 0)
+	for _, child := range box.Children {
+		if child.Type == boxType {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+// Duration returns movie duration from the movie header ('mvhd' box)
+func (box *Box) Duration() (time.Duration, error) {
+	mvhd := box.Find("mvhd")
+	if mvhd == nil || len(mvhd.Data) < 20 {
+		return 0, fmt.Errorf("'mvhd' box was not found")
+	}
+
+	var timescale uint32
+	var duration uint64
+	if mvhd.Data[0] == 1 {
+		if len(mvhd.Data) < 32 {
+			return 0, fmt.Errorf("invalid 'mvhd' box size %v", len(mvhd.Data))
+		}
+		timescale = binary.BigEndian.Uint32(mvhd.Data[20:])
+		duration = binary.BigEndian.Uint64(mvhd.Data[24:])
+	} else {
+		timescale = binary.BigEndian.Uint32(mvhd.Data[12:])
+		duration = uint64(binary.BigEndian.Uint32(mvhd.Data[16:]))
+	}
+	if timescale == 0 {
+		return 0, fmt.Errorf("'mvhd' box has zero timescale")
+	}
+	return toDuration(duration, timescale), nil
+}
+
+// Times returns creation and modification times (UTC) of a header box: 'mvhd', 'tkhd' or 'mdhd'. Unset times are zero
+func (box *Box) Times() (time.Time, time.Time, error) {
+	if len(box.Data) < 12 || (box.Data[0] == 1 && len(box.Data) < 20) {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid '%v' box size %v", box.Type, len(box.Data))
+	}
+	if box.Data[0] == 1 {
+		return quickTime(binary.BigEndian.Uint64(box.Data[4:])), quickTime(binary.BigEndian.Uint64(box.Data[12:])), nil
+	}
+	return quickTime(uint64(binary.BigEndian.Uint32(box.Data[4:]))), quickTime(uint64(binary.BigEndian.Uint32(box.Data[8:]))), nil
+}
+
+func quickTime(seconds uint64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return quickTimeEpoch.Add(time.Duration(seconds) * time.Second)
+}
@@ -0,0 +1,150 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func box(boxType string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	return append(append(u32(uint32(len(data)+8)), boxType...), data...)
+}
+
+func u32(values ...uint32) []byte {
+	result := make([]byte, 0, len(values)*4)
+	for _, value := range values {
+		result = binary.BigEndian.AppendUint32(result, value)
+	}
+	return result
+}
+
+func u64(value uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, value)
+}
+
+func metaTrack(stbl ...[]byte) []byte {
+	return box("trak",
+		box("tkhd", make([]byte, 84)),
+		box("mdia",
+			box("mdhd", u32(0, 0, 0, 1000, 3000), make([]byte, 4)),
+			box("hdlr", u32(0, 0), []byte("meta"), make([]byte, 12)),
+			box("minf", box("stbl", stbl...)),
+		),
+	)
+}
+
+func TestReadMovie(t *testing.T) {
+	r := require.New(t)
+
+	file := bytes.Join([][]byte{
+		box("ftyp", []byte("mp41")),
+		// 64-bit size mdat
+		append(append(u32(1), "mdat"...), append(u64(16+4), []byte("data")...)...),
+		box("moov", box("mvhd", make([]byte, 100)), metaTrack()),
+	}, nil)
+
+	movie, err := ReadMovie(bytes.NewReader(file))
+
+	r.NoError(err)
+	r.Equal("moov", movie.Type)
+	r.Len(movie.Tracks(), 1)
+	r.NotNil(movie.Find("mvhd"))
+	r.Equal("meta", movie.Tracks()[0].HandlerType())
+	r.Equal(uint32(1000), movie.Tracks()[0].Timescale())
+	r.Nil(movie.Find("trak", "udta"))
+}
+
+func TestBox_Duration(t *testing.T) {
+	r := require.New(t)
+
+	movie, err := ReadMovie(bytes.NewReader(box("moov", box("mvhd", u32(0, 0, 0, 600, 90300), make([]byte, 80)))))
+	r.NoError(err)
+	duration, err := movie.Duration()
+	r.NoError(err)
+	r.Equal(150500*time.Millisecond, duration)
+
+	movie, err = ReadMovie(bytes.NewReader(box("moov", box("mvhd", u32(1<<24, 0, 0, 0, 0, 1000), u64(3600000), make([]byte, 80)))))
+	r.NoError(err)
+	duration, err = movie.Duration()
+	r.NoError(err)
+	r.Equal(time.Hour, duration)
+
+	movie, err = ReadMovie(bytes.NewReader(box("moov", metaTrack())))
+	r.NoError(err)
+	_, err = movie.Duration()
+	r.EqualError(err, "'mvhd' box was not found")
+}
+
+func TestBox_Times(t *testing.T) {
+	r := require.New(t)
+	created := uint64(time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC).Sub(quickTimeEpoch) / time.Second)
+
+	mvhd := &Box{Type: "mvhd", Data: append(u32(0, uint32(created), 0), make([]byte, 88)...)}
+	createTime, modifyTime, err := mvhd.Times()
+	r.NoError(err)
+	r.Equal(time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC), createTime)
+	r.True(modifyTime.IsZero())
+
+	tkhd := &Box{Type: "tkhd", Data: append(append(u32(1<<24), u64(created)...), u64(created+60)...)}
+	createTime, modifyTime, err = tkhd.Times()
+	r.NoError(err)
+	r.Equal(time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC), createTime)
+	r.Equal(time.Date(2024, 5, 18, 10, 31, 15, 0, time.UTC), modifyTime)
+
+	_, _, err = (&Box{Type: "mdhd", Data: make([]byte, 8)}).Times()
+	r.EqualError(err, "invalid 'mdhd' box size 8")
+}
+
+func TestReadMovie_NoMovie(t *testing.T) {
+	_, err := ReadMovie(bytes.NewReader(box("ftyp", []byte("mp41"))))
+
+	assert.EqualError(t, err, "'moov' box was not found")
+}
+
+func TestReadMovie_BrokenBox(t *testing.T) {
+	file := box("moov", box("trak", append(u32(100), "mdia"...)))
+
+	_, err := ReadMovie(bytes.NewReader(file))
+
+	assert.EqualError(t, err, "invalid 'mdia' box size 100 inside 'trak'")
+}
+
+func TestTrack_Samples(t *testing.T) {
+	r := require.New(t)
+
+	movie, err := ReadMovie(bytes.NewReader(box("moov", metaTrack(
+		box("stsd", u32(0, 1, 16), []byte("gpmd")),
+		box("stts", u32(0, 2, 2, 1000, 1, 500)),
+		box("stsz", u32(0, 0, 3, 10, 20, 30)),
+		box("stsc", u32(0, 2, 1, 2, 1, 2, 1, 1)),
+		box("co64", u32(0, 2), u64(1<<32), u64(100)),
+	))))
+	r.NoError(err)
+	track := movie.Tracks()[0]
+
+	samples, err := track.Samples()
+
+	r.NoError(err)
+	r.Equal("gpmd", track.SampleFormat())
+	r.Equal([]Sample{
+		{Offset: 1 << 32, Size: 10, Time: 0, Duration: time.Second},
+		{Offset: 1<<32 + 10, Size: 20, Time: time.Second, Duration: time.Second},
+		{Offset: 100, Size: 30, Time: 2 * time.Second, Duration: 500 * time.Millisecond},
+	}, samples)
+}
+
+func TestTrack_SamplesWithoutTable(t *testing.T) {
+	movie, err := ReadMovie(bytes.NewReader(box("moov", metaTrack(
+		box("stsz", u32(0, 16, 2)),
+	))))
+	require.NoError(t, err)
+
+	_, err = movie.Tracks()[0].Samples()
+
+	assert.EqualError(t, err, "track has no chunk offsets")
+}
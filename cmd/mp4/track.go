@@ -0,0 +1,220 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Track is a 'trak' box of the movie
+type Track struct {
+	box *Box
+}
+
+// Sample is a media sample of the track. Time and Duration are relative to the track start
+type Sample struct {
+	Offset   int64
+	Size     int64
+	Time     time.Duration
+	Duration time.Duration
+}
+
+// Tracks returns movie tracks
+func (box *Box) Tracks() []Track {
+	result := make([]Track, 0)
+	for _, trak := range box.FindAll("trak") {
+		result = append(result, Track{box: trak})
+	}
+	return result
+}
+
+// HandlerType returns track handler type, e.g. 'vide', 'soun' or 'meta'
+func (track Track) HandlerType() string {
+	hdlr := track.box.Find("mdia", "hdlr")
+	if hdlr == nil || len(hdlr.Data) < 12 {
+		return ""
+	}
+	return string(hdlr.Data[8:12])
+}
+
+// Header returns track header ('tkhd' box)
+func (track Track) Header() *Box {
+	return track.box.Find("tkhd")
+}
+
+// MediaHeader returns media header ('mdhd' box)
+func (track Track) MediaHeader() *Box {
+	return track.box.Find("mdia", "mdhd")
+}
+
+// SampleFormat returns format of the first sample description, e.g. 'avc1' or 'gpmd'
+func (track Track) SampleFormat() string {
+	stsd := track.box.Find("mdia", "minf", "stbl", "stsd")
+	if stsd == nil || len(stsd.Data) < 16 {
+		return ""
+	}
+	return string(stsd.Data[12:16])
+}
+
+// Timescale returns number of track time units per second
+func (track Track) Timescale() uint32 {
+	mdhd := track.box.Find("mdia", "mdhd")
+	if mdhd == nil || len(mdhd.Data) < 4 {
+		return 0
+	}
+	if mdhd.Data[0] == 1 {
+		if len(mdhd.Data) < 24 {
+			return 0
+		}
+		return binary.BigEndian.Uint32(mdhd.Data[20:])
+	}
+	if len(mdhd.Data) < 16 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(mdhd.Data[12:])
+}
+
+// Samples calculates file offsets, sizes and times of track samples using the sample table
+func (track Track) Samples() ([]Sample, error) {
+	stbl := track.box.Find("mdia", "minf", "stbl")
+	if stbl == nil {
+		return nil, fmt.Errorf("track has no sample table")
+	}
+	timescale := track.Timescale()
+	if timescale == 0 {
+		return nil, fmt.Errorf("track has no timescale")
+	}
+
+	sizes, err := readSampleSizes(stbl.Find("stsz"))
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := readChunkOffsets(stbl)
+	if err != nil {
+		return nil, err
+	}
+	samplesPerChunk, err := readSamplesPerChunk(stbl.Find("stsc"), len(chunks))
+	if err != nil {
+		return nil, err
+	}
+	durations, err := readSampleDurations(stbl.Find("stts"), len(sizes))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Sample, 0, len(sizes))
+	elapsed := uint64(0)
+	for chunk, offset := range chunks {
+		for i := uint32(0); i < samplesPerChunk[chunk] && len(result) < len(sizes); i++ {
+			index := len(result)
+			result = append(result, Sample{
+				Offset:   offset,
+				Size:     sizes[index],
+				Time:     toDuration(elapsed, timescale),
+				Duration: toDuration(uint64(durations[index]), timescale),
+			})
+			offset += sizes[index]
+			elapsed += uint64(durations[index])
+		}
+	}
+	if len(result) != len(sizes) {
+		return nil, fmt.Errorf("sample table describes %v of %v samples", len(result), len(sizes))
+	}
+	return result, nil
+}
+
+func toDuration(units uint64, timescale uint32) time.Duration {
+	return time.Duration(units * uint64(time.Second) / uint64(timescale))
+}
+
+func readSampleSizes(stsz *Box) ([]int64, error) {
+	if stsz == nil || len(stsz.Data) < 12 {
+		return nil, fmt.Errorf("invalid 'stsz' box")
+	}
+	sampleSize := binary.BigEndian.Uint32(stsz.Data[4:])
+	count := int(binary.BigEndian.Uint32(stsz.Data[8:]))
+	if sampleSize == 0 && len(stsz.Data) < 12+count*4 {
+		return nil, fmt.Errorf("invalid 'stsz' box")
+	}
+
+	result := make([]int64, count)
+	for i := range result {
+		if sampleSize != 0 {
+			result[i] = int64(sampleSize)
+		} else {
+			result[i] = int64(binary.BigEndian.Uint32(stsz.Data[12+i*4:]))
+		}
+	}
+	return result, nil
+}
+
+func readChunkOffsets(stbl *Box) ([]int64, error) {
+	box, entrySize := stbl.Find("stco"), 4
+	if box == nil {
+		box, entrySize = stbl.Find("co64"), 8
+	}
+	if box == nil || len(box.Data) < 8 {
+		return nil, fmt.Errorf("track has no chunk offsets")
+	}
+	count := int(binary.BigEndian.Uint32(box.Data[4:]))
+	if len(box.Data) < 8+count*entrySize {
+		return nil, fmt.Errorf("invalid '%v' box", box.Type)
+	}
+
+	result := make([]int64, count)
+	for i := range result {
+		if entrySize == 4 {
+			result[i] = int64(binary.BigEndian.Uint32(box.Data[8+i*4:]))
+		} else {
+			result[i] = int64(binary.BigEndian.Uint64(box.Data[8+i*8:]))
+		}
+	}
+	return result, nil
+}
+
+// readSamplesPerChunk expands 'stsc' runs into number of samples of every chunk
+func readSamplesPerChunk(stsc *Box, chunkCount int) ([]uint32, error) {
+	if stsc == nil || len(stsc.Data) < 8 {
+		return nil, fmt.Errorf("invalid 'stsc' box")
+	}
+	count := int(binary.BigEndian.Uint32(stsc.Data[4:]))
+	if len(stsc.Data) < 8+count*12 {
+		return nil, fmt.Errorf("invalid 'stsc' box")
+	}
+
+	result := make([]uint32, chunkCount)
+	for i := 0; i < count; i++ {
+		entry := stsc.Data[8+i*12:]
+		firstChunk := int(binary.BigEndian.Uint32(entry))
+		samples := binary.BigEndian.Uint32(entry[4:])
+		for chunk := max(firstChunk, 1); chunk <= chunkCount; chunk++ {
+			result[chunk-1] = samples
+		}
+	}
+	return result, nil
+}
+
+// readSampleDurations expands 'stts' runs into duration of every sample
+func readSampleDurations(stts *Box, sampleCount int) ([]uint32, error) {
+	if stts == nil || len(stts.Data) < 8 {
+		return nil, fmt.Errorf("invalid 'stts' box")
+	}
+	count := int(binary.BigEndian.Uint32(stts.Data[4:]))
+	if len(stts.Data) < 8+count*8 {
+		return nil, fmt.Errorf("invalid 'stts' box")
+	}
+
+	result := make([]uint32, 0, sampleCount)
+	for i := 0; i < count && len(result) < sampleCount; i++ {
+		entry := stts.Data[8+i*8:]
+		samples := int(binary.BigEndian.Uint32(entry))
+		delta := binary.BigEndian.Uint32(entry[4:])
+		for j := 0; j < samples && len(result) < sampleCount; j++ {
+			result = append(result, delta)
+		}
+	}
+	for len(result) < sampleCount {
+		result = append(result, 0)
+	}
+	return result, nil
+}
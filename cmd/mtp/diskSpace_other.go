@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package mtp
+
+import "fmt"
+
+// GetDiskSpace is not supported on this platform
+func GetDiskSpace(path string) (free int64, total int64, err error) {
+	return 0, 0, fmt.Errorf("disk space check is not supported on this platform")
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package mtp
+
+import "syscall"
+
+// GetDiskSpace returns free (available to the current user) and total space of the volume with the path
+func GetDiskSpace(path string) (free int64, total int64, err error) {
+	var stat syscall.Statfs_t
+	if err = syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	blockSize := uint64(stat.Bsize)
+	return int64(uint64(stat.Bavail) * blockSize), int64(uint64(stat.Blocks) * blockSize), nil
+}
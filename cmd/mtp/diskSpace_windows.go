@@ -0,0 +1,30 @@
+//go:build windows
+// +build windows
+
+package mtp
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// GetDiskSpace returns free (available to the current user) and total space of the volume with the path
+func GetDiskSpace(path string) (free int64, total int64, err error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var freeBytes, totalBytes uint64
+	ret, _, callErr := getDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&freeBytes)),
+		uintptr(unsafe.Pointer(&totalBytes)),
+		0)
+	if ret == 0 {
+		return 0, 0, callErr
+	}
+	return int64(freeBytes), int64(totalBytes), nil
+}
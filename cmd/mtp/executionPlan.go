@@ -3,9 +3,17 @@ package mtp
 import "fmt"
 
 type ExecutionPlan struct {
-	files      []*wpdFile
-	totalSize  int64
-	wpdRootDir string
+	files        []*wpdFile
+	totalSize    int64
+	skippedFiles int
+	wpdRootDir   string
+	// junkFiles are deleted from the device without copying
+	junkFiles []*wpdFile
+}
+
+// PlanFilter decides whether device file should be added to the execution plan
+type PlanFilter interface {
+	accept(file *wpdFile, wpdRootDir string) bool
 }
 
 type ExecutionFileIterator struct {
@@ -23,12 +31,20 @@ func (executionPlan *ExecutionPlan) GetFilesCount() int {
 	return len(executionPlan.files)
 }
 
+func (executionPlan *ExecutionPlan) GetSkippedCount() int {
+	return executionPlan.skippedFiles
+}
+
 func (executionPlan *ExecutionPlan) GetTotalSize() int64 {
 	return executionPlan.totalSize
 }
 
+func (executionPlan *ExecutionPlan) GetJunkCount() int {
+	return len(executionPlan.junkFiles)
+}
+
 func (executionPlan *ExecutionPlan) IsEmpty() bool {
-	return executionPlan.GetFilesCount() == 0
+	return executionPlan.GetFilesCount() == 0 && executionPlan.GetJunkCount() == 0
 }
 
 func (executionPlan *ExecutionPlan) GetTotalSizeString() string {
@@ -89,23 +105,36 @@ func (fileIt *ExecutionFileIterator) GetFilesTotal() int {
 	return fileIt.plan.GetFilesCount()
 }
 
-func BuildExecutionPlan(rootDirs []*wpdFile, wpdRootDir string) *ExecutionPlan {
+func BuildExecutionPlan(rootDirs []*wpdFile, wpdRootDir string, filters ...PlanFilter) *ExecutionPlan {
 	var result = new(ExecutionPlan)
 	result.wpdRootDir = wpdRootDir
 	for _, file := range rootDirs {
-		addToPlan(file, result)
+		addToPlan(file, result, filters)
 	}
 	return result
 }
 
-func addToPlan(wpdFile *wpdFile, executionPlan *ExecutionPlan) {
+func addToPlan(wpdFile *wpdFile, executionPlan *ExecutionPlan, filters []PlanFilter) {
 	if wpdFile.wpdObject.IsDir {
 		children := wpdFile.chidren
 
 		for _, child := range children {
-			addToPlan(child, executionPlan)
+			addToPlan(child, executionPlan, filters)
 		}
+	} else if !acceptedByAll(wpdFile, executionPlan.wpdRootDir, filters) {
+		executionPlan.skippedFiles++
+	} else if wpdFile.isJunk {
+		executionPlan.junkFiles = append(executionPlan.junkFiles, wpdFile)
 	} else {
 		executionPlan.AddFile(wpdFile)
 	}
 }
+
+func acceptedByAll(wpdFile *wpdFile, wpdRootDir string, filters []PlanFilter) bool {
+	for _, filter := range filters {
+		if !filter.accept(wpdFile, wpdRootDir) {
+			return false
+		}
+	}
+	return true
+}
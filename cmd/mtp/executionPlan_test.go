@@ -5,8 +5,6 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
-
-	"github.com/tobwithu/gowpd"
 )
 
 func TestExecutionPlan_EmptyPlan(t *testing.T) {
@@ -132,9 +130,34 @@ func TestExecutionFileIterator_3xFile(t *testing.T) {
 	r.Equal(int64(0), sut.GetSizeLeft())
 }
 
+func TestBuildExecutionPlan_Filters(t *testing.T) {
+	r := require.New(t)
+
+	dir := wpdFile{filePath: "dir", wpdObject: &Object{IsDir: true}}
+	for indx := 0; indx < 3; indx++ {
+		fileName := fmt.Sprintf("file_%v", indx)
+		dir.chidren = append(dir.chidren, &wpdFile{filePath: fileName, fileName: fileName, wpdObject: &Object{Size: 100}})
+	}
+
+	sut := BuildExecutionPlan([]*wpdFile{&dir}, "", testPlanFilter{skip: "file_1"})
+
+	r.Equal(2, sut.GetFilesCount())
+	r.Equal(1, sut.GetSkippedCount())
+	r.Equal(int64(200), sut.GetTotalSize())
+	r.Equal("file_0", sut.GetFileInterator().Current().fileName)
+}
+
+type testPlanFilter struct {
+	skip string
+}
+
+func (filter testPlanFilter) accept(file *wpdFile, wpdRootDir string) bool {
+	return file.fileName != filter.skip
+}
+
 func addFile(executionPlan *ExecutionPlan, files int) *ExecutionPlan {
 	for indx := 0; indx < files; indx++ {
-		wpdObject := &gowpd.Object{}
+		wpdObject := &Object{}
 		wpdObject.Size = int64((indx + 1) * 100)
 		fileName := fmt.Sprintf("file_%v", indx)
 		file := wpdFile{filePath: fileName, fileName: fileName, wpdObject: wpdObject}
@@ -144,7 +167,7 @@ func addFile(executionPlan *ExecutionPlan, files int) *ExecutionPlan {
 }
 
 func setTotalSize(executionPlan *ExecutionPlan, totalSize int) *ExecutionPlan {
-	wpdObject := &gowpd.Object{}
+	wpdObject := &Object{}
 	wpdObject.Size = int64(totalSize)
 	file := wpdFile{wpdObject: wpdObject}
 	executionPlan.AddFile(&file)
@@ -0,0 +1,114 @@
+package mtp
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileFilter selects device files to be imported. Zero values mean no restriction.
+// Files rejected by the filter are not added to the execution plan, so they are never copied or deleted
+type FileFilter struct {
+	// Since and Until limit file modification time: Since <= time < Until
+	Since time.Time
+	Until time.Time
+	// IncludeExt and ExcludeExt are file extensions without leading dot, case insensitive
+	IncludeExt []string
+	ExcludeExt []string
+	MinSize    int64
+	MaxSize    int64
+	// IncludePaths and ExcludePaths are glob patterns of paths relative to the device dir, e.g. '100GOPRO/*.MP4'
+	// or '**/*.LRV'. Patterns without '/' are matched against file names
+	IncludePaths []string
+	ExcludePaths []string
+}
+
+// IsEmpty checks whether the filter accepts all files
+func (filter FileFilter) IsEmpty() bool {
+	return filter.Since.IsZero() && filter.Until.IsZero() &&
+		len(filter.IncludeExt) == 0 && len(filter.ExcludeExt) == 0 &&
+		filter.MinSize <= 0 && filter.MaxSize <= 0 &&
+		len(filter.IncludePaths) == 0 && len(filter.ExcludePaths) == 0
+}
+
+func (filter FileFilter) accept(file *wpdFile, wpdRootDir string) bool {
+	obj := file.wpdObject
+
+	modTime := time.Unix(obj.ModTime, 0)
+	if !filter.Since.IsZero() && modTime.Before(filter.Since) {
+		log.Debugf("Skipping '%v', it was modified before %v", file.filePath, filter.Since)
+		return false
+	}
+	if !filter.Until.IsZero() && !modTime.Before(filter.Until) {
+		log.Debugf("Skipping '%v', it was modified after %v", file.filePath, filter.Until)
+		return false
+	}
+
+	if filter.MinSize > 0 && obj.Size < filter.MinSize || filter.MaxSize > 0 && obj.Size > filter.MaxSize {
+		log.Debugf("Skipping '%v', size %v is out of range", file.filePath, SizeToLabel(obj.Size))
+		return false
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(file.fileName), ".")
+	if len(filter.IncludeExt) > 0 && !containsFold(filter.IncludeExt, ext) || containsFold(filter.ExcludeExt, ext) {
+		log.Debugf("Skipping '%v', '%v' extension is filtered out", file.filePath, ext)
+		return false
+	}
+
+	relPath := filepath.ToSlash(file.relPath(wpdRootDir))
+	if len(filter.IncludePaths) > 0 && !matchAnyGlob(filter.IncludePaths, relPath) || matchAnyGlob(filter.ExcludePaths, relPath) {
+		log.Debugf("Skipping '%v', path is filtered out", file.filePath)
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, item := range values {
+		if strings.EqualFold(strings.TrimPrefix(item, "."), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyGlob(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches slash separated relative path. '**' matches any number of dirs,
+// patterns without '/' are matched against the file name. Matching is case insensitive
+func matchGlob(pattern string, relPath string) bool {
+	pattern = strings.ToLower(filepath.ToSlash(pattern))
+	relPath = strings.ToLower(relPath)
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(relPath))
+		return matched
+	}
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(relPath, "/"))
+}
+
+func matchSegments(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], segments[0])
+	return matched && matchSegments(pattern[1:], segments[1:])
+}
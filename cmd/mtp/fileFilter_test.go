@@ -0,0 +1,86 @@
+package mtp
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileFilter_Accept(t *testing.T) {
+	rootDir := filepath.FromSlash("/DCIM")
+	newFile := func(relPath string, size int64, modTime time.Time) *wpdFile {
+		filePath := filepath.Join(rootDir, filepath.FromSlash(relPath))
+		return &wpdFile{
+			fileName:  filepath.Base(filePath),
+			filePath:  filePath,
+			wpdObject: &Object{Name: filepath.Base(filePath), Size: size, ModTime: modTime.Unix()},
+		}
+	}
+	video := newFile("100GOPRO/GX010001.MP4", 1000, testModTime)
+	preview := newFile("100GOPRO/GX010001.LRV", 100, testModTime.Add(-48*time.Hour))
+
+	tests := []struct {
+		name     string
+		filter   FileFilter
+		file     *wpdFile
+		expected bool
+	}{
+		{"empty", FileFilter{}, video, true},
+		{"since", FileFilter{Since: testModTime}, video, true},
+		{"since rejects", FileFilter{Since: testModTime.Add(-time.Hour)}, preview, false},
+		{"until is exclusive", FileFilter{Until: testModTime}, video, false},
+		{"until", FileFilter{Until: testModTime}, preview, true},
+		{"include ext", FileFilter{IncludeExt: []string{"mp4", "jpg"}}, video, true},
+		{"include ext rejects", FileFilter{IncludeExt: []string{".mp4"}}, preview, false},
+		{"exclude ext", FileFilter{ExcludeExt: []string{"LRV"}}, preview, false},
+		{"min size", FileFilter{MinSize: 1000}, video, true},
+		{"min size rejects", FileFilter{MinSize: 1000}, preview, false},
+		{"max size rejects", FileFilter{MaxSize: 999}, video, false},
+		{"include name glob", FileFilter{IncludePaths: []string{"GX01*.mp4"}}, video, true},
+		{"include path glob rejects", FileFilter{IncludePaths: []string{"100NIKON/*"}}, video, false},
+		{"include any dir glob", FileFilter{IncludePaths: []string{"**/*.MP4"}}, video, true},
+		{"exclude path glob", FileFilter{ExcludePaths: []string{"100GOPRO/*.LRV"}}, preview, false},
+		{"exclude path glob keeps", FileFilter{ExcludePaths: []string{"100GOPRO/*.LRV"}}, video, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.filter.accept(tt.file, rootDir))
+			assert.Equal(t, tt.name == "empty", tt.filter.IsEmpty())
+		})
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	assert.True(t, matchGlob("*.mp4", "100GOPRO/GX010001.MP4"))
+	assert.True(t, matchGlob("100GOPRO/*", "100GOPRO/GX010001.MP4"))
+	assert.False(t, matchGlob("100GOPRO/*", "100GOPRO/sub/GX010001.MP4"))
+	assert.True(t, matchGlob("100GOPRO/**", "100GOPRO/sub/GX010001.MP4"))
+	assert.True(t, matchGlob("**/sub/*.MP4", "100GOPRO/sub/GX010001.MP4"))
+	assert.True(t, matchGlob("**/GX010001.MP4", "GX010001.MP4"))
+	assert.False(t, matchGlob("**/sub", "100GOPRO/sub/GX010001.MP4"))
+}
+
+func TestLoadFromAllWpd_FilteredFilesKept(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "old video", testModTime.Add(-72*time.Hour)).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "new video", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.LRV", "preview", testModTime)
+	source := newFakeSource(goPro)
+	options := ImportOptions{Files: FileFilter{Since: testModTime.Add(-24 * time.Hour), ExcludeExt: []string{"lrv"}}}
+
+	resultDir, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), options)
+
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "0", "GX010002.MP4"), "new video", testModTime)
+	r.NoFileExists(filepath.Join(resultDir, "0", "GX010001.MP4"))
+	r.NoFileExists(filepath.Join(resultDir, "0", "GX010002.LRV"))
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010001.MP4"))
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010002.LRV"))
+	r.False(goPro.hasFile("DCIM/100GOPRO/GX010002.MP4"))
+}
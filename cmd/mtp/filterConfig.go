@@ -0,0 +1,249 @@
+package mtp
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// BuiltInFilters are device filters which may be referenced by name from configuration
+var BuiltInFilters = map[string]MtpDeviceFilter{
+	"gopro":    GoProFilter,
+	"camvideo": CamFilter,
+	"sdphotos": SdPhotosFilter,
+}
+
+var devicePropertyNames = map[string]bool{"name": true, "description": true, "manufacturer": true, "serial": true}
+
+// FilterError describes invalid filter configuration
+type FilterError struct {
+	// Key is configuration key of invalid node, e.g. 'import.profiles.dji.filter.any[1]'
+	Key string
+	// Position is 1-based position in filter expression, 0 if the whole node is invalid
+	Position int
+	Message  string
+}
+
+func (err *FilterError) Error() string {
+	if err.Position > 0 {
+		return fmt.Sprintf("'%s': %s at position %v", err.Key, err.Message, err.Position)
+	}
+	return fmt.Sprintf("'%s': %s", err.Key, err.Message)
+}
+
+// ParseDeviceFilter builds device filter from configuration tree (e.g. decoded YAML). Key is configuration key
+// of the tree and is used in error messages. Supported nodes:
+//
+//	any: [...]             - at least one of child filters matches
+//	all: [...]             - all child filters match (same as a plain list)
+//	not: {...}             - child filter does not match
+//	name: HERO             - device property contains the text (name, description, manufacturer or serial)
+//	has: DCIM/100GOPRO     - device has the file or directory
+//	capacity: '>=32G'      - device capacity comparison
+//	"expression"           - filter expression, see ParseFilterExpression()
+func ParseDeviceFilter(key string, node interface{}) (MtpDeviceFilter, error) {
+	switch value := node.(type) {
+	case string:
+		return ParseFilterExpression(key, value)
+	case []interface{}:
+		filters, err := parseDeviceFilters(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return AndFilter{filters}, nil
+	case map[string]interface{}:
+		return parseDeviceFilterMap(key, value)
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for childKey, child := range value {
+			converted[fmt.Sprint(childKey)] = child
+		}
+		return parseDeviceFilterMap(key, converted)
+	case nil:
+		return nil, &FilterError{Key: key, Message: "empty filter"}
+	}
+	return nil, &FilterError{Key: key, Message: fmt.Sprintf("unexpected '%v' filter", node)}
+}
+
+// ParseFilterExpression parses single filter expression:
+//
+//	gopro                  - built-in filter (gopro, camvideo or sdphotos)
+//	has:DCIM/100GOPRO      - device has the file or directory
+//	name~HERO              - device property contains the text, case insensitive
+//	serial=C3441325        - device property equals the text, case insensitive
+//	description=~^HERO\d+  - device property matches the regexp
+//	capacity>=32G          - device capacity comparison (<, <=, >, >= or =)
+//	!name~HERO             - negation of any expression above
+//
+// Supported properties are name, description, manufacturer and serial
+func ParseFilterExpression(key string, expression string) (MtpDeviceFilter, error) {
+	fail := func(pos int, format string, args ...interface{}) (MtpDeviceFilter, error) {
+		return nil, &FilterError{Key: key, Position: pos + 1, Message: fmt.Sprintf(format, args...)}
+	}
+
+	pos := 0
+	negate := strings.HasPrefix(expression, "!")
+	if negate {
+		pos++
+	}
+
+	nameStart := pos
+	for pos < len(expression) && isFilterNameChar(expression[pos]) {
+		pos++
+	}
+	name := strings.ToLower(expression[nameStart:pos])
+	if name == "" {
+		return fail(nameStart, "filter name is expected")
+	}
+
+	var filter MtpDeviceFilter
+	if pos == len(expression) {
+		builtIn, exists := BuiltInFilters[name]
+		if !exists {
+			return fail(nameStart, "unknown '%s' built-in filter", expression[nameStart:])
+		}
+		filter = builtIn
+	} else {
+		operatorStart := pos
+		for pos < len(expression) && strings.IndexByte("~=:<>", expression[pos]) >= 0 {
+			pos++
+		}
+		operator := expression[operatorStart:pos]
+		value := expression[pos:]
+		if operator == "" {
+			return fail(operatorStart, "operator is expected")
+		}
+		if value == "" {
+			return fail(pos, "value is expected")
+		}
+
+		switch {
+		case name == "has":
+			if operator != ":" {
+				return fail(operatorStart, "unexpected '%s' operator, ':' is expected", operator)
+			}
+			filter = HasFileFilter{fileName: filepath.FromSlash(strings.Trim(value, "/"))}
+		case name == "capacity":
+			if operator != "<" && operator != "<=" && operator != ">" && operator != ">=" && operator != "=" {
+				return fail(operatorStart, "unexpected '%s' operator, one of <, <=, >, >=, = is expected", operator)
+			}
+			size, err := ParseSize(value)
+			if err != nil {
+				return fail(pos, "%v", err)
+			}
+			filter = CapacityFilter{operator: operator, size: size}
+		case devicePropertyNames[name]:
+			propertyFilter := DevicePropertyFilter{property: name, operator: operator, value: value}
+			switch operator {
+			case "~", "=":
+			case "=~":
+				regex, err := regexp.Compile(value)
+				if err != nil {
+					return fail(pos, "invalid regexp: %v", err)
+				}
+				propertyFilter.regex = regex
+			default:
+				return fail(operatorStart, "unexpected '%s' operator, one of ~, =, =~ is expected", operator)
+			}
+			filter = propertyFilter
+		default:
+			return fail(nameStart, "unknown '%s' filter", expression[nameStart:operatorStart])
+		}
+	}
+
+	if negate {
+		return NotFilter{filter}, nil
+	}
+	return filter, nil
+}
+
+func parseDeviceFilterMap(key string, node map[string]interface{}) (MtpDeviceFilter, error) {
+	if len(node) == 0 {
+		return nil, &FilterError{Key: key, Message: "empty filter"}
+	}
+
+	names := make([]string, 0, len(node))
+	for name := range node {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	filters := make([]MtpDeviceFilter, 0, len(names))
+	for _, name := range names {
+		filter, err := parseDeviceFilterNode(key+"."+name, strings.ToLower(name), node[name])
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return AndFilter{filters}, nil
+}
+
+func parseDeviceFilterNode(key string, name string, value interface{}) (MtpDeviceFilter, error) {
+	switch {
+	case name == "any" || name == "all":
+		children, ok := value.([]interface{})
+		if !ok {
+			return nil, &FilterError{Key: key, Message: "list of filters is expected"}
+		}
+		filters, err := parseDeviceFilters(key, children)
+		if err != nil {
+			return nil, err
+		}
+		if name == "any" {
+			return OrFilter{filters}, nil
+		}
+		return AndFilter{filters}, nil
+	case name == "not":
+		filter, err := ParseDeviceFilter(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return NotFilter{filter}, nil
+	case name == "has":
+		text, ok := value.(string)
+		if !ok || text == "" {
+			return nil, &FilterError{Key: key, Message: "non empty text is expected"}
+		}
+		return HasFileFilter{fileName: filepath.FromSlash(strings.Trim(text, "/"))}, nil
+	case name == "capacity":
+		text, ok := value.(string)
+		if !ok || text == "" {
+			return nil, &FilterError{Key: key, Message: "comparison (e.g. '>=32G') is expected"}
+		}
+		filter, err := ParseFilterExpression(key, name+text)
+		if filterErr, ok := err.(*FilterError); ok {
+			filterErr.Position = max(filterErr.Position-len(name), 1)
+		}
+		return filter, err
+	case devicePropertyNames[name]:
+		text, ok := value.(string)
+		if !ok || text == "" {
+			return nil, &FilterError{Key: key, Message: "non empty text is expected"}
+		}
+		return DevicePropertyFilter{property: name, operator: "~", value: text}, nil
+	}
+	return nil, &FilterError{Key: key, Message: "unknown filter"}
+}
+
+func parseDeviceFilters(key string, nodes []interface{}) ([]MtpDeviceFilter, error) {
+	filters := make([]MtpDeviceFilter, 0, len(nodes))
+	for i, node := range nodes {
+		filter, err := ParseDeviceFilter(fmt.Sprintf("%s[%v]", key, i), node)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+func isFilterNameChar(char byte) bool {
+	return char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || char == '_'
+}
@@ -0,0 +1,199 @@
+package mtp
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeviceFilter_Tree(t *testing.T) {
+	r := require.New(t)
+
+	filter, err := ParseDeviceFilter("filter", map[string]interface{}{
+		"any": []interface{}{
+			map[string]interface{}{"name": "DJI"},
+			map[interface{}]interface{}{"all": []interface{}{
+				map[string]interface{}{"has": "DCIM/100MEDIA"},
+				map[string]interface{}{"not": "gopro"},
+			}},
+		},
+	})
+	r.NoError(err)
+
+	r.True(accepts(filter, newFakeDevice("DJI Mini", "drone")))
+	r.True(accepts(filter, newFakeDevice("SD", "card").withDir("DCIM/100MEDIA")))
+	r.False(accepts(filter, newFakeDevice("HERO9", "camera").withDir("DCIM/100MEDIA")))
+	r.False(accepts(filter, newFakeDevice("Phone", "phone").withDir("DCIM/Camera")))
+}
+
+func TestParseDeviceFilter_Expressions(t *testing.T) {
+	r := require.New(t)
+
+	filter, err := ParseDeviceFilter("filter", map[string]interface{}{
+		"any": []interface{}{"name~HERO", "name~GoPro", "has:DCIM/100GOPRO"},
+	})
+	r.NoError(err)
+
+	r.True(accepts(filter, newFakeDevice("HERO8 Black", "")))
+	r.True(accepts(filter, newFakeDevice("gopro max", "")))
+	r.True(accepts(filter, newFakeDevice("SD", "").withDir("DCIM/100GOPRO")))
+	r.False(accepts(filter, newFakeDevice("SD", "").withDir("DCIM/100NIKON")))
+}
+
+func TestParseFilterExpression_Predicates(t *testing.T) {
+	device := newFakeDevice("HERO9 Black", "GoPro camera").withInfo("GoPro", "C3441325", 64<<30).withDir("DCIM/100GOPRO")
+	unknown := newFakeDevice("SD", "card")
+
+	tests := []struct {
+		expression string
+		device     *fakeDevice
+		expected   bool
+	}{
+		{"gopro", device, true},
+		{"!gopro", device, false},
+		{"has:DCIM/100GOPRO", device, true},
+		{"has:/DCIM/100NIKON", device, false},
+		{"name~hero", device, true},
+		{"name=hero9 black", device, true},
+		{"name=HERO9", device, false},
+		{"name=~^HERO[0-9]+ ", device, true},
+		{"name=~^HERO[0-9]+$", device, false},
+		{"description~camera", device, true},
+		{"manufacturer=gopro", device, true},
+		{"serial=C3441325", device, true},
+		{"!serial~C344", device, false},
+		{"capacity>=64G", device, true},
+		{"capacity>64GB", device, false},
+		{"capacity<128GiB", device, true},
+		{"capacity<=32G", device, false},
+		{"capacity=65536M", device, true},
+		{"capacity<128G", unknown, false},
+		{"manufacturer~gopro", unknown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			filter, err := ParseFilterExpression("filter", tt.expression)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, accepts(filter, tt.device))
+		})
+	}
+}
+
+func TestParseDeviceFilter_Errors(t *testing.T) {
+	tests := []struct {
+		node     interface{}
+		expected string
+	}{
+		{"unknown", "'filter': unknown 'unknown' built-in filter at position 1"},
+		{nil, "'filter': empty filter"},
+		{"", "'filter': filter name is expected at position 1"},
+		{"!~HERO", "'filter': filter name is expected at position 2"},
+		{"name HERO", "'filter': operator is expected at position 5"},
+		{"name~", "'filter': value is expected at position 6"},
+		{"size>1G", "'filter': unknown 'size' filter at position 1"},
+		{"has~DCIM", "'filter': unexpected '~' operator, ':' is expected at position 4"},
+		{"name<HERO", "'filter': unexpected '<' operator, one of ~, =, =~ is expected at position 5"},
+		{"capacity~1G", "'filter': unexpected '~' operator, one of <, <=, >, >=, = is expected at position 9"},
+		{"capacity>lots", "'filter': invalid 'lots' size at position 10"},
+		{"name=~(HERO", "'filter': invalid regexp: error parsing regexp: missing closing ): `(HERO` at position 7"},
+		{[]interface{}{"gopro", "name~"}, "'filter[1]': value is expected at position 6"},
+		{map[string]interface{}{"any": []interface{}{map[string]interface{}{"size": "1"}}}, "'filter.any[0].size': unknown filter"},
+		{map[string]interface{}{"any": "gopro"}, "'filter.any': list of filters is expected"},
+		{map[string]interface{}{"name": 1}, "'filter.name': non empty text is expected"},
+		{map[string]interface{}{"capacity": ">x"}, "'filter.capacity': invalid 'x' size at position 2"},
+		{map[string]interface{}{"not": map[string]interface{}{}}, "'filter.not': empty filter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			_, err := ParseDeviceFilter("filter", tt.node)
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	r := require.New(t)
+
+	for text, expected := range map[string]int64{
+		"512": 512, "512B": 512, "100K": 100 << 10, "1.5GB": 3 << 29, "2TiB": 2 << 40, " 10 m ": 10 << 20,
+	} {
+		size, err := ParseSize(text)
+		r.NoError(err, text)
+		r.Equal(expected, size, text)
+	}
+
+	for _, text := range []string{"", "GB", "-1K", "lots"} {
+		_, err := ParseSize(text)
+		r.Error(err, text)
+	}
+}
+
+func TestCheckFilterProperties(t *testing.T) {
+	fsSource := newFsSource([]string{filepath.Join(t.TempDir(), "*")})
+	parse := func(node interface{}) MtpDeviceFilter {
+		filter, err := ParseDeviceFilter("filter", node)
+		require.NoError(t, err)
+		return filter
+	}
+
+	assert.NoError(t, CheckFilterProperties(fsSource, parse("capacity>=32G")))
+	assert.NoError(t, CheckFilterProperties(fsSource, SdPhotosFilter))
+	assert.NoError(t, CheckFilterProperties(newFakeSource(), parse("serial=C3441325")))
+	assert.EqualError(t, CheckFilterProperties(fsSource, parse(map[string]interface{}{"any": []interface{}{"name~HERO", "!serial=C3441325"}})),
+		"'FS' devices source does not provide 'serial' device property, the filter cannot be checked")
+}
+
+func TestLoadFromAllWpd_UnsupportedFilterProperty(t *testing.T) {
+	filter, err := ParseFilterExpression("filter", "manufacturer~GoPro")
+	require.NoError(t, err)
+	profile := DeviceProfile{Name: "gopro", DeviceFilter: filter, DeviceDirs: []string{DCIM_DIR}}
+
+	mountDir := t.TempDir()
+	createFsFile(t, mountDir, "HERO8/DCIM/100GOPRO/GX010001.MP4", "video")
+
+	files, err := ScanAllWpd(newFsSource([]string{filepath.Join(mountDir, "*")}), profile, ImportOptions{})
+
+	assert.NoError(t, err, "devices are skipped, the import is not failed")
+	assert.Empty(t, files)
+}
+
+func accepts(filter MtpDeviceFilter, device *fakeDevice) bool {
+	source := newFakeSource(device)
+	info := source.GetDeviceInfo(0)
+	info.Label = device.name + " (" + device.description + ")"
+	return filter.accept(device, info)
+}
+
+func TestExplainFilter(t *testing.T) {
+	device := newFakeDevice("HERO8 Black", "GoPro").withFile("DCIM/100GOPRO/GX010001.MP4", "video", testModTime)
+	source := newFakeSource(device)
+	info := source.GetDeviceInfo(0)
+	info.Label = device.name + " (" + device.description + ")"
+
+	tests := []struct {
+		filter   MtpDeviceFilter
+		accepted bool
+		reason   string
+	}{
+		{GoProFilter, true, ""},
+		{SdPhotosFilter, false, "!any(label~HERO, label~GoPro, has:DCIM/100GOPRO)"},
+		{OrFilter{[]MtpDeviceFilter{camDeviceNameFilter, AndFilter{[]MtpDeviceFilter{dcmiFilderFilter, streamFolderFilter}}}}, false,
+			"any(label~CAM, has:PRIVATE/AVCHD/BDMV/STREAM)"},
+		{NotFilter{OrFilter{[]MtpDeviceFilter{camDeviceNameFilter, heroDeviceNameFilter}}}, false, "!any(label~HERO)"},
+		{NotFilter{NotFilter{camDeviceNameFilter}}, false, "!!label~CAM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.filter), func(t *testing.T) {
+			accepted, reason := ExplainFilter(tt.filter, device, info)
+
+			assert.Equal(t, tt.accepted, accepted)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package mtp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// checkFreeSpace makes sure volumes of temp and target dirs can hold all planned files plus safety margin.
+// Files copied by previous (interrupted) run are not counted
+func (downloader *MtpDownloader) checkFreeSpace(devicePlans []*devicePlan) error {
+	dirs := []string{downloader.resultDir}
+	if downloader.targetDir != "" {
+		dirs = append(dirs, downloader.targetDir)
+	}
+
+	requiredSizes := make([]int64, len(devicePlans))
+	for i, devicePlan := range devicePlans {
+		requiredSizes[i] = downloader.getRequiredSize(devicePlan)
+	}
+
+	for _, dir := range dirs {
+		free, _, err := GetDiskSpace(findExistingDir(dir))
+		if err != nil {
+			log.Warningf("Unable to check free space of '%v': %v", dir, err)
+			continue
+		}
+
+		missingTotal := checkRequiredSpace(dir, free, downloader.options.FreeSpaceMargin, devicePlans, requiredSizes)
+		if missingTotal > 0 {
+			return fmt.Errorf("not enough free space in '%v': %v missing (%v free, %v margin)", dir, SizeToLabel(missingTotal), SizeToLabel(free), SizeToLabel(downloader.options.FreeSpaceMargin))
+		}
+	}
+	return nil
+}
+
+// checkRequiredSpace reports devices which do not fit into free space. Devices are copied in order,
+// so the space missing for a device is the part of its files which exceeds the space left after previous devices
+func checkRequiredSpace(dir string, free int64, margin int64, devicePlans []*devicePlan, requiredSizes []int64) int64 {
+	available := free - margin
+	missingTotal := int64(0)
+	for i, devicePlan := range devicePlans {
+		required := requiredSizes[i]
+		missing := required - max(available, 0)
+		available -= required
+		if missing <= 0 {
+			continue
+		}
+
+		log.Errorf("%s requires %v in '%v', %v is missing", devicePlan.label, SizeToLabel(required), dir, SizeToLabel(missing))
+		missingTotal += missing
+	}
+	return missingTotal
+}
+
+func (downloader *MtpDownloader) getRequiredSize(devicePlan *devicePlan) int64 {
+	result := int64(0)
+	for _, wpdFile := range devicePlan.plan.files {
+		if wpdFile.wasCopied {
+			continue
+		}
+		if _, imported := downloader.findImported(devicePlan.key, wpdFile, wpdFile.relPath(devicePlan.plan.wpdRootDir)); !imported {
+			result += wpdFile.wpdObject.Size
+		}
+	}
+	return result
+}
+
+// findExistingDir returns the dir or the nearest existing parent dir
+func findExistingDir(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
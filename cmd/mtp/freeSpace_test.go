@@ -0,0 +1,65 @@
+package mtp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFromAllWpd_NotEnoughFreeSpace(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime)
+	source := newFakeSource(goPro)
+
+	_, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{FreeSpaceMargin: 1 << 62})
+
+	r.ErrorContains(err, "not enough free space")
+	r.Empty(goPro.deleted)
+	r.Zero(goPro.readCounts[fakeObjectId("DCIM/100GOPRO/GX010001.MP4")])
+}
+
+func TestCheckRequiredSpace(t *testing.T) {
+	r := require.New(t)
+
+	plans := []*devicePlan{{label: "FAKE#0"}, {label: "FAKE#1"}, {label: "FAKE#2"}}
+
+	r.Zero(checkRequiredSpace("dst", 100, 10, plans, []int64{30, 30, 30}))
+	r.Equal(int64(20), checkRequiredSpace("dst", 100, 10, plans, []int64{50, 30, 30}))
+	r.Equal(int64(80), checkRequiredSpace("dst", 100, 10, plans, []int64{100, 50, 20}))
+	r.Equal(int64(30), checkRequiredSpace("dst", 10, 20, plans[:1], []int64{30}))
+}
+
+func TestGetRequiredSize_SkipsImported(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video22", testModTime)
+
+	sut := MtpDownloader{source: newFakeSource(goPro), profile: GoProProfile}
+	sut.init(t.TempDir())
+	defer sut.close()
+	plans := sut.planMatchedDevices()
+	r.Len(plans, 1)
+	r.Equal(int64(13), sut.getRequiredSize(plans[0]))
+
+	localPath := filepath.Join(sut.resultDir, "0", "GX010001.MP4")
+	r.NoError(os.MkdirAll(filepath.Dir(localPath), 0755))
+	r.NoError(os.WriteFile(localPath, []byte("video1"), 0644))
+	sut.journal.record(JournalFile{Device: plans[0].key, RelPath: "GX010001.MP4", LocalPath: filepath.Join("0", "GX010001.MP4"), Size: 6, Status: journalVerified})
+
+	r.Equal(int64(7), sut.getRequiredSize(plans[0]))
+}
+
+func TestFindExistingDir(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+
+	r.Equal(dir, findExistingDir(dir))
+	r.Equal(dir, findExistingDir(filepath.Join(dir, "a", "b")))
+}
@@ -0,0 +1,144 @@
+package mtp
+
+import "sort"
+
+// DeviceReport describes connected device, its storages and matched device profiles
+type DeviceReport struct {
+	Info     DeviceInfo
+	Storages []StorageReport
+	Profiles []ProfileReport
+	// Error is set if the device could not be opened
+	Error error
+}
+
+// StorageReport describes device storage and its top-level folders
+type StorageReport struct {
+	Storage
+	// Index may be used to select the storage in import profiles
+	Index   int
+	Folders []string
+}
+
+// ProfileReport describes whether device profile matches the device and what would be imported
+type ProfileReport struct {
+	Name    string
+	Matched bool
+	// Reason describes failed sub-filters if the profile does not match
+	Reason       string
+	FilesCount   int
+	SkippedCount int
+	TotalSize    int64
+}
+
+// InspectDevices collects information about all devices of the source. Execution plans are built for matched profiles only
+func InspectDevices(source Source, profiles []DeviceProfile, options ImportOptions) ([]DeviceReport, error) {
+	if err := source.Init(); err != nil {
+		return nil, err
+	}
+	defer source.Destroy()
+
+	result := make([]DeviceReport, 0, source.GetDeviceCount())
+	for id := 0; id < source.GetDeviceCount(); id++ {
+		result = append(result, inspectDevice(source, id, profiles, options))
+	}
+	return result, nil
+}
+
+func inspectDevice(source Source, id int, profiles []DeviceProfile, options ImportOptions) DeviceReport {
+	report := DeviceReport{Info: DeviceInfo{Id: id, Label: buildDeviceLabel(source, id)}}
+
+	device, err := source.ChooseDevice(id)
+	if err != nil {
+		report.Error = err
+		return report
+	}
+	report.Info = source.GetDeviceInfo(id)
+	report.Info.Label = buildDeviceLabel(source, id)
+
+	storages, err := device.GetStorages()
+	if err != nil {
+		log.Warningf("Unable to read %s storages: %v", report.Info.Label, err)
+	}
+	for i, storage := range storages {
+		report.Storages = append(report.Storages, inspectStorage(device, i, storage))
+	}
+
+	deviceKey := buildDeviceKey(source, id)
+	for _, profile := range profiles {
+		profileReport := ProfileReport{Name: profile.Name}
+		if err := CheckFilterProperties(source, profile.DeviceFilter); err != nil {
+			profileReport.Reason = err.Error()
+			report.Profiles = append(report.Profiles, profileReport)
+			continue
+		}
+		profileReport.Matched, profileReport.Reason = ExplainFilter(profile.DeviceFilter, device, report.Info)
+		if profileReport.Matched {
+			for _, storage := range listDeviceStorages(device, report.Info.Label, profile.Storages) {
+				wpdRootDirs, wpdRootDirName := listDeviceDirs(device, storage.Id, profile.DeviceDirs, profile.Scan)
+				plan := BuildExecutionPlan(wpdRootDirs, wpdRootDirName, getPlanFilters(options, buildStorageKey(deviceKey, storage.dir))...)
+				profileReport.FilesCount += plan.GetFilesCount()
+				profileReport.SkippedCount += plan.GetSkippedCount()
+				profileReport.TotalSize += plan.GetTotalSize()
+			}
+		}
+		report.Profiles = append(report.Profiles, profileReport)
+	}
+	return report
+}
+
+func inspectStorage(device Device, index int, storage Storage) StorageReport {
+	report := StorageReport{Storage: storage, Index: index, Folders: make([]string, 0)}
+
+	objs, err := device.GetChildObjects(storage.Id)
+	if err != nil {
+		log.Warningf("Unable to read '%v' storage: %v", storage.Name, err)
+	}
+	for _, obj := range objs {
+		if obj.IsDir && !isIgnored(obj.Name) {
+			report.Folders = append(report.Folders, obj.Name)
+		}
+	}
+	sort.Strings(report.Folders)
+	return report
+}
+
+// WalkDeviceTree visits storages (depth 0) and all their objects. maxDepth limits the depth, 0 means unlimited
+func WalkDeviceTree(source Source, id int, maxDepth int, visit func(obj *Object, depth int)) error {
+	if err := source.Init(); err != nil {
+		return err
+	}
+	defer source.Destroy()
+
+	device, err := source.ChooseDevice(id)
+	if err != nil {
+		return err
+	}
+
+	storages, err := device.GetStorages()
+	if err != nil {
+		return err
+	}
+	for _, storage := range storages {
+		visit(&Object{Id: storage.Id, Name: storage.Name, Size: storage.Capacity, IsDir: true}, 0)
+		walkObjects(device, storage.Id, 1, maxDepth, visit)
+	}
+	return nil
+}
+
+func walkObjects(device Device, parentId string, depth int, maxDepth int, visit func(obj *Object, depth int)) {
+	if maxDepth > 0 && depth > maxDepth {
+		return
+	}
+
+	objs, err := device.GetChildObjects(parentId)
+	if err != nil {
+		log.Warningf("Unable to read '%v' children: %v", parentId, err)
+	}
+	sort.Slice(objs, func(i, j int) bool { return objs[i].Name < objs[j].Name })
+	for _, obj := range objs {
+		visit(obj, depth)
+		if obj.IsDir {
+			walkObjects(device, obj.Id, depth+1, maxDepth, visit)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package mtp
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectDevices(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withInfo("GoPro", "C3441325", 64<<30).
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video22", testModTime).
+		withDir("MISC").
+		withDir("System Volume Information")
+	broken := newFakeDevice("Broken", "")
+	broken.chooseError = errors.New("busy")
+	source := newFakeSource(goPro, broken)
+
+	reports, err := InspectDevices(source, []DeviceProfile{GoProProfile, SdPhotosProfile}, ImportOptions{})
+
+	r.NoError(err)
+	r.True(source.destroyed)
+	r.Len(reports, 2)
+
+	report := reports[0]
+	r.NoError(report.Error)
+	r.Equal("FAKE#0 - 'HERO8 Black (GoPro)'", report.Info.Label)
+	r.Equal("GoPro", report.Info.Manufacturer)
+	r.Len(report.Storages, 1)
+	r.Equal(int64(64<<30), report.Storages[0].Capacity)
+	r.Equal([]string{"DCIM", "MISC"}, report.Storages[0].Folders)
+
+	r.Len(report.Profiles, 2)
+	r.Equal(ProfileReport{Name: "gopro", Matched: true, FilesCount: 2, TotalSize: 13}, report.Profiles[0])
+	r.False(report.Profiles[1].Matched)
+	r.Equal("!any(label~HERO, label~GoPro, has:DCIM/100GOPRO)", report.Profiles[1].Reason)
+	r.Zero(report.Profiles[1].FilesCount)
+
+	r.EqualError(reports[1].Error, "busy")
+	r.Equal("FAKE#1 - 'Broken ()'", reports[1].Info.Label)
+}
+
+func TestInspectDevices_Ledger(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime)
+	ledger, err := OpenLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	r.NoError(err)
+	defer ledger.Close()
+	r.NoError(ledger.Add("HERO8 (GoPro)", filepath.FromSlash("/DCIM/100GOPRO/GX010001.MP4"), 6, testModTime.Unix()))
+
+	reports, err := InspectDevices(newFakeSource(goPro), []DeviceProfile{GoProProfile}, ImportOptions{Ledger: ledger})
+
+	r.NoError(err)
+	r.Equal(1, reports[0].Profiles[0].FilesCount)
+	r.Equal(1, reports[0].Profiles[0].SkippedCount)
+}
+
+func TestWalkDeviceTree(t *testing.T) {
+	r := require.New(t)
+
+	device := newFakeDevice("SD", "card").
+		withFile("DCIM/100NIKON/DSC_0002.NEF", "raw2", testModTime).
+		withFile("DCIM/100NIKON/DSC_0001.NEF", "raw1", testModTime).
+		withFile("MISC/info.txt", "info", testModTime)
+
+	visited := make([]string, 0)
+	err := WalkDeviceTree(newFakeSource(device), 0, 2, func(obj *Object, depth int) {
+		visited = append(visited, fmt.Sprintf("%v:%v", depth, obj.Name))
+	})
+
+	r.NoError(err)
+	r.Equal([]string{"0:Internal", "1:DCIM", "2:100NIKON", "1:MISC", "2:info.txt"}, visited)
+
+	visited = visited[:0]
+	r.NoError(WalkDeviceTree(newFakeSource(device), 0, 0, func(obj *Object, depth int) {
+		visited = append(visited, fmt.Sprintf("%v:%v", depth, obj.Name))
+	}))
+	r.Contains(visited, "3:DSC_0001.NEF")
+}
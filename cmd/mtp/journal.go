@@ -0,0 +1,170 @@
+package mtp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// JournalFileName is name of import journal inside import temp directory
+const JournalFileName = "media-tool.journal"
+
+const (
+	journalCopied   = "copied"
+	journalVerified = "verified"
+	journalFailed   = "failed"
+	journalPlaced   = "placed"
+	journalDeleted  = "deleted"
+)
+
+// JournalRun describes an import run
+type JournalRun struct {
+	Profile   string    `json:"profile"`
+	TargetDir string    `json:"targetDir"`
+	Started   time.Time `json:"started"`
+}
+
+// JournalFile is a state of single device file
+type JournalFile struct {
+	DeviceId  int    `json:"deviceId"`
+	Device    string `json:"device"`
+	ObjectId  string `json:"objectId"`
+	RelPath   string `json:"relPath"`
+	LocalPath string `json:"localPath"`
+	Size      int64  `json:"size"`
+	Status    string `json:"status"`
+}
+
+type journalRecord struct {
+	Run  *JournalRun  `json:"run,omitempty"`
+	File *JournalFile `json:"file,omitempty"`
+}
+
+// importJournal is append only log of import progress. Allows to resume interrupted imports
+type importJournal struct {
+	file    *os.File
+	run     *JournalRun
+	files   map[string]*JournalFile
+	devices map[string]int
+}
+
+func GetJournalPath(tempDir string) string {
+	return filepath.Join(tempDir, JournalFileName)
+}
+
+// ReadJournalRun reads import run details from journal of the temp directory
+func ReadJournalRun(tempDir string) (*JournalRun, error) {
+	journal, err := loadJournal(tempDir)
+	if err != nil {
+		return nil, err
+	}
+	return journal.run, nil
+}
+
+func createJournal(tempDir string, run JournalRun) (*importJournal, error) {
+	f, err := os.OpenFile(GetJournalPath(tempDir), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	journal := newJournal()
+	journal.file = f
+	journal.run = &run
+	return journal, journal.write(journalRecord{Run: &run})
+}
+
+func openJournal(tempDir string) (*importJournal, error) {
+	journal, err := loadJournal(tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	journal.file, err = os.OpenFile(GetJournalPath(tempDir), os.O_APPEND|os.O_WRONLY, 0644)
+	return journal, err
+}
+
+func loadJournal(tempDir string) (*importJournal, error) {
+	f, err := os.Open(GetJournalPath(tempDir))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	journal := newJournal()
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// the last line may be incomplete if the process was killed
+			log.Warningf("Unable to parse line %v of '%v' journal: %v", lineNumber, f.Name(), err)
+			continue
+		}
+		if record.Run != nil {
+			journal.run = record.Run
+		}
+		if record.File != nil {
+			journal.add(record.File)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if journal.run == nil {
+		return nil, fmt.Errorf("'%v' journal has no import run details", f.Name())
+	}
+	return journal, nil
+}
+
+func newJournal() *importJournal {
+	return &importJournal{
+		files:   make(map[string]*JournalFile),
+		devices: make(map[string]int),
+	}
+}
+
+func (journal *importJournal) record(file JournalFile) {
+	journal.add(&file)
+	if err := journal.write(journalRecord{File: &file}); err != nil {
+		log.Warningf("Unable to update import journal: %v", err)
+	}
+}
+
+func (journal *importJournal) add(file *JournalFile) {
+	journal.files[journalKey(file.Device, file.RelPath)] = file
+	journal.devices[file.Device] = file.DeviceId
+}
+
+func (journal *importJournal) write(record journalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := journal.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return journal.file.Sync()
+}
+
+func (journal *importJournal) find(device string, relPath string) *JournalFile {
+	return journal.files[journalKey(device, relPath)]
+}
+
+// findDeviceId returns id which was used for device temp directory
+func (journal *importJournal) findDeviceId(device string) (int, bool) {
+	deviceId, exists := journal.devices[device]
+	return deviceId, exists
+}
+
+func (journal *importJournal) close() {
+	if journal.file != nil {
+		journal.file.Close()
+	}
+}
+
+func journalKey(device string, relPath string) string {
+	return device + "|" + filepath.ToSlash(relPath)
+}
@@ -0,0 +1,87 @@
+package mtp
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportJournal_CreateAndLoad(t *testing.T) {
+	r := require.New(t)
+
+	tempDir := t.TempDir()
+	started := time.Date(2024, 5, 18, 10, 30, 15, 0, time.UTC)
+
+	sut, err := createJournal(tempDir, JournalRun{Profile: "gopro", TargetDir: "/video", Started: started})
+	r.NoError(err)
+	sut.record(JournalFile{DeviceId: 1, Device: "HERO8", ObjectId: "o1", RelPath: "GX010001.MP4", LocalPath: "1/GX010001.MP4", Size: 10, Status: journalCopied})
+	sut.record(JournalFile{DeviceId: 1, Device: "HERO8", ObjectId: "o1", RelPath: "GX010001.MP4", LocalPath: "1/GX010001.MP4", Size: 10, Status: journalVerified})
+	sut.close()
+
+	run, err := ReadJournalRun(tempDir)
+	r.NoError(err)
+	r.Equal("gopro", run.Profile)
+	r.Equal("/video", run.TargetDir)
+	r.True(started.Equal(run.Started))
+
+	loaded, err := loadJournal(tempDir)
+	r.NoError(err)
+	entry := loaded.find("HERO8", "GX010001.MP4")
+	r.NotNil(entry)
+	r.Equal(journalVerified, entry.Status)
+	r.Equal("1/GX010001.MP4", entry.LocalPath)
+	r.Nil(loaded.find("HERO7", "GX010001.MP4"))
+
+	deviceId, exists := loaded.findDeviceId("HERO8")
+	r.True(exists)
+	r.Equal(1, deviceId)
+}
+
+func TestImportJournal_CreateTwice(t *testing.T) {
+	r := require.New(t)
+
+	tempDir := t.TempDir()
+	sut, err := createJournal(tempDir, JournalRun{Profile: "gopro"})
+	r.NoError(err)
+	sut.close()
+
+	_, err = createJournal(tempDir, JournalRun{Profile: "gopro"})
+	r.Error(err)
+}
+
+func TestImportJournal_IncompleteLastLine(t *testing.T) {
+	r := require.New(t)
+
+	tempDir := t.TempDir()
+	content := `{"run":{"profile":"sdphotos","targetDir":"/photos"}}
+{"file":{"deviceId":0,"device":"SD","relPath":"DSC_0001.JPG","size":3,"status":"verified"}}
+{"file":{"deviceId":0,"device":"SD","relPath":"DSC_0002.JPG","si`
+	r.NoError(os.WriteFile(GetJournalPath(tempDir), []byte(content), 0644))
+
+	sut, err := openJournal(tempDir)
+	r.NoError(err)
+	defer sut.close()
+
+	r.Equal("sdphotos", sut.run.Profile)
+	r.NotNil(sut.find("SD", "DSC_0001.JPG"))
+	r.Nil(sut.find("SD", "DSC_0002.JPG"))
+}
+
+func TestImportJournal_NoRun(t *testing.T) {
+	r := require.New(t)
+
+	tempDir := t.TempDir()
+	r.NoError(os.WriteFile(GetJournalPath(tempDir), []byte(`{"file":{"device":"SD","relPath":"a.jpg"}}`), 0644))
+
+	_, err := ReadJournalRun(tempDir)
+	r.Error(err)
+}
+
+func TestImportJournal_Missing(t *testing.T) {
+	r := require.New(t)
+
+	_, err := ReadJournalRun(t.TempDir())
+	r.Error(err)
+}
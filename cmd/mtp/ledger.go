@@ -0,0 +1,156 @@
+package mtp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LedgerEntry is a device file which was imported earlier
+type LedgerEntry struct {
+	Device   string    `json:"device"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  int64     `json:"modTime"`
+	Imported time.Time `json:"imported"`
+}
+
+// ImportLedger is persistent list of imported device files. Allows to skip files which were imported by previous runs
+type ImportLedger struct {
+	filePath string
+	file     *os.File
+	entries  map[string]LedgerEntry
+}
+
+// OpenLedger loads ledger file (or creates a new one) and opens it for appending
+func OpenLedger(filePath string) (*ImportLedger, error) {
+	ledger := &ImportLedger{filePath: filePath, entries: make(map[string]LedgerEntry)}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, err
+	}
+	if err := ledger.load(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	ledger.file, err = os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	return ledger, err
+}
+
+func (ledger *ImportLedger) load() error {
+	f, err := os.Open(ledger.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		var entry LedgerEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Warningf("Unable to parse line %v of '%v' ledger: %v", lineNumber, ledger.filePath, err)
+			continue
+		}
+		ledger.entries[entry.key()] = entry
+	}
+	return scanner.Err()
+}
+
+func (ledger *ImportLedger) Contains(device string, filePath string, size int64, modTime int64) bool {
+	entry := LedgerEntry{Device: device, Path: filepath.ToSlash(filePath), Size: size, ModTime: modTime}
+	_, exists := ledger.entries[entry.key()]
+	return exists
+}
+
+func (ledger *ImportLedger) Add(device string, filePath string, size int64, modTime int64) error {
+	entry := LedgerEntry{Device: device, Path: filepath.ToSlash(filePath), Size: size, ModTime: modTime, Imported: time.Now()}
+	ledger.entries[entry.key()] = entry
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = ledger.file.Write(append(data, '\n'))
+	return err
+}
+
+func (ledger *ImportLedger) GetEntries() []LedgerEntry {
+	result := make([]LedgerEntry, 0, len(ledger.entries))
+	for _, entry := range ledger.entries {
+		result = append(result, entry)
+	}
+	return result
+}
+
+func (ledger *ImportLedger) GetEntriesCount() int {
+	return len(ledger.entries)
+}
+
+// Prune removes entries which match the predicate and rewrites ledger file. Returns number of removed entries
+func (ledger *ImportLedger) Prune(remove func(entry LedgerEntry) bool) (int, error) {
+	tmpFile := ledger.filePath + ".tmp"
+	f, err := os.Create(tmpFile)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	writer := bufio.NewWriter(f)
+	encoder := json.NewEncoder(writer)
+	for key, entry := range ledger.entries {
+		if remove(entry) {
+			delete(ledger.entries, key)
+			removed++
+			continue
+		}
+		if err := encoder.Encode(entry); err != nil {
+			f.Close()
+			return 0, err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+
+	ledger.file.Close()
+	if err := os.Rename(tmpFile, ledger.filePath); err != nil {
+		return 0, err
+	}
+	ledger.file, err = os.OpenFile(ledger.filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	return removed, err
+}
+
+func (ledger *ImportLedger) Close() {
+	if ledger.file != nil {
+		ledger.file.Close()
+	}
+}
+
+// ledgerFilter excludes device files which were imported earlier
+type ledgerFilter struct {
+	ledger *ImportLedger
+	device string
+}
+
+func (filter ledgerFilter) accept(file *wpdFile, wpdRootDir string) bool {
+	if filter.ledger.Contains(filter.device, file.filePath, file.wpdObject.Size, file.wpdObject.ModTime) {
+		log.Debugf("Skipping '%v', it was imported earlier", file.filePath)
+		return false
+	}
+	return true
+}
+
+func (entry LedgerEntry) key() string {
+	return entry.Device + "|" + entry.Path + "|" + fmt.Sprint(entry.Size) + "|" + fmt.Sprint(entry.ModTime)
+}
@@ -0,0 +1,75 @@
+package mtp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportLedger_AddAndReload(t *testing.T) {
+	r := require.New(t)
+
+	ledgerFile := filepath.Join(t.TempDir(), "conf", "ledger.jsonl")
+
+	sut, err := OpenLedger(ledgerFile)
+	r.NoError(err)
+	r.Equal(0, sut.GetEntriesCount())
+	r.NoError(sut.Add("CAM_SD (Panasonic)", filepath.FromSlash("/PRIVATE/00001.MTS"), 100, 1000))
+	r.True(sut.Contains("CAM_SD (Panasonic)", filepath.FromSlash("/PRIVATE/00001.MTS"), 100, 1000))
+	sut.Close()
+
+	sut, err = OpenLedger(ledgerFile)
+	r.NoError(err)
+	defer sut.Close()
+
+	r.Equal(1, sut.GetEntriesCount())
+	r.True(sut.Contains("CAM_SD (Panasonic)", filepath.FromSlash("/PRIVATE/00001.MTS"), 100, 1000))
+	r.False(sut.Contains("CAM_SD (Panasonic)", filepath.FromSlash("/PRIVATE/00001.MTS"), 101, 1000))
+	r.False(sut.Contains("CAM_SD (Panasonic)", filepath.FromSlash("/PRIVATE/00001.MTS"), 100, 1001))
+	r.False(sut.Contains("CAM_SD (Other)", filepath.FromSlash("/PRIVATE/00001.MTS"), 100, 1000))
+	r.False(sut.Contains("CAM_SD (Panasonic)", filepath.FromSlash("/PRIVATE/00002.MTS"), 100, 1000))
+}
+
+func TestImportLedger_BrokenLine(t *testing.T) {
+	r := require.New(t)
+
+	ledgerFile := filepath.Join(t.TempDir(), "ledger.jsonl")
+	content := `{"device":"SD","path":"/DCIM/a.jpg","size":1,"modTime":2}
+{"device":"SD","pa`
+	r.NoError(os.WriteFile(ledgerFile, []byte(content), 0644))
+
+	sut, err := OpenLedger(ledgerFile)
+	r.NoError(err)
+	defer sut.Close()
+
+	r.Equal(1, sut.GetEntriesCount())
+	r.True(sut.Contains("SD", "/DCIM/a.jpg", 1, 2))
+}
+
+func TestImportLedger_Prune(t *testing.T) {
+	r := require.New(t)
+
+	ledgerFile := filepath.Join(t.TempDir(), "ledger.jsonl")
+	sut, err := OpenLedger(ledgerFile)
+	r.NoError(err)
+	r.NoError(sut.Add("SD", "/DCIM/a.jpg", 1, 2))
+	r.NoError(sut.Add("SD", "/DCIM/b.jpg", 1, 2))
+	r.NoError(sut.Add("HERO8", "/DCIM/c.mp4", 1, 2))
+
+	removed, err := sut.Prune(func(entry LedgerEntry) bool { return entry.Device == "SD" })
+	r.NoError(err)
+	r.Equal(2, removed)
+	r.NoError(sut.Add("HERO8", "/DCIM/d.mp4", 1, 2))
+	sut.Close()
+
+	sut, err = OpenLedger(ledgerFile)
+	r.NoError(err)
+	defer sut.Close()
+
+	r.Equal(2, sut.GetEntriesCount())
+	r.False(sut.Contains("SD", "/DCIM/a.jpg", 1, 2))
+	r.True(sut.Contains("HERO8", "/DCIM/c.mp4", 1, 2))
+	r.True(sut.Contains("HERO8", "/DCIM/d.mp4", 1, 2))
+}
@@ -5,10 +5,10 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/tobwithu/gowpd"
-
 	"github.com/cheggaaa/pb/v3"
 )
 
@@ -16,39 +16,79 @@ var CopyProgressTemplate pb.ProgressBarTemplate = `{{with string . "prefix"}}{{.
 var DeletingProgressTemplate pb.ProgressBarTemplate = `{{with string . "prefix"}}{{.}} {{end}}{{counters . "%s/%s" "%s/?"}} {{bar . }} {{percent . "%.0f%%" "?"}} {{rtime . "ETA %s"}}{{with string . "suffix"}} {{.}}{{end}}`
 
 type MtpDownloader struct {
-	resultDir    string
-	tmpDir       string
-	error        error
-	dryRun       bool
-	deviceFilter MtpDeviceFilter
+	source    Source
+	resultDir string
+	targetDir string
+	tmpDir    string
+	error     error
+	options   ImportOptions
+	profile   DeviceProfile
+	journal   *importJournal
+	keptFiles []string
 
 	currentDeviceId    int
 	currentDeviceLabel string
-	currentDevice      *gowpd.Device
+	currentDeviceInfo  DeviceInfo
+	currentDeviceKey   string
+	currentDevice      Device
+	currentStorage     deviceStorage
 }
 
-func LoadSdPhotos(targetDir string, dryRun bool) (string, error) {
-	return loadFromAllWpd(SdPhotosFilter, DCIM_DIR, targetDir, dryRun)
+// devicePlan is an execution plan of a single storage of matched device
+type devicePlan struct {
+	id      int
+	label   string
+	info    DeviceInfo
+	key     string
+	device  Device
+	storage deviceStorage
+	plan    *ExecutionPlan
 }
 
-func LoadGoProVideos(targetDir string, dryRun bool) (string, error) {
-	return loadFromAllWpd(GoProFilter, GOPRO_DIR, targetDir, dryRun)
-}
+// LoadFromAllWpd copies files from all devices matched by the profile to a new temp directory inside the targetDir
+func LoadFromAllWpd(source Source, profile DeviceProfile, targetDir string, options ImportOptions) (string, error) {
+	result := MtpDownloader{source: source, options: options, profile: profile}
+	result.init(targetDir)
+	defer result.close()
+
+	result.loadFromMatchedDevices()
+	result.printSummary()
 
-func LoadCamVideos(targetDir string, dryRun bool) (string, error) {
-	return loadFromAllWpd(CamFilter, CAM_FILES_DIR, targetDir, dryRun)
+	return result.GetResultDir(), result.GetError()
 }
 
-func loadFromAllWpd(deviceFilter MtpDeviceFilter, deviceDir string, targetDir string, dryRun bool) (string, error) {
-	result := MtpDownloader{dryRun: dryRun, deviceFilter: deviceFilter}
-	result.init(targetDir)
+// ResumeFromAllWpd continues interrupted import into existing temp directory. Files which were copied and verified are skipped
+func ResumeFromAllWpd(source Source, profile DeviceProfile, tempDir string, options ImportOptions) (string, error) {
+	result := MtpDownloader{source: source, options: options, profile: profile}
+	result.resume(tempDir)
 	defer result.close()
 
-	result.loadFromMatchedDevices(deviceDir)
+	result.loadFromMatchedDevices()
+	result.printSummary()
 
 	return result.GetResultDir(), result.GetError()
 }
 
+// ScanAllWpd lists files of all devices matched by the profile without copying them. Nothing is changed on devices or disk
+func ScanAllWpd(source Source, profile DeviceProfile, options ImportOptions) ([]*PlannedFile, error) {
+	result := MtpDownloader{source: source, options: options, profile: profile}
+	result.error = source.Init()
+	defer result.close()
+
+	files := make([]*PlannedFile, 0)
+	if result.HasError() {
+		return files, result.GetError()
+	}
+
+	for _, devicePlan := range result.planMatchedDevices() {
+		log.Infof("%v file(s) (%v) would be downloaded from %s", devicePlan.plan.GetFilesCount(), devicePlan.plan.GetTotalSizeString(), devicePlan.label)
+		for _, wpdFile := range devicePlan.plan.files {
+			files = append(files, newPlannedFile(devicePlan.key, wpdFile))
+		}
+	}
+	return files, nil
+}
+
 func (downloader *MtpDownloader) HasError() bool {
 	return downloader.GetError() != nil
 }
@@ -65,85 +105,238 @@ func (downloader *MtpDownloader) GetResultDir() string {
 }
 
 func (downloader *MtpDownloader) init(targetDir string) {
-	downloader.error = gowpd.Init()
+	downloader.error = downloader.source.Init()
 
 	if !downloader.HasError() {
+		downloader.targetDir = targetDir
 		downloader.resultDir = downloader.generateTmpDir(targetDir)
-		downloader.error = os.MkdirAll(downloader.resultDir, os.ModeDir)
+		downloader.error = os.MkdirAll(downloader.resultDir, 0755)
+	}
+
+	if !downloader.HasError() {
+		run := JournalRun{Profile: downloader.profile.Name, TargetDir: targetDir, Started: time.Now()}
+		downloader.journal, downloader.error = createJournal(downloader.resultDir, run)
+	}
+}
+
+func (downloader *MtpDownloader) resume(tempDir string) {
+	downloader.error = downloader.source.Init()
+
+	if !downloader.HasError() {
+		downloader.resultDir = tempDir
+		downloader.journal, downloader.error = openJournal(tempDir)
+	}
+
+	if !downloader.HasError() {
+		downloader.targetDir = downloader.journal.run.TargetDir
 	}
 }
 
 func (downloader *MtpDownloader) close() {
-	gowpd.Destroy()
+	if downloader.journal != nil {
+		downloader.journal.close()
+	}
+	downloader.source.Destroy()
 }
 
-func (downloader *MtpDownloader) loadFromMatchedDevices(deviceDir string) {
+func (downloader *MtpDownloader) loadFromMatchedDevices() {
+	if downloader.HasError() {
+		return
+	}
+	devicePlans := downloader.planMatchedDevices()
+
+	downloader.error = downloader.checkFreeSpace(devicePlans)
 	if downloader.HasError() {
 		return
 	}
 
-	mtpDeviceCount := gowpd.GetDeviceCount()
+	for _, devicePlan := range devicePlans {
+		downloader.selectDevice(devicePlan)
+		downloader.copyContentToTempDir(devicePlan.plan)
+	}
+
+	downloader.finishImport(devicePlans)
+}
+
+// planMatchedDevices builds execution plans of all storages of devices matched by the profile. Storages without files are skipped
+func (downloader *MtpDownloader) planMatchedDevices() []*devicePlan {
+	result := make([]*devicePlan, 0)
+	// devices are skipped if the source does not provide device properties checked by the filter
+	filterErr := CheckFilterProperties(downloader.source, downloader.profile.DeviceFilter)
+
+	mtpDeviceCount := downloader.source.GetDeviceCount()
 	for i := 0; i < mtpDeviceCount; i++ {
 		downloader.initCurrentDevice(i)
 		if downloader.HasError() {
 			log.Warningf("Unable to read %s!", downloader.currentDeviceLabel)
+			downloader.error = nil
 			continue
 		}
 
-		if !downloader.deviceFilter.accept(downloader.currentDeviceId, downloader.currentDevice, downloader.currentDeviceLabel) {
+		if filterErr != nil {
+			log.Warningf("Skipping %s device: %v", downloader.currentDeviceLabel, filterErr)
+			continue
+		}
+
+		if !downloader.profile.DeviceFilter.accept(downloader.currentDevice, downloader.currentDeviceInfo) {
 			log.Infof("Skipping %s device", downloader.currentDeviceLabel)
 			continue
 		}
 
-		downloader.copyContentToTempDir(deviceDir)
+		deviceLabel, deviceKey := downloader.currentDeviceLabel, downloader.currentDeviceKey
+		for _, storage := range listDeviceStorages(downloader.currentDevice, deviceLabel, downloader.profile.Storages) {
+			downloader.selectStorage(storage, deviceLabel, deviceKey)
+
+			executionPlan := downloader.buildPlan(downloader.profile.DeviceDirs)
+			if executionPlan == nil || executionPlan.IsEmpty() {
+				continue
+			}
+
+			result = append(result, downloader.newDevicePlan(executionPlan))
+		}
+	}
+	return result
+}
+
+func (downloader *MtpDownloader) newDevicePlan(executionPlan *ExecutionPlan) *devicePlan {
+	return &devicePlan{
+		id:      downloader.currentDeviceId,
+		label:   downloader.currentDeviceLabel,
+		info:    downloader.currentDeviceInfo,
+		key:     downloader.currentDeviceKey,
+		device:  downloader.currentDevice,
+		storage: downloader.currentStorage,
+		plan:    executionPlan,
 	}
 }
 
-func (downloader *MtpDownloader) copyContentToTempDir(deviceDir string) {
+func (downloader *MtpDownloader) buildPlan(deviceDirs []string) *ExecutionPlan {
+	wpdRootDirs, wpdRootDirName := listDeviceDirs(downloader.currentDevice, downloader.currentStorage.Id, deviceDirs, downloader.profile.Scan)
+	if len(wpdRootDirs) == 0 {
+		return nil
+	}
+
+	log.Infof("Scanning %s...", downloader.currentDeviceLabel)
+
+	executionPlan := BuildExecutionPlan(wpdRootDirs, wpdRootDirName, getPlanFilters(downloader.options, downloader.currentDeviceKey)...)
+	if executionPlan.GetSkippedCount() > 0 {
+		log.Infof("%v file(s) were skipped as filtered out or already imported", executionPlan.GetSkippedCount())
+	}
+	if executionPlan.GetJunkCount() > 0 {
+		log.Infof("%v junk file(s) will be deleted without copying", executionPlan.GetJunkCount())
+	}
+	return executionPlan
+}
+
+func (downloader *MtpDownloader) copyContentToTempDir(executionPlan *ExecutionPlan) {
 	downloader.prepareTempDir()
 	if downloader.HasError() {
 		log.Warningf("Unable to create '%v' temp directory. %s was skipped", downloader.tmpDir, downloader.currentDeviceLabel)
 		return
 	}
 
-	wpdRootDirName := gowpd.PathSeparator + deviceDir
-	wpdRootDirs := listWpdDir(downloader.currentDevice, wpdRootDirName)
+	log.Infof("%v file(s) (%v) will be downloaded to '%v' temp directory", executionPlan.GetFilesCount(), executionPlan.GetTotalSizeString(), downloader.tmpDir)
+
+	downloader.copyToTmpDir(executionPlan)
+
+	downloader.verifyTmpFiles(executionPlan)
+
+	downloader.recordPlannedFiles(executionPlan)
+}
+
+// finishImport places downloaded files to the target dir. Device files are deleted (and recorded into the ledger)
+// only when their placement is confirmed, other files are kept on devices and reported
+func (downloader *MtpDownloader) finishImport(devicePlans []*devicePlan) {
+	if downloader.options.Place != nil {
+		downloader.options.Place(downloader.resultDir)
+	}
 
-	if len(wpdRootDirs) > 0 {
-		log.Infof("Scanning %s...", downloader.currentDeviceLabel)
+	for _, devicePlan := range devicePlans {
+		downloader.selectDevice(devicePlan)
+		downloader.checkPlacedFiles(devicePlan.plan)
+		downloader.updateLedger(devicePlan.plan)
+		downloader.removeSrcFiles(devicePlan.plan)
+	}
+}
 
-		executionPlan := BuildExecutionPlan(wpdRootDirs, wpdRootDirName)
-		if executionPlan.IsEmpty() {
-			return
+// checkPlacedFiles marks copied files which are not in the temp dir anymore as placed
+func (downloader *MtpDownloader) checkPlacedFiles(executionPlan *ExecutionPlan) {
+	fileIterator := executionPlan.GetFileInterator()
+	for fileIterator.Current() != nil {
+		wpdFile := fileIterator.Current()
+
+		if wpdFile.wasCopied {
+			if _, err := os.Stat(wpdFile.localPath); downloader.options.Place != nil && err == nil {
+				log.Debugf("'%v' was not moved from the temp dir", wpdFile.localPath)
+				downloader.keptFiles = append(downloader.keptFiles, fmt.Sprintf("%v: '%v' - was not moved to the target dir", downloader.currentDeviceLabel, wpdFile.filePath))
+			} else {
+				wpdFile.wasPlaced = true
+				downloader.recordFile(wpdFile, executionPlan, journalPlaced)
+			}
 		}
-		log.Infof("%v file(s) (%v) will be downloaded to '%v' temp directory", executionPlan.GetFilesCount(), executionPlan.GetTotalSizeString(), downloader.tmpDir)
 
-		downloader.copyToTmpDir(executionPlan)
+		fileIterator.Next()
+	}
+}
+
+func getPlanFilters(options ImportOptions, deviceKey string) []PlanFilter {
+	result := make([]PlanFilter, 0)
+	if !options.Files.IsEmpty() {
+		result = append(result, options.Files)
+	}
+	if options.Ledger != nil && !options.Reimport {
+		result = append(result, ledgerFilter{ledger: options.Ledger, device: deviceKey})
+	}
+	return result
+}
+
+func (downloader *MtpDownloader) updateLedger(executionPlan *ExecutionPlan) {
+	ledger := downloader.options.Ledger
+	if ledger == nil || downloader.options.Plan != nil {
+		return
+	}
+
+	fileIterator := executionPlan.GetFileInterator()
+	for fileIterator.Current() != nil {
+		wpdFile := fileIterator.Current()
+
+		if wpdFile.wasPlaced {
+			err := ledger.Add(downloader.currentDeviceKey, wpdFile.filePath, wpdFile.wpdObject.Size, wpdFile.wpdObject.ModTime)
+			if err != nil {
+				log.Warningf("Unable to update import ledger: %v", err)
+			}
+		}
 
-		downloader.removeSrcFiles(executionPlan)
+		fileIterator.Next()
 	}
 }
 
 func (downloader *MtpDownloader) removeSrcFiles(executionPlan *ExecutionPlan) {
-	if downloader.dryRun {
-		log.Infof("Source files will not be removed ('DryRun' flag is true)")
+	if downloader.options.KeepSource {
+		log.Infof("Source files will not be removed ('keep source' option is set)")
+		return
+	}
+	if downloader.profile.KeepSource {
+		log.Infof("Source files will not be removed ('%v' profile keeps source files)", downloader.profile.Name)
 		return
 	}
 
 	log.Infof("Deleting origin files from %v", downloader.currentDeviceLabel)
-	progressBar := DeletingProgressTemplate.Start(executionPlan.GetFilesCount())
+	progressBar := DeletingProgressTemplate.Start(executionPlan.GetFilesCount() + executionPlan.GetJunkCount())
 	defer progressBar.Finish()
 
 	fileIterator := executionPlan.GetFileInterator()
 	for fileIterator.Current() != nil {
 		wpdFile := fileIterator.Current()
 
-		progressBar.Set("prefix", fmt.Sprintf("(%v/%v) '%v'", fileIterator.GetFilesCount(), fileIterator.GetFilesTotal(), wpdFile.relPath(executionPlan.wpdRootDir)))
+		progressBar.Set("prefix", downloader.progressPrefix(fileIterator, wpdFile.relPath(executionPlan.wpdRootDir)))
 
-		if wpdFile.wasCopied {
+		if wpdFile.wasPlaced {
 			err := wpdFile.deleteFile()
 			if err != nil {
 				log.Infof("Deleting of '%v' - failed: %v", wpdFile.filePath, err)
+			} else {
+				downloader.recordFile(wpdFile, executionPlan, journalDeleted)
 			}
 		} else {
 			log.Infof("Deleting of '%v' - skipped", wpdFile.filePath)
@@ -152,41 +345,114 @@ func (downloader *MtpDownloader) removeSrcFiles(executionPlan *ExecutionPlan) {
 
 		fileIterator.Next()
 	}
+
+	for _, wpdFile := range executionPlan.junkFiles {
+		progressBar.Set("prefix", fmt.Sprintf("(junk) '%v'", wpdFile.relPath(executionPlan.wpdRootDir)))
+
+		if err := wpdFile.deleteFile(); err != nil {
+			log.Infof("Deleting of '%v' junk file - failed: %v", wpdFile.filePath, err)
+		} else {
+			downloader.recordFile(wpdFile, executionPlan, journalDeleted)
+		}
+		progressBar.Increment()
+	}
 }
 
 func (downloader *MtpDownloader) prepareTempDir() {
-	downloader.tmpDir = path.Join(downloader.resultDir, fmt.Sprint(downloader.currentDeviceId))
-	downloader.error = os.MkdirAll(downloader.tmpDir, os.ModeDir)
+	tmpDirId := downloader.currentDeviceId
+	if journalDeviceId, exists := downloader.journal.findDeviceId(downloader.currentDeviceKey); exists {
+		tmpDirId = journalDeviceId
+	}
+	downloader.tmpDir = path.Join(downloader.resultDir, fmt.Sprint(tmpDirId))
+	downloader.error = os.MkdirAll(downloader.tmpDir, 0755)
 }
 
 func (downloader *MtpDownloader) initCurrentDevice(i int) {
 	downloader.currentDeviceId = i
-	downloader.currentDeviceLabel = downloader.buildMtpDeviceLabel()
+	downloader.currentDeviceLabel = buildDeviceLabel(downloader.source, i)
+	downloader.currentDeviceKey = buildDeviceKey(downloader.source, i)
 	log.Infof("Found %s device", downloader.currentDeviceLabel)
 
-	downloader.currentDevice, downloader.error = gowpd.ChooseDevice(downloader.currentDeviceId)
+	downloader.currentDevice, downloader.error = downloader.source.ChooseDevice(downloader.currentDeviceId)
+	if downloader.error == nil {
+		downloader.currentDeviceInfo = downloader.source.GetDeviceInfo(i)
+		downloader.currentDeviceInfo.Label = downloader.currentDeviceLabel
+	}
+}
+
+func (downloader *MtpDownloader) selectDevice(devicePlan *devicePlan) {
+	downloader.currentDeviceId = devicePlan.id
+	downloader.currentDeviceLabel = devicePlan.label
+	downloader.currentDeviceInfo = devicePlan.info
+	downloader.currentDeviceKey = devicePlan.key
+	downloader.currentDevice = devicePlan.device
+	downloader.currentStorage = devicePlan.storage
 }
 
-func (downloader *MtpDownloader) buildMtpDeviceLabel() string {
-	return fmt.Sprintf("MTP#%v - '%v (%v)'", downloader.currentDeviceId, gowpd.GetDeviceName(downloader.currentDeviceId), gowpd.GetDeviceDescription(downloader.currentDeviceId))
+// selectStorage makes the storage current. Label and key of the current device get the storage name if the device has several storages
+func (downloader *MtpDownloader) selectStorage(storage deviceStorage, deviceLabel string, deviceKey string) {
+	downloader.currentStorage = storage
+	downloader.currentDeviceLabel = buildStorageLabel(deviceLabel, storage.dir)
+	downloader.currentDeviceKey = buildStorageKey(deviceKey, storage.dir)
 }
 
+// progressPrefix returns progress bar prefix with storage name (for devices with several storages) and file counters
+func (downloader *MtpDownloader) progressPrefix(fileIterator *ExecutionFileIterator, relPath string) string {
+	result := fmt.Sprintf("(%v/%v) '%v'", fileIterator.GetFilesCount(), fileIterator.GetFilesTotal(), relPath)
+	if downloader.currentStorage.dir != "" {
+		result = fmt.Sprintf("[%v] %v", downloader.currentStorage.dir, result)
+	}
+	return result
+}
+
+func buildDeviceLabel(source Source, id int) string {
+	return fmt.Sprintf("%v#%v - '%v (%v)'", source.GetName(), id, source.GetDeviceName(id), source.GetDeviceDescription(id))
+}
+
+// buildDeviceKey returns device identifier used by import journal and ledger
+func buildDeviceKey(source Source, id int) string {
+	return fmt.Sprintf("%v (%v)", source.GetDeviceName(id), source.GetDeviceDescription(id))
+}
+
+// generateTmpDir returns a new timestamp named dir. Existing dirs (e.g. the temp dir of an import plan made
+// in the same second) are not reused
 func (downloader *MtpDownloader) generateTmpDir(targetDir string) string {
-	return filepath.Join(targetDir, time.Now().Format("20060102_150405"))
+	name := time.Now().Format("20060102_150405")
+	result := filepath.Join(targetDir, name)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(result); os.IsNotExist(err) {
+			return result
+		}
+		result = filepath.Join(targetDir, fmt.Sprintf("%v_%v", name, i))
+	}
 }
 
-func listWpdDir(dev *gowpd.Device, dir string) []*wpdFile {
-	obj := dev.FindObject(dir)
+// listDeviceDirs lists device dirs content of the storage. Several device dirs are copied with paths relative to the storage root
+func listDeviceDirs(dev Device, storageId string, deviceDirs []string, rules ScanRules) ([]*wpdFile, string) {
+	wpdRootDirName := PathSeparator
+	if len(deviceDirs) == 1 {
+		wpdRootDirName = PathSeparator + deviceDirs[0]
+	}
+	wpdRootDirs := make([]*wpdFile, 0)
+	for _, deviceDir := range deviceDirs {
+		wpdRootDirs = append(wpdRootDirs, listWpdDir(dev, storageId, PathSeparator+deviceDir, rules)...)
+	}
+	return wpdRootDirs, wpdRootDirName
+}
+
+func listWpdDir(dev Device, storageId string, dir string, rules ScanRules) []*wpdFile {
+	obj := findStorageObject(dev, storageId, dir)
 	if obj == nil {
 		log.Debugf("%v was not found.", dir)
 		return make([]*wpdFile, 0)
 	}
 
-	wpdFile := newWpdFile(filepath.Dir(dir), dev, obj)
+	wpdFile := newWpdFile(filepath.Dir(dir), dev, obj, rules)
 	return wpdFile.chidren
 }
 
-func sizeToLabel(size int64) string {
+// SizeToLabel formats size in bytes as a human readable text, e.g. "1.5 GiB"
+func SizeToLabel(size int64) string {
 	const unit = 1024
 	if size < unit {
 		return fmt.Sprintf("%d B", size)
@@ -200,6 +466,28 @@ func sizeToLabel(size int64) string {
 		float64(size)/float64(div), "KMGTPE"[exp])
 }
 
+// ParseSize parses size with optional binary unit suffix, e.g. '512', '100K', '1.5GB' or '2TiB'
+func ParseSize(value string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(value))
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "B"), "I")
+
+	multiplier := int64(1)
+	if text != "" {
+		if exp := strings.IndexByte("KMGTPE", text[len(text)-1]); exp >= 0 {
+			for i := 0; i <= exp; i++ {
+				multiplier *= 1024
+			}
+			text = text[:len(text)-1]
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid '%s' size", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
 func (downloader *MtpDownloader) copyToTmpDir(executionPlan *ExecutionPlan) {
 	progressBar := CopyProgressTemplate.Start64(executionPlan.GetTotalSize())
 	defer progressBar.Finish()
@@ -209,23 +497,153 @@ func (downloader *MtpDownloader) copyToTmpDir(executionPlan *ExecutionPlan) {
 		wpdFile := fileIterator.Current()
 
 		relWpdFilePath := wpdFile.relPath(executionPlan.wpdRootDir)
-		targetFile := filepath.Join(downloader.tmpDir, relWpdFilePath)
+		targetFile := filepath.Join(downloader.tmpDir, downloader.currentStorage.dir, relWpdFilePath)
+
+		if wpdFile.wasCopied || downloader.wasImported(wpdFile, relWpdFilePath) {
+			log.Debugf("Copy of '%v' - skipped, it was copied by previous run", wpdFile.filePath)
+			progressBar.Add64(wpdFile.wpdObject.Size)
+		} else {
+			log.Debugf("Copying from '%v' to %v... ", wpdFile.filePath, targetFile)
+			progressBar.Set("prefix", downloader.progressPrefix(fileIterator, relWpdFilePath))
+
+			targetDir := filepath.Dir(targetFile)
+			os.MkdirAll(targetDir, 0755)
+
+			copyCount, error := downloader.copyWithRetries(wpdFile, targetFile, progressBar)
+
+			if error != nil {
+				log.Warningf("Copy of '%v' - failed - %v", wpdFile.filePath, error)
+				downloader.keptFiles = append(downloader.keptFiles, fmt.Sprintf("%v: '%v' - %v", downloader.currentDeviceLabel, wpdFile.filePath, error))
+				downloader.recordFile(wpdFile, executionPlan, journalFailed)
+			} else {
+				log.Debugf("Copy of '%v' - done ('%v')", wpdFile.filePath, SizeToLabel(copyCount))
+				wpdFile.wasCopied = true
+				wpdFile.localPath = targetFile
+				downloader.recordFile(wpdFile, executionPlan, journalCopied)
+			}
+		}
 
-		log.Debugf("Copying from '%v' to %v... ", wpdFile.filePath, targetFile)
-		progressBar.Set("prefix", fmt.Sprintf("(%v/%v) '%v'", fileIterator.GetFilesCount(), fileIterator.GetFilesTotal(), relWpdFilePath))
+		fileIterator.Next()
+	}
+}
 
-		targetDir := filepath.Dir(targetFile)
-		os.MkdirAll(targetDir, os.ModeDir)
+// copyWithRetries copies device file. Device read errors are retried with exponential backoff
+func (downloader *MtpDownloader) copyWithRetries(wpdFile *wpdFile, targetFile string, progressBar *pb.ProgressBar) (int64, error) {
+	attempts := max(downloader.options.CopyAttempts, 1)
+	delay := downloader.options.CopyRetryDelay
 
-		copyCount, error := wpdFile.copyTo(targetFile, progressBar)
+	for attempt := 1; ; attempt++ {
+		copyCount, err := wpdFile.copyTo(targetFile, progressBar)
+		if err == nil {
+			return copyCount, nil
+		}
+		progressBar.Add64(-copyCount)
 
-		if error != nil {
-			log.Infof("Copy of '%v' - filed - %v", wpdFile.filePath, error)
-		} else {
-			log.Debugf("Copy of '%v' - done ('%v')", wpdFile.filePath, sizeToLabel(copyCount))
-			wpdFile.wasCopied = true
+		if attempt >= attempts || !isDeviceReadError(err) {
+			return 0, err
+		}
+		log.Infof("Copy of '%v' - attempt %v of %v failed - %v. Retrying in %v", wpdFile.filePath, attempt, attempts, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// wasImported checks journal of resumed import for already copied and verified file
+func (downloader *MtpDownloader) wasImported(wpdFile *wpdFile, relPath string) bool {
+	localPath, imported := downloader.findImported(downloader.currentDeviceKey, wpdFile, relPath)
+	if !imported {
+		return false
+	}
+
+	wpdFile.wasCopied = true
+	wpdFile.wasVerified = true
+	wpdFile.localPath = localPath
+	return true
+}
+
+// findImported returns local path of the file if it was copied and verified (or already placed) by previous run
+func (downloader *MtpDownloader) findImported(deviceKey string, wpdFile *wpdFile, relPath string) (string, bool) {
+	entry := downloader.journal.find(deviceKey, relPath)
+	if entry == nil || entry.Size != wpdFile.wpdObject.Size {
+		return "", false
+	}
+
+	localPath := filepath.Join(downloader.resultDir, entry.LocalPath)
+	if entry.Status == journalPlaced {
+		return localPath, true
+	}
+	if entry.Status != journalVerified {
+		return "", false
+	}
+	if stat, err := os.Stat(localPath); err != nil || stat.Size() != entry.Size {
+		return "", false
+	}
+	return localPath, true
+}
+
+func (downloader *MtpDownloader) recordFile(wpdFile *wpdFile, executionPlan *ExecutionPlan, status string) {
+	localPath := ""
+	if wpdFile.localPath != "" {
+		localPath, _ = filepath.Rel(downloader.resultDir, wpdFile.localPath)
+	}
+
+	downloader.journal.record(JournalFile{
+		DeviceId:  downloader.currentDeviceId,
+		Device:    downloader.currentDeviceKey,
+		ObjectId:  wpdFile.wpdObject.Id,
+		RelPath:   wpdFile.relPath(executionPlan.wpdRootDir),
+		LocalPath: localPath,
+		Size:      wpdFile.wpdObject.Size,
+		Status:    status,
+	})
+}
+
+func (downloader *MtpDownloader) verifyTmpFiles(executionPlan *ExecutionPlan) {
+	var progressBar *pb.ProgressBar
+	if downloader.options.VerifyChecksum {
+		log.Infof("Verifying checksums of copied files from %v", downloader.currentDeviceLabel)
+		progressBar = CopyProgressTemplate.Start64(executionPlan.GetTotalSize())
+		defer progressBar.Finish()
+	}
+
+	fileIterator := executionPlan.GetFileInterator()
+	for fileIterator.Current() != nil {
+		wpdFile := fileIterator.Current()
+
+		if wpdFile.wasCopied && !wpdFile.wasVerified {
+			if progressBar != nil {
+				progressBar.Set("prefix", downloader.progressPrefix(fileIterator, wpdFile.relPath(executionPlan.wpdRootDir)))
+			}
+
+			err := wpdFile.verify(downloader.options.VerifyChecksum, progressBar)
+			if err != nil {
+				log.Warningf("Verification of '%v' - failed: %v", wpdFile.filePath, err)
+				wpdFile.wasCopied = false
+				// corrupted copy should not be placed, the device file is kept and imported next time
+				if err := os.Remove(wpdFile.localPath); err != nil && !os.IsNotExist(err) {
+					log.Warningf("Unable to remove '%v' corrupted copy: %v", wpdFile.localPath, err)
+				}
+				wpdFile.localPath = ""
+				downloader.keptFiles = append(downloader.keptFiles, fmt.Sprintf("%v: '%v' - %v", downloader.currentDeviceLabel, wpdFile.filePath, err))
+				downloader.recordFile(wpdFile, executionPlan, journalFailed)
+			} else {
+				log.Debugf("Verification of '%v' - done", wpdFile.filePath)
+				wpdFile.wasVerified = true
+				downloader.recordFile(wpdFile, executionPlan, journalVerified)
+			}
 		}
 
 		fileIterator.Next()
 	}
 }
+
+func (downloader *MtpDownloader) printSummary() {
+	if len(downloader.keptFiles) == 0 {
+		return
+	}
+
+	log.Warningf("%v file(s) were kept on device(s) because they were not copied, verified or placed:", len(downloader.keptFiles))
+	for _, keptFile := range downloader.keptFiles {
+		log.Warningf(" - %v", keptFile)
+	}
+}
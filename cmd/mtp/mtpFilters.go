@@ -1,68 +1,285 @@
 package mtp
 
 import (
+	"fmt"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
-
-	"github.com/tobwithu/gowpd"
 )
 
 type MtpDeviceFilter interface {
-	accept(deviceId int, device *gowpd.Device, deviceLabel string) bool
+	accept(device Device, info DeviceInfo) bool
 }
 
 type HasFileFilter struct {
 	fileName string
 }
 
-func (filter HasFileFilter) accept(deviceId int, device *gowpd.Device, deviceLabel string) bool {
-	deviceFileName := gowpd.PathSeparator + filter.fileName
-	obj := device.FindObject(deviceFileName)
-	return obj != nil
+// accept checks whether any of device storages has the file
+func (filter HasFileFilter) accept(device Device, info DeviceInfo) bool {
+	deviceFileName := PathSeparator + filter.fileName
+
+	storages, err := device.GetStorages()
+	if err != nil || len(storages) == 0 {
+		return device.FindObject(deviceFileName) != nil
+	}
+	for _, storage := range storages {
+		if findStorageObject(device, storage.Id, deviceFileName) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (filter HasFileFilter) String() string {
+	return "has:" + filepath.ToSlash(filter.fileName)
 }
 
 type HasDeviceNameFilter struct {
 	deviceName string
 }
 
-func (filter HasDeviceNameFilter) accept(deviceId int, device *gowpd.Device, deviceLabel string) bool {
-	return strings.Contains(deviceLabel, filter.deviceName)
+func (filter HasDeviceNameFilter) accept(device Device, info DeviceInfo) bool {
+	return strings.Contains(info.Label, filter.deviceName)
+}
+
+func (filter HasDeviceNameFilter) String() string {
+	return "label~" + filter.deviceName
 }
 
 type NotFilter struct {
 	filter MtpDeviceFilter
 }
 
-func (filter NotFilter) accept(deviceId int, device *gowpd.Device, deviceLabel string) bool {
-	return !filter.filter.accept(deviceId, device, deviceLabel)
+func (filter NotFilter) accept(device Device, info DeviceInfo) bool {
+	return !filter.filter.accept(device, info)
+}
+
+func (filter NotFilter) String() string {
+	return fmt.Sprintf("!%v", filter.filter)
 }
 
 type AndFilter struct {
 	filters []MtpDeviceFilter
 }
 
-func (filter AndFilter) accept(deviceId int, device *gowpd.Device, deviceLabel string) bool {
+func (filter AndFilter) accept(device Device, info DeviceInfo) bool {
 	result := true
 
 	for _, f := range filter.filters {
-		result = result && f.accept(deviceId, device, deviceLabel)
+		result = result && f.accept(device, info)
 	}
 	return result
 }
 
+func (filter AndFilter) String() string {
+	return "all(" + joinFilters(filter.filters) + ")"
+}
+
 type OrFilter struct {
 	filters []MtpDeviceFilter
 }
 
-func (filter OrFilter) accept(deviceId int, device *gowpd.Device, deviceLabel string) bool {
+func (filter OrFilter) accept(device Device, info DeviceInfo) bool {
 	result := false
 
 	for _, f := range filter.filters {
-		result = result || f.accept(deviceId, device, deviceLabel)
+		result = result || f.accept(device, info)
+	}
+	return result
+}
+
+func (filter OrFilter) String() string {
+	return "any(" + joinFilters(filter.filters) + ")"
+}
+
+// DevicePropertyFilter matches device name, description, manufacturer or serial number.
+// Supported operators are '~' (contains, case insensitive), '=' (equals, case insensitive) and '=~' (regexp)
+type DevicePropertyFilter struct {
+	property string
+	operator string
+	value    string
+	regex    *regexp.Regexp
+}
+
+func (filter DevicePropertyFilter) accept(device Device, info DeviceInfo) bool {
+	value := filter.getValue(info)
+	switch filter.operator {
+	case "~":
+		return strings.Contains(strings.ToLower(value), strings.ToLower(filter.value))
+	case "=":
+		return strings.EqualFold(value, filter.value)
+	case "=~":
+		return filter.regex.MatchString(value)
+	}
+	return false
+}
+
+func (filter DevicePropertyFilter) getValue(info DeviceInfo) string {
+	switch filter.property {
+	case "name":
+		return info.Name
+	case "description":
+		return info.Description
+	case "manufacturer":
+		return info.Manufacturer
+	case "serial":
+		return info.SerialNumber
+	}
+	return ""
+}
+
+func (filter DevicePropertyFilter) String() string {
+	return filter.property + filter.operator + filter.value
+}
+
+// CapacityFilter compares device capacity with a size. Devices with unknown capacity never match
+type CapacityFilter struct {
+	operator string
+	size     int64
+}
+
+func (filter CapacityFilter) accept(device Device, info DeviceInfo) bool {
+	if info.Capacity <= 0 {
+		return false
+	}
+	switch filter.operator {
+	case "<":
+		return info.Capacity < filter.size
+	case "<=":
+		return info.Capacity <= filter.size
+	case ">":
+		return info.Capacity > filter.size
+	case ">=":
+		return info.Capacity >= filter.size
+	case "=":
+		return info.Capacity == filter.size
+	}
+	return false
+}
+
+func (filter CapacityFilter) String() string {
+	return fmt.Sprintf("capacity%v%v", filter.operator, SizeToLabel(filter.size))
+}
+
+// propertySource is implemented by sources which do not provide some of device properties
+// (manufacturer, serial or capacity). Filters on such properties would never match
+type propertySource interface {
+	UnsupportedProperties() []string
+}
+
+// CheckFilterProperties returns error if the filter uses device properties which are not provided by the source.
+// Devices of such sources are skipped with a warning, other sources and profiles are still imported
+func CheckFilterProperties(source Source, filter MtpDeviceFilter) error {
+	properties, ok := source.(propertySource)
+	if !ok {
+		return nil
 	}
+
+	used := filterProperties(filter)
+	for _, property := range properties.UnsupportedProperties() {
+		if used[property] {
+			return fmt.Errorf("'%v' devices source does not provide '%v' device property, the filter cannot be checked", source.GetName(), property)
+		}
+	}
+	return nil
+}
+
+// filterProperties returns device properties used by the filter and its sub-filters
+func filterProperties(filter MtpDeviceFilter) map[string]bool {
+	result := make(map[string]bool)
+	var visit func(filter MtpDeviceFilter)
+	visit = func(filter MtpDeviceFilter) {
+		switch value := filter.(type) {
+		case AndFilter:
+			for _, child := range value.filters {
+				visit(child)
+			}
+		case OrFilter:
+			for _, child := range value.filters {
+				visit(child)
+			}
+		case NotFilter:
+			visit(value.filter)
+		case DevicePropertyFilter:
+			result[value.property] = true
+		case CapacityFilter:
+			result["capacity"] = true
+		}
+	}
+	visit(filter)
 	return result
 }
 
+func joinFilters(filters []MtpDeviceFilter) string {
+	result := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		result = append(result, fmt.Sprint(filter))
+	}
+	return strings.Join(result, ", ")
+}
+
+// ExplainFilter checks the filter against the device. If the device is not accepted returns description
+// of failed sub-filters, e.g. "has:DCIM, !any(label~HERO, label~GoPro, has:DCIM/100GOPRO)". Failed 'any' filters
+// list their failed sub-filters, failed '!' filters list sub-filters which matched the device
+func ExplainFilter(filter MtpDeviceFilter, device Device, info DeviceInfo) (bool, string) {
+	switch value := filter.(type) {
+	case AndFilter:
+		failed := make([]string, 0)
+		for _, child := range value.filters {
+			if accepted, reason := ExplainFilter(child, device, info); !accepted {
+				failed = append(failed, reason)
+			}
+		}
+		return len(failed) == 0, strings.Join(failed, ", ")
+	case OrFilter:
+		failed := make([]string, 0, len(value.filters))
+		for _, child := range value.filters {
+			accepted, reason := ExplainFilter(child, device, info)
+			if accepted {
+				return true, ""
+			}
+			failed = append(failed, reason)
+		}
+		return false, "any(" + strings.Join(failed, ", ") + ")"
+	case NotFilter:
+		if !value.filter.accept(device, info) {
+			return true, ""
+		}
+		return false, "!" + explainMatch(value.filter, device, info)
+	}
+
+	if filter.accept(device, info) {
+		return true, ""
+	}
+	return false, fmt.Sprint(filter)
+}
+
+// explainMatch describes sub-filters which accepted the device, e.g. "any(label~HERO)"
+func explainMatch(filter MtpDeviceFilter, device Device, info DeviceInfo) string {
+	switch value := filter.(type) {
+	case AndFilter:
+		matched := make([]string, 0, len(value.filters))
+		for _, child := range value.filters {
+			matched = append(matched, explainMatch(child, device, info))
+		}
+		return "all(" + strings.Join(matched, ", ") + ")"
+	case OrFilter:
+		matched := make([]string, 0)
+		for _, child := range value.filters {
+			if child.accept(device, info) {
+				matched = append(matched, explainMatch(child, device, info))
+			}
+		}
+		return "any(" + strings.Join(matched, ", ") + ")"
+	case NotFilter:
+		_, reason := ExplainFilter(value.filter, device, info)
+		return "!" + reason
+	}
+	return fmt.Sprint(filter)
+}
+
 var DCIM_DIR string = "DCIM"
 var CAM_FILES_DIR string = path.Join("PRIVATE", "AVCHD", "BDMV", "STREAM")
 var GOPRO_DIR string = path.Join("DCIM", "100GOPRO")
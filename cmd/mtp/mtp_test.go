@@ -0,0 +1,536 @@
+package mtp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testModTime = time.Date(2024, 5, 18, 10, 30, 15, 0, time.Local)
+
+func TestLoadFromAllWpd_CopyAndDelete(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010001.LRV", "preview1", testModTime).
+		withFile("DCIM/100GOPRO/sub/GX010002.MP4", "video2", testModTime.Add(time.Hour))
+	source := newFakeSource(goPro)
+
+	resultDir, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	r.True(source.destroyed)
+	assertFile(t, filepath.Join(resultDir, "0", "GX010001.MP4"), "video1", testModTime)
+	assertFile(t, filepath.Join(resultDir, "0", "GX010001.LRV"), "preview1", testModTime)
+	assertFile(t, filepath.Join(resultDir, "0", "sub", "GX010002.MP4"), "video2", testModTime.Add(time.Hour))
+
+	r.ElementsMatch([]string{"/DCIM/100GOPRO/GX010001.MP4", "/DCIM/100GOPRO/GX010001.LRV", "/DCIM/100GOPRO/sub/GX010002.MP4"}, goPro.deleted)
+	r.False(goPro.hasFile("DCIM/100GOPRO/GX010001.MP4"))
+	r.True(goPro.hasFile("DCIM/100GOPRO/sub"))
+}
+
+func TestLoadFromAllWpd_KeepSourceKeepsSource(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime)
+	source := newFakeSource(goPro)
+
+	resultDir, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{KeepSource: true})
+
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "0", "GX010001.MP4"), "video1", testModTime)
+	r.Empty(goPro.deleted)
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010001.MP4"))
+}
+
+func TestLoadFromAllWpd_DeletesOnlyPlacedFiles(t *testing.T) {
+	r := require.New(t)
+
+	ledger, err := OpenLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	r.NoError(err)
+	defer ledger.Close()
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime)
+	targetDir := t.TempDir()
+	place := func(resultDir string) {
+		r.NoError(os.Rename(filepath.Join(resultDir, "0", "GX010001.MP4"), filepath.Join(targetDir, "VID_1.MP4")))
+	}
+
+	resultDir, err := LoadFromAllWpd(newFakeSource(goPro), GoProProfile, t.TempDir(), ImportOptions{Ledger: ledger, Place: place})
+
+	r.NoError(err)
+	assertFile(t, filepath.Join(targetDir, "VID_1.MP4"), "video1", testModTime)
+	assertFile(t, filepath.Join(resultDir, "0", "GX010002.MP4"), "video2", testModTime)
+	r.Equal([]string{"/DCIM/100GOPRO/GX010001.MP4"}, goPro.deleted)
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010002.MP4"))
+	r.Equal(1, ledger.GetEntriesCount())
+	r.True(ledger.Contains("HERO8 Black (GoPro)", filepath.FromSlash("/DCIM/100GOPRO/GX010001.MP4"), 6, testModTime.Unix()))
+}
+
+func TestScanAllWpd_CopiesNothing(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010001.THM", "thumb", testModTime)
+	profile := GoProProfile
+	profile.Scan = mustParseScanRules(nil, []string{"*.THM"})
+
+	files, err := ScanAllWpd(newFakeSource(goPro), profile, ImportOptions{})
+
+	r.NoError(err)
+	r.Len(files, 1)
+	r.Equal("HERO8 Black (GoPro)", files[0].Device)
+	r.Equal("/DCIM/100GOPRO/GX010001.MP4", files[0].Path)
+	r.Equal(int64(len("video1")), files[0].Size)
+	r.Equal(testModTime.Unix(), files[0].ModTime)
+	r.Empty(files[0].LocalPath)
+	r.Empty(goPro.deleted)
+	r.Zero(goPro.readCounts["/DCIM/100GOPRO/GX010001.MP4"])
+}
+
+func TestLoadFromAllWpd_DeviceFiltering(t *testing.T) {
+	r := require.New(t)
+
+	nikon := newFakeDevice("D750", "Nikon DSC").
+		withFile("DCIM/100NIKON/DSC_0001.NEF", "raw", testModTime)
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime)
+	cam := newFakeDevice("CAM_SD", "Panasonic").
+		withFile("PRIVATE/AVCHD/BDMV/STREAM/00001.MTS", "avchd", testModTime)
+	source := newFakeSource(nikon, goPro, cam)
+
+	resultDir, err := LoadFromAllWpd(source, SdPhotosProfile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "0", "100NIKON", "DSC_0001.NEF"), "raw", testModTime)
+	r.NoDirExists(filepath.Join(resultDir, "1"))
+	r.NoDirExists(filepath.Join(resultDir, "2"))
+
+	r.Equal([]string{"/DCIM/100NIKON/DSC_0001.NEF"}, nikon.deleted)
+	r.Empty(goPro.deleted)
+	r.Empty(cam.deleted)
+}
+
+func TestLoadFromAllWpd_ReadErrorKeepsSource(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime).
+		withReadError("DCIM/100GOPRO/GX010001.MP4", errors.New("device is busy"))
+	source := newFakeSource(goPro)
+
+	resultDir, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "0", "GX010002.MP4"), "video2", testModTime)
+	r.Equal([]string{"/DCIM/100GOPRO/GX010002.MP4"}, goPro.deleted)
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010001.MP4"))
+}
+
+func TestLoadFromAllWpd_DeleteErrorDoesNotStopOthers(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime).
+		withDeleteError("DCIM/100GOPRO/GX010001.MP4", errors.New("read only storage"))
+	source := newFakeSource(goPro)
+
+	_, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	r.Equal([]string{"/DCIM/100GOPRO/GX010002.MP4"}, goPro.deleted)
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010001.MP4"))
+}
+
+func TestLoadFromAllWpd_IgnoredFiles(t *testing.T) {
+	r := require.New(t)
+
+	sdCard := newFakeDevice("SD", "Card").
+		withFile("DCIM/100NIKON/DSC_0001.JPG", "jpeg", testModTime).
+		withFile("DCIM/System Volume Information/IndexerVolumeGuid", "guid", testModTime)
+	source := newFakeSource(sdCard)
+
+	resultDir, err := LoadFromAllWpd(source, SdPhotosProfile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "0", "100NIKON", "DSC_0001.JPG"), "jpeg", testModTime)
+	r.NoDirExists(filepath.Join(resultDir, "0", "System Volume Information"))
+	r.True(sdCard.hasFile("DCIM/System Volume Information/IndexerVolumeGuid"))
+}
+
+func TestLoadFromAllWpd_UnreadableDeviceSkipped(t *testing.T) {
+	r := require.New(t)
+
+	broken := newFakeDevice("HERO7", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime)
+	broken.chooseError = errors.New("access denied")
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime)
+	source := newFakeSource(broken, goPro)
+
+	resultDir, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "1", "GX010002.MP4"), "video2", testModTime)
+	r.Empty(broken.deleted)
+}
+
+func TestLoadFromAllWpd_NoMatchedDirectory(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").withDir("DCIM/100GOPRO")
+	source := newFakeSource(goPro)
+
+	resultDir, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	r.DirExists(resultDir)
+	r.Empty(goPro.deleted)
+}
+
+func TestLoadFromAllWpd_InitError(t *testing.T) {
+	r := require.New(t)
+
+	source := newFakeSource()
+	source.initError = errors.New("no WPD")
+
+	resultDir, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{})
+
+	r.Error(err)
+	r.Equal("", resultDir)
+	r.True(source.destroyed)
+}
+
+func TestLoadFromAllWpd_SizeMismatchKeepsSource(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime).
+		withSize("DCIM/100GOPRO/GX010001.MP4", 100)
+	source := newFakeSource(goPro)
+
+	_, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	r.Equal([]string{"/DCIM/100GOPRO/GX010002.MP4"}, goPro.deleted)
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010001.MP4"))
+}
+
+func TestLoadFromAllWpd_ChecksumMismatchKeepsSource(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime).
+		withFirstRead("DCIM/100GOPRO/GX010001.MP4", "vidxx1")
+	source := newFakeSource(goPro)
+
+	_, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{VerifyChecksum: true})
+
+	r.NoError(err)
+	r.Equal([]string{"/DCIM/100GOPRO/GX010002.MP4"}, goPro.deleted)
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010001.MP4"))
+	r.Equal(2, goPro.readCounts["/DCIM/100GOPRO/GX010002.MP4"])
+}
+
+func TestLoadFromAllWpd_ChecksumMismatchIsNotPlaced(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime).
+		withFirstRead("DCIM/100GOPRO/GX010001.MP4", "vidxx1")
+	placed := make([]string, 0)
+	place := func(resultDir string) {
+		filepath.WalkDir(resultDir, func(path string, entry os.DirEntry, err error) error {
+			if err == nil && strings.HasSuffix(path, ".MP4") {
+				placed = append(placed, filepath.Base(path))
+				os.Remove(path)
+			}
+			return nil
+		})
+	}
+
+	_, err := LoadFromAllWpd(newFakeSource(goPro), GoProProfile, t.TempDir(), ImportOptions{VerifyChecksum: true, Place: place})
+
+	r.NoError(err)
+	r.Equal([]string{"GX010002.MP4"}, placed)
+	r.Equal([]string{"/DCIM/100GOPRO/GX010002.MP4"}, goPro.deleted)
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010001.MP4"))
+}
+
+func TestLoadFromAllWpd_ChecksumNotVerifiedByDefault(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFirstRead("DCIM/100GOPRO/GX010001.MP4", "vidxx1")
+	source := newFakeSource(goPro)
+
+	_, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	r.Equal([]string{"/DCIM/100GOPRO/GX010001.MP4"}, goPro.deleted)
+	r.Equal(1, goPro.readCounts["/DCIM/100GOPRO/GX010001.MP4"])
+}
+
+func TestMtpDownloader_KeptFilesSummary(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withSize("DCIM/100GOPRO/GX010001.MP4", 100)
+	sut := MtpDownloader{source: newFakeSource(goPro), profile: GoProProfile}
+	sut.init(t.TempDir())
+	defer sut.close()
+
+	sut.loadFromMatchedDevices()
+
+	r.Len(sut.keptFiles, 1)
+	r.Contains(sut.keptFiles[0], "GX010001.MP4")
+	r.Contains(sut.keptFiles[0], "size mismatch")
+}
+
+func TestLoadFromAllWpd_Journal(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime).
+		withReadError("DCIM/100GOPRO/GX010002.MP4", errors.New("device is busy"))
+	targetDir := t.TempDir()
+
+	resultDir, err := LoadFromAllWpd(newFakeSource(goPro), GoProProfile, targetDir, ImportOptions{})
+	r.NoError(err)
+
+	run, err := ReadJournalRun(resultDir)
+	r.NoError(err)
+	r.Equal(GoProProfile.Name, run.Profile)
+	r.Equal(targetDir, run.TargetDir)
+
+	journal, err := loadJournal(resultDir)
+	r.NoError(err)
+	r.Equal(journalDeleted, journal.find("HERO8 Black (GoPro)", "GX010001.MP4").Status)
+	r.Equal(filepath.Join("0", "GX010001.MP4"), journal.find("HERO8 Black (GoPro)", "GX010001.MP4").LocalPath)
+	r.Equal(journalFailed, journal.find("HERO8 Black (GoPro)", "GX010002.MP4").Status)
+}
+
+func TestResumeFromAllWpd_SkipsVerifiedFiles(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime).
+		withReadError("DCIM/100GOPRO/GX010002.MP4", errors.New("device is busy"))
+
+	// the first run is interrupted before files are placed
+	resultDir, err := LoadFromAllWpd(newFakeSource(goPro), GoProProfile, t.TempDir(), ImportOptions{Place: func(string) {}})
+	r.NoError(err)
+	r.Empty(goPro.deleted)
+
+	// device was reconnected with another id
+	delete(goPro.readErrors, "/DCIM/100GOPRO/GX010002.MP4")
+	other := newFakeDevice("D750", "Nikon DSC").withFile("DCIM/100NIKON/DSC_0001.NEF", "raw", testModTime)
+	source := newFakeSource(other, goPro)
+
+	resumedDir, err := ResumeFromAllWpd(source, GoProProfile, resultDir, ImportOptions{})
+
+	r.NoError(err)
+	r.Equal(resultDir, resumedDir)
+	assertFile(t, filepath.Join(resultDir, "0", "GX010001.MP4"), "video1", testModTime)
+	assertFile(t, filepath.Join(resultDir, "0", "GX010002.MP4"), "video2", testModTime)
+	r.NoDirExists(filepath.Join(resultDir, "1"))
+	r.Equal(1, goPro.readCounts["/DCIM/100GOPRO/GX010001.MP4"])
+	r.ElementsMatch([]string{"/DCIM/100GOPRO/GX010001.MP4", "/DCIM/100GOPRO/GX010002.MP4"}, goPro.deleted)
+	r.Empty(other.deleted)
+}
+
+func TestResumeFromAllWpd_RecopiesMissingFiles(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime)
+
+	resultDir, err := LoadFromAllWpd(newFakeSource(goPro), GoProProfile, t.TempDir(), ImportOptions{Place: func(string) {}})
+	r.NoError(err)
+	r.NoError(os.Remove(filepath.Join(resultDir, "0", "GX010001.MP4")))
+
+	_, err = ResumeFromAllWpd(newFakeSource(goPro), GoProProfile, resultDir, ImportOptions{})
+
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "0", "GX010001.MP4"), "video1", testModTime)
+	r.Equal(2, goPro.readCounts["/DCIM/100GOPRO/GX010001.MP4"])
+	r.Equal([]string{"/DCIM/100GOPRO/GX010001.MP4"}, goPro.deleted)
+}
+
+func TestResumeFromAllWpd_DeletesPlacedFiles(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withDeleteError("DCIM/100GOPRO/GX010001.MP4", errors.New("device is busy"))
+
+	// files were placed, but the device was disconnected before deletion
+	resultDir, err := LoadFromAllWpd(newFakeSource(goPro), GoProProfile, t.TempDir(), ImportOptions{Place: moveToDir(t.TempDir())})
+	r.NoError(err)
+	r.Empty(goPro.deleted)
+
+	delete(goPro.deleteErrors, "/DCIM/100GOPRO/GX010001.MP4")
+	_, err = ResumeFromAllWpd(newFakeSource(goPro), GoProProfile, resultDir, ImportOptions{Place: func(string) {}})
+
+	r.NoError(err)
+	r.Equal(1, goPro.readCounts["/DCIM/100GOPRO/GX010001.MP4"])
+	r.NoFileExists(filepath.Join(resultDir, "0", "GX010001.MP4"))
+	r.Equal([]string{"/DCIM/100GOPRO/GX010001.MP4"}, goPro.deleted)
+}
+
+func TestResumeFromAllWpd_NoJournal(t *testing.T) {
+	r := require.New(t)
+
+	resultDir, err := ResumeFromAllWpd(newFakeSource(), GoProProfile, t.TempDir(), ImportOptions{})
+
+	r.Error(err)
+	r.Equal("", resultDir)
+}
+
+func TestLoadFromAllWpd_LedgerSkipsImportedFiles(t *testing.T) {
+	r := require.New(t)
+
+	ledger, err := OpenLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	r.NoError(err)
+	defer ledger.Close()
+
+	cam := newFakeDevice("CAM_SD", "Panasonic").
+		withFile("PRIVATE/AVCHD/BDMV/STREAM/00001.MTS", "avchd1", testModTime).
+		withDeleteError("PRIVATE/AVCHD/BDMV/STREAM/00001.MTS", errors.New("read only storage"))
+	options := ImportOptions{Ledger: ledger}
+
+	resultDir, err := LoadFromAllWpd(newFakeSource(cam), CamVideoProfile, t.TempDir(), options)
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "0", "00001.MTS"), "avchd1", testModTime)
+
+	cam.withFile("PRIVATE/AVCHD/BDMV/STREAM/00002.MTS", "avchd2", testModTime)
+
+	resultDir, err = LoadFromAllWpd(newFakeSource(cam), CamVideoProfile, t.TempDir(), options)
+	r.NoError(err)
+	r.NoFileExists(filepath.Join(resultDir, "0", "00001.MTS"))
+	assertFile(t, filepath.Join(resultDir, "0", "00002.MTS"), "avchd2", testModTime)
+	r.Equal(1, cam.readCounts["/PRIVATE/AVCHD/BDMV/STREAM/00001.MTS"])
+
+	options.Reimport = true
+	resultDir, err = LoadFromAllWpd(newFakeSource(cam), CamVideoProfile, t.TempDir(), options)
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "0", "00001.MTS"), "avchd1", testModTime)
+}
+
+func TestLoadFromAllWpd_LedgerUpdatedWhenSourceIsKept(t *testing.T) {
+	r := require.New(t)
+
+	ledger, err := OpenLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	r.NoError(err)
+	defer ledger.Close()
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withSize("DCIM/100GOPRO/GX010001.MP4", 100).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime)
+
+	_, err = LoadFromAllWpd(newFakeSource(goPro), GoProProfile, t.TempDir(), ImportOptions{Ledger: ledger, KeepSource: true})
+	r.NoError(err)
+	// size mismatch: file was not imported
+	r.Equal(1, ledger.GetEntriesCount())
+	r.True(ledger.Contains("HERO8 Black (GoPro)", filepath.FromSlash("/DCIM/100GOPRO/GX010002.MP4"), 6, testModTime.Unix()))
+	r.Empty(goPro.deleted)
+}
+
+func TestLoadFromAllWpd_LedgerNotUpdatedByPlan(t *testing.T) {
+	r := require.New(t)
+
+	ledger, err := OpenLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	r.NoError(err)
+	defer ledger.Close()
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime)
+	plan := NewImportPlan("gopro", t.TempDir())
+
+	_, err = LoadFromAllWpd(newFakeSource(goPro), GoProProfile, plan.TargetDir, ImportOptions{Ledger: ledger, KeepSource: true, Plan: plan})
+
+	r.NoError(err)
+	r.Len(plan.Files, 1)
+	r.Equal(0, ledger.GetEntriesCount())
+}
+
+// moveToDir returns Place function which moves all downloaded files to the dir
+func moveToDir(dir string) func(string) {
+	return func(resultDir string) {
+		filepath.WalkDir(resultDir, func(path string, entry os.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() && entry.Name() != JournalFileName {
+				err = os.Rename(path, filepath.Join(dir, entry.Name()))
+			}
+			return err
+		})
+	}
+}
+
+func assertFile(t *testing.T, filePath string, content string, modTime time.Time) {
+	r := require.New(t)
+
+	data, err := os.ReadFile(filePath)
+	r.NoError(err, "'%v' should exist", filePath)
+	r.Equal(content, string(data))
+
+	stat, err := os.Stat(filePath)
+	r.NoError(err)
+	r.Equal(modTime.Unix(), stat.ModTime().Unix())
+}
+
+func TestLoadFromAllWpd_RetriesBrokenReads(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withBrokenReads("DCIM/100GOPRO/GX010001.MP4", 2)
+	source := newFakeSource(goPro)
+
+	resultDir, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{CopyAttempts: 3})
+
+	r.NoError(err)
+	r.Equal(3, goPro.readCounts["/DCIM/100GOPRO/GX010001.MP4"])
+	assertFile(t, filepath.Join(resultDir, "0", "GX010001.MP4"), "video1", testModTime)
+	r.NoFileExists(filepath.Join(resultDir, "0", "GX010001.MP4"+partFileSuffix))
+	r.Equal([]string{"/DCIM/100GOPRO/GX010001.MP4"}, goPro.deleted)
+}
+
+func TestLoadFromAllWpd_BrokenReadRemovesPartFile(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime).
+		withBrokenReads("DCIM/100GOPRO/GX010001.MP4", 2)
+	source := newFakeSource(goPro)
+
+	resultDir, err := LoadFromAllWpd(source, GoProProfile, t.TempDir(), ImportOptions{CopyAttempts: 2})
+
+	r.NoError(err)
+	r.Equal(2, goPro.readCounts["/DCIM/100GOPRO/GX010001.MP4"])
+	r.NoFileExists(filepath.Join(resultDir, "0", "GX010001.MP4"))
+	r.NoFileExists(filepath.Join(resultDir, "0", "GX010001.MP4"+partFileSuffix))
+	assertFile(t, filepath.Join(resultDir, "0", "GX010002.MP4"), "video2", testModTime)
+	r.Equal([]string{"/DCIM/100GOPRO/GX010002.MP4"}, goPro.deleted)
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010001.MP4"))
+}
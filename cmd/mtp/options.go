@@ -0,0 +1,35 @@
+package mtp
+
+import "time"
+
+// ImportOptions holds settings of an import from devices
+type ImportOptions struct {
+	// KeepSource copies files to temp directory but keeps them on the device
+	KeepSource bool
+	// VerifyChecksum re-reads device files and compares SHA-256 digests with copied files
+	VerifyChecksum bool
+	// Ledger keeps track of imported files. Optional
+	Ledger *ImportLedger
+	// Reimport ignores ledger and imports all device files
+	Reimport bool
+	// Files selects device files to be imported
+	Files FileFilter
+	// FreeSpaceMargin is space (in bytes) which should stay free on temp and target volumes after the import
+	FreeSpaceMargin int64
+	// CopyAttempts is number of attempts to copy a file if device read fails. Values below 1 mean a single attempt
+	CopyAttempts int
+	// CopyRetryDelay is a delay before the second copy attempt, the delay is doubled for each next attempt
+	CopyRetryDelay time.Duration
+	// Place moves downloaded files from the temp dir (passed as argument) to the target dir. Device files are deleted
+	// only if their local copies were moved out of the temp dir. All copied files are considered placed if it is nil
+	Place func(resultDir string)
+	// Plan collects downloaded files if set, e.g. to export them as a plan document. Import ledger is not updated in this case
+	Plan *ImportPlan
+}
+
+// Close releases resources (e.g. ledger file) held by options
+func (options ImportOptions) Close() {
+	if options.Ledger != nil {
+		options.Ledger.Close()
+	}
+}
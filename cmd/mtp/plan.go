@@ -0,0 +1,331 @@
+package mtp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ImportPlanVersion is version of the plan document format
+const ImportPlanVersion = 1
+
+// ImportPlan is a reviewable (and editable) document which describes device files to be imported
+// and their destinations inside the target dir
+type ImportPlan struct {
+	Version   int       `json:"version"`
+	Profile   string    `json:"profile"`
+	TargetDir string    `json:"targetDir"`
+	Created   time.Time `json:"created"`
+	// TempDir keeps files downloaded while the plan was made, ApplyImportPlan reuses them instead of copying again
+	TempDir string         `json:"tempDir,omitempty"`
+	Files   []*PlannedFile `json:"files"`
+}
+
+// PlannedFile is a single device file of the import plan
+type PlannedFile struct {
+	// Device is device identifier, the same as used by import ledger. It includes storage name for devices with several storages
+	Device string `json:"device"`
+	// Path is slash separated path of the file on the device
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+	// Destination is slash separated path relative to the target dir
+	Destination string `json:"destination"`
+	// TempPath is slash separated path of the downloaded copy relative to the plan temp dir
+	TempPath string `json:"tempPath,omitempty"`
+
+	// LocalPath is path of the downloaded file in the temp dir
+	LocalPath string `json:"-"`
+}
+
+// NewImportPlan creates empty plan. Downloaded files are added to the plan if it is set to ImportOptions.Plan
+func NewImportPlan(profile string, targetDir string) *ImportPlan {
+	return &ImportPlan{
+		Version:   ImportPlanVersion,
+		Profile:   profile,
+		TargetDir: targetDir,
+		Created:   time.Now(),
+		Files:     make([]*PlannedFile, 0),
+	}
+}
+
+// ReadImportPlan loads plan document from JSON file
+func ReadImportPlan(planPath string) (*ImportPlan, error) {
+	data, err := os.ReadFile(planPath)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ImportPlan{}
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("unable to parse '%v' plan: %w", planPath, err)
+	}
+	if err := plan.validate(); err != nil {
+		return nil, fmt.Errorf("invalid '%v' plan: %w", planPath, err)
+	}
+	return plan, nil
+}
+
+// Write saves plan document as JSON file
+func (plan *ImportPlan) Write(planPath string) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(planPath, append(data, '\n'), 0644)
+}
+
+func (plan *ImportPlan) validate() error {
+	if plan.Version != ImportPlanVersion {
+		return fmt.Errorf("unsupported version %v, %v is expected", plan.Version, ImportPlanVersion)
+	}
+	if plan.TargetDir == "" {
+		return fmt.Errorf("targetDir is required")
+	}
+
+	destinations := make(map[string]int, len(plan.Files))
+	for i, file := range plan.Files {
+		switch {
+		case file.Device == "":
+			return fmt.Errorf("files[%v]: device is required", i)
+		case file.Path == "":
+			return fmt.Errorf("files[%v]: path is required", i)
+		case file.Destination == "":
+			return fmt.Errorf("files[%v]: destination is required", i)
+		case filepath.IsAbs(filepath.FromSlash(file.Destination)) || strings.HasPrefix(file.Destination, "/"):
+			return fmt.Errorf("files[%v]: destination should be relative to the target dir", i)
+		case file.TempPath != "" && !isRelativeSubPath(file.TempPath):
+			return fmt.Errorf("files[%v]: tempPath should be relative to the temp dir", i)
+		}
+
+		destination := filepath.ToSlash(filepath.Clean(filepath.FromSlash(file.Destination)))
+		if destination == ".." || strings.HasPrefix(destination, "../") {
+			return fmt.Errorf("files[%v]: destination is outside of the target dir", i)
+		}
+		destination = strings.ToLower(destination)
+		if other, exists := destinations[destination]; exists {
+			return fmt.Errorf("files[%v]: '%v' destination is already used by files[%v]", i, file.Destination, other)
+		}
+		destinations[destination] = i
+	}
+	return nil
+}
+
+// isRelativeSubPath checks that slash separated path does not leave its base dir
+func isRelativeSubPath(path string) bool {
+	if filepath.IsAbs(filepath.FromSlash(path)) || strings.HasPrefix(path, "/") {
+		return false
+	}
+	path = filepath.ToSlash(filepath.Clean(filepath.FromSlash(path)))
+	return path != ".." && !strings.HasPrefix(path, "../")
+}
+
+// GetDestinationPath returns absolute destination path of the planned file
+func (plan *ImportPlan) GetDestinationPath(file *PlannedFile) string {
+	return filepath.Join(plan.TargetDir, filepath.FromSlash(file.Destination))
+}
+
+// ApplyImportPlan copies files listed in the plan to a new temp directory inside the plan target dir. Verified copies
+// kept in the plan temp dir are reused, only missing ones are copied again. The plan is refused if any of listed files
+// is missing or was changed on the device. LocalPath of copied files is updated before options.Place is called
+func ApplyImportPlan(source Source, profile DeviceProfile, plan *ImportPlan, options ImportOptions) (string, error) {
+	options.Plan = nil
+	result := MtpDownloader{source: source, options: options, profile: profile}
+	result.init(plan.TargetDir)
+	defer result.close()
+
+	result.loadPlannedFiles(plan)
+	result.printSummary()
+
+	return result.GetResultDir(), result.GetError()
+}
+
+// CheckImportPlan checks that all files listed in the plan are connected and were not changed. Nothing is copied
+func CheckImportPlan(source Source, profile DeviceProfile, plan *ImportPlan) error {
+	result := MtpDownloader{source: source, profile: profile}
+	result.error = source.Init()
+	defer result.close()
+
+	if result.HasError() {
+		return result.GetError()
+	}
+	_, _, err := result.matchPlannedFiles(plan)
+	return err
+}
+
+func (downloader *MtpDownloader) loadPlannedFiles(plan *ImportPlan) {
+	if downloader.HasError() {
+		return
+	}
+
+	devicePlans, plannedFiles, err := downloader.matchPlannedFiles(plan)
+	if err == nil {
+		reusePlannedFiles(plan, plannedFiles)
+		err = downloader.checkFreeSpace(devicePlans)
+	}
+	if err != nil {
+		downloader.error = err
+		downloader.discardResultDir()
+		return
+	}
+
+	for _, devicePlan := range devicePlans {
+		downloader.selectDevice(devicePlan)
+		downloader.copyContentToTempDir(devicePlan.plan)
+	}
+
+	for wpdFile, plannedFile := range plannedFiles {
+		if wpdFile.wasCopied {
+			plannedFile.LocalPath = wpdFile.localPath
+		}
+	}
+
+	downloader.finishImport(devicePlans)
+}
+
+// matchPlannedFiles finds planned files on connected devices. Error lists all missing or changed files
+func (downloader *MtpDownloader) matchPlannedFiles(plan *ImportPlan) ([]*devicePlan, map[*wpdFile]*PlannedFile, error) {
+	filesByDevice := make(map[string][]*PlannedFile)
+	for _, file := range plan.Files {
+		filesByDevice[file.Device] = append(filesByDevice[file.Device], file)
+	}
+
+	devicePlans := make([]*devicePlan, 0)
+	plannedFiles := make(map[*wpdFile]*PlannedFile)
+	problems := make([]string, 0)
+
+	for i := 0; i < downloader.source.GetDeviceCount(); i++ {
+		if !hasPlannedStorages(filesByDevice, buildDeviceKey(downloader.source, i)) {
+			continue
+		}
+
+		downloader.initCurrentDevice(i)
+		if downloader.HasError() {
+			log.Warningf("Unable to read %s: %v", downloader.currentDeviceLabel, downloader.error)
+			downloader.error = nil
+			continue
+		}
+
+		deviceLabel, deviceKey := downloader.currentDeviceLabel, downloader.currentDeviceKey
+		for _, storage := range listDeviceStorages(downloader.currentDevice, deviceLabel, nil) {
+			downloader.selectStorage(storage, deviceLabel, deviceKey)
+			files, planned := filesByDevice[downloader.currentDeviceKey]
+			if !planned {
+				continue
+			}
+			delete(filesByDevice, downloader.currentDeviceKey)
+
+			executionPlan := &ExecutionPlan{files: make([]*wpdFile, 0), wpdRootDir: PathSeparator}
+			for _, file := range files {
+				devicePath := filepath.FromSlash(file.Path)
+				obj := findStorageObject(downloader.currentDevice, storage.Id, devicePath)
+				switch {
+				case obj == nil || obj.IsDir:
+					problems = append(problems, fmt.Sprintf("'%v' was not found on '%v'", file.Path, file.Device))
+				case obj.Size != file.Size || obj.ModTime != file.ModTime:
+					problems = append(problems, fmt.Sprintf("'%v' was changed on '%v'", file.Path, file.Device))
+				default:
+					wpdFile := newWpdFile(filepath.Dir(devicePath), downloader.currentDevice, obj, ScanRules{})
+					executionPlan.AddFile(&wpdFile)
+					plannedFiles[&wpdFile] = file
+				}
+			}
+
+			devicePlans = append(devicePlans, downloader.newDevicePlan(executionPlan))
+		}
+	}
+
+	for device := range filesByDevice {
+		problems = append(problems, fmt.Sprintf("'%v' device or storage is not connected", device))
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			log.Errorf("%v", problem)
+		}
+		return nil, nil, fmt.Errorf("device contents changed since the plan was made: %v problem(s) found", len(problems))
+	}
+	return devicePlans, plannedFiles, nil
+}
+
+// reusePlannedFiles marks files downloaded while the plan was made as copied, so they are not read from devices again
+func reusePlannedFiles(plan *ImportPlan, plannedFiles map[*wpdFile]*PlannedFile) {
+	if plan.TempDir == "" {
+		return
+	}
+
+	reused := 0
+	for wpdFile, plannedFile := range plannedFiles {
+		if plannedFile.TempPath == "" {
+			continue
+		}
+		localPath := filepath.Join(plan.TempDir, filepath.FromSlash(plannedFile.TempPath))
+		info, err := os.Stat(localPath)
+		if err != nil || info.IsDir() || info.Size() != plannedFile.Size {
+			log.Debugf("'%v' copy is missing or incomplete, the file is copied again", localPath)
+			continue
+		}
+
+		wpdFile.wasCopied = true
+		wpdFile.wasVerified = true
+		wpdFile.localPath = localPath
+		reused++
+	}
+	if reused > 0 {
+		log.Infof("%v file(s) downloaded while the plan was made are reused from '%v'", reused, plan.TempDir)
+	}
+}
+
+// hasPlannedStorages checks whether the plan has files of the device or any of its storages
+func hasPlannedStorages(filesByDevice map[string][]*PlannedFile, deviceKey string) bool {
+	for key := range filesByDevice {
+		if key == deviceKey || strings.HasPrefix(key, deviceKey+" [") {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPlannedFiles adds downloaded files to the import plan
+func (downloader *MtpDownloader) recordPlannedFiles(executionPlan *ExecutionPlan) {
+	plan := downloader.options.Plan
+	if plan == nil {
+		return
+	}
+
+	plan.TempDir = downloader.resultDir
+	for _, wpdFile := range executionPlan.files {
+		if !wpdFile.wasCopied {
+			continue
+		}
+		plannedFile := newPlannedFile(downloader.currentDeviceKey, wpdFile)
+		plannedFile.LocalPath = wpdFile.localPath
+		if tempPath, err := filepath.Rel(downloader.resultDir, wpdFile.localPath); err == nil {
+			plannedFile.TempPath = filepath.ToSlash(tempPath)
+		}
+		plan.Files = append(plan.Files, plannedFile)
+	}
+}
+
+func newPlannedFile(deviceKey string, wpdFile *wpdFile) *PlannedFile {
+	return &PlannedFile{
+		Device:  deviceKey,
+		Path:    filepath.ToSlash(wpdFile.filePath),
+		Size:    wpdFile.wpdObject.Size,
+		ModTime: wpdFile.wpdObject.ModTime,
+	}
+}
+
+// discardResultDir removes temp directory if nothing was copied into it yet
+func (downloader *MtpDownloader) discardResultDir() {
+	if downloader.journal != nil {
+		downloader.journal.close()
+		downloader.journal = nil
+	}
+	if err := os.RemoveAll(downloader.resultDir); err != nil {
+		log.Warningf("Unable to remove '%v' temp directory: %v", downloader.resultDir, err)
+	}
+}
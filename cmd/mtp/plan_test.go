@@ -0,0 +1,243 @@
+package mtp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFromAllWpd_RecordsPlan(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010001.LRV", "preview1", testModTime)
+	source := newFakeSource(goPro)
+
+	plan := NewImportPlan("gopro", t.TempDir())
+	resultDir, err := LoadFromAllWpd(source, GoProProfile, plan.TargetDir, ImportOptions{KeepSource: true, Plan: plan})
+
+	r.NoError(err)
+	r.Len(plan.Files, 2)
+	r.Empty(goPro.deleted)
+
+	byPath := make(map[string]*PlannedFile)
+	for _, file := range plan.Files {
+		byPath[file.Path] = file
+	}
+	video := byPath["/DCIM/100GOPRO/GX010001.MP4"]
+	r.NotNil(video)
+	r.Equal("HERO8 Black (GoPro)", video.Device)
+	r.Equal(int64(len("video1")), video.Size)
+	r.Equal(testModTime.Unix(), video.ModTime)
+	r.Equal(filepath.Join(resultDir, "0", "GX010001.MP4"), video.LocalPath)
+}
+
+func TestImportPlan_WriteAndRead(t *testing.T) {
+	r := require.New(t)
+
+	plan := NewImportPlan("gopro", "/video/gopro")
+	plan.Files = append(plan.Files, &PlannedFile{
+		Device:      "HERO8 Black (GoPro)",
+		Path:        "/DCIM/100GOPRO/GX010001.MP4",
+		Size:        6,
+		ModTime:     testModTime.Unix(),
+		Destination: "2024.05.18/src/VID_20240518_103015.MP4",
+		LocalPath:   "/tmp/GX010001.MP4",
+	})
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+
+	r.NoError(plan.Write(planPath))
+	loaded, err := ReadImportPlan(planPath)
+
+	r.NoError(err)
+	r.Equal("gopro", loaded.Profile)
+	r.Equal("/video/gopro", loaded.TargetDir)
+	r.Len(loaded.Files, 1)
+	r.Equal("2024.05.18/src/VID_20240518_103015.MP4", loaded.Files[0].Destination)
+	r.Empty(loaded.Files[0].LocalPath)
+	r.Equal(filepath.Join("/video/gopro", "2024.05.18", "src", "VID_20240518_103015.MP4"), loaded.GetDestinationPath(loaded.Files[0]))
+}
+
+func TestImportPlan_Validate(t *testing.T) {
+	file := func(destination string) *PlannedFile {
+		return &PlannedFile{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/" + destination, Destination: destination}
+	}
+
+	tests := []struct {
+		name  string
+		files []*PlannedFile
+		error string
+	}{
+		{"valid", []*PlannedFile{file("a.MP4"), file("b.MP4")}, ""},
+		{"no destination", []*PlannedFile{file("")}, "files[0]: destination is required"},
+		{"absolute destination", []*PlannedFile{file("/a.MP4")}, "files[0]: destination should be relative to the target dir"},
+		{"outside of target dir", []*PlannedFile{file("a/../../a.MP4")}, "files[0]: destination is outside of the target dir"},
+		{"duplicated destination", []*PlannedFile{file("a.MP4"), file("A.mp4")}, "files[1]: 'A.mp4' destination is already used by files[0]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan := NewImportPlan("gopro", "/video/gopro")
+			plan.Files = test.files
+
+			err := plan.validate()
+
+			if test.error == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.error)
+			}
+		})
+	}
+}
+
+func TestApplyImportPlan_CopiesPlannedFiles(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime)
+	source := newFakeSource(goPro)
+
+	plan := NewImportPlan("gopro", t.TempDir())
+	plan.Files = append(plan.Files, &PlannedFile{
+		Device:      "HERO8 Black (GoPro)",
+		Path:        "/DCIM/100GOPRO/GX010001.MP4",
+		Size:        int64(len("video1")),
+		ModTime:     testModTime.Unix(),
+		Destination: "2024.05.18/VID_20240518_103015.MP4",
+	})
+
+	resultDir, err := ApplyImportPlan(source, GoProProfile, plan, ImportOptions{})
+
+	r.NoError(err)
+	assertFile(t, plan.Files[0].LocalPath, "video1", testModTime)
+	r.Equal(filepath.Join(resultDir, "0", "DCIM", "100GOPRO", "GX010001.MP4"), plan.Files[0].LocalPath)
+	r.Equal([]string{"/DCIM/100GOPRO/GX010001.MP4"}, goPro.deleted)
+	r.True(goPro.hasFile("DCIM/100GOPRO/GX010002.MP4"))
+}
+
+func TestApplyImportPlan_ReusesPlanDownload(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime)
+	source := newFakeSource(goPro)
+
+	plan := NewImportPlan("gopro", t.TempDir())
+	planDir, err := LoadFromAllWpd(source, GoProProfile, plan.TargetDir, ImportOptions{KeepSource: true, Plan: plan})
+	r.NoError(err)
+	r.Equal(planDir, plan.TempDir)
+	for i, file := range plan.Files {
+		file.Destination = fmt.Sprintf("VID_%v.MP4", i)
+	}
+
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	r.NoError(plan.Write(planPath))
+	loaded, err := ReadImportPlan(planPath)
+	r.NoError(err)
+	// the second copy is lost, only it should be read from the device again
+	lost := filepath.Join(planDir, filepath.FromSlash(loaded.Files[1].TempPath))
+	r.NoError(os.Remove(lost))
+
+	_, err = ApplyImportPlan(source, GoProProfile, loaded, ImportOptions{})
+
+	r.NoError(err)
+	r.Equal(filepath.Join(planDir, filepath.FromSlash(loaded.Files[0].TempPath)), loaded.Files[0].LocalPath)
+	r.NotEqual(lost, loaded.Files[1].LocalPath)
+	r.Equal(1, goPro.readCounts[fakeObjectId(loaded.Files[0].Path)])
+	r.Equal(2, goPro.readCounts[fakeObjectId(loaded.Files[1].Path)])
+	r.Len(goPro.deleted, 2)
+}
+
+func TestApplyImportPlan_RefusesChangedDevice(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010002.MP4", "video2", testModTime.Add(time.Hour))
+	source := newFakeSource(goPro)
+
+	targetDir := t.TempDir()
+	plan := NewImportPlan("gopro", targetDir)
+	plan.Files = append(plan.Files,
+		&PlannedFile{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GX010001.MP4", Size: int64(len("video1")), ModTime: testModTime.Unix(), Destination: "1.MP4"},
+		&PlannedFile{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GX010002.MP4", Size: int64(len("video2")), ModTime: testModTime.Unix(), Destination: "2.MP4"},
+		&PlannedFile{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GX010003.MP4", Size: 1, ModTime: testModTime.Unix(), Destination: "3.MP4"},
+	)
+
+	resultDir, err := ApplyImportPlan(source, GoProProfile, plan, ImportOptions{})
+
+	r.EqualError(err, "device contents changed since the plan was made: 2 problem(s) found")
+	r.Empty(resultDir)
+	r.Empty(goPro.deleted)
+	r.Empty(plan.Files[0].LocalPath)
+
+	entries, _ := filepath.Glob(filepath.Join(targetDir, "*"))
+	r.Empty(entries)
+}
+
+func TestApplyImportPlan_RefusesMissingDevice(t *testing.T) {
+	r := require.New(t)
+
+	source := newFakeSource(newFakeDevice("D750", "Nikon DSC"))
+	plan := NewImportPlan("gopro", t.TempDir())
+	plan.Files = append(plan.Files, &PlannedFile{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GX010001.MP4", Size: 1, Destination: "1.MP4"})
+
+	_, err := ApplyImportPlan(source, GoProProfile, plan, ImportOptions{})
+
+	r.EqualError(err, "device contents changed since the plan was made: 1 problem(s) found")
+}
+
+func TestCheckImportPlan(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime)
+	plan := NewImportPlan("gopro", t.TempDir())
+	plan.Files = append(plan.Files, &PlannedFile{Device: "HERO8 Black (GoPro)", Path: "/DCIM/100GOPRO/GX010001.MP4", Size: int64(len("video1")), ModTime: testModTime.Unix(), Destination: "1.MP4"})
+
+	r.NoError(CheckImportPlan(newFakeSource(goPro), GoProProfile, plan))
+	r.Empty(goPro.deleted)
+	r.Zero(goPro.readCounts["/DCIM/100GOPRO/GX010001.MP4"])
+
+	plan.Files[0].Size = 1
+	r.EqualError(CheckImportPlan(newFakeSource(goPro), GoProProfile, plan), "device contents changed since the plan was made: 1 problem(s) found")
+	entries, _ := filepath.Glob(filepath.Join(plan.TargetDir, "*"))
+	r.Empty(entries)
+}
+
+func TestApplyImportPlan_MultipleStorages(t *testing.T) {
+	r := require.New(t)
+
+	camera := newFakeDevice("X-T4", "Fujifilm").
+		withStorages("SD1", "SD2").
+		withFile("SD1/DCIM/100_FUJI/DSCF0001.JPG", "jpeg1", testModTime).
+		withFile("SD2/DCIM/100_FUJI/DSCF0001.JPG", "jpeg2", testModTime)
+
+	plan := NewImportPlan("sdphotos", t.TempDir())
+	_, err := LoadFromAllWpd(newFakeSource(camera), SdPhotosProfile, plan.TargetDir, ImportOptions{KeepSource: true, Plan: plan})
+	r.NoError(err)
+	r.Len(plan.Files, 2)
+	for i, file := range plan.Files {
+		file.Destination = fmt.Sprintf("%v.JPG", i)
+	}
+	plan.TargetDir = t.TempDir()
+	// files are copied again to check storage dirs of the apply temp dir
+	plan.TempDir = ""
+
+	resultDir, err := ApplyImportPlan(newFakeSource(camera), SdPhotosProfile, plan, ImportOptions{})
+
+	r.NoError(err)
+	r.ElementsMatch([]string{"X-T4 (Fujifilm) [SD1]", "X-T4 (Fujifilm) [SD2]"}, []string{plan.Files[0].Device, plan.Files[1].Device})
+	for _, file := range plan.Files {
+		r.FileExists(file.LocalPath)
+	}
+	r.FileExists(filepath.Join(resultDir, "0", "SD2", "DCIM", "100_FUJI", "DSCF0001.JPG"))
+	r.ElementsMatch([]string{"/SD1/DCIM/100_FUJI/DSCF0001.JPG", "/SD2/DCIM/100_FUJI/DSCF0001.JPG"}, camera.deleted)
+}
@@ -0,0 +1,18 @@
+package mtp
+
+// DeviceProfile describes devices to import from and device dirs to be copied
+type DeviceProfile struct {
+	Name         string
+	DeviceFilter MtpDeviceFilter
+	DeviceDirs   []string
+	// KeepSource disables deletion of copied files from the device
+	KeepSource bool
+	// Scan selects ignored and junk device files
+	Scan ScanRules
+	// Storages selects device storages by name or index. All storages are imported if it is empty
+	Storages []string
+}
+
+var GoProProfile = DeviceProfile{Name: "gopro", DeviceFilter: GoProFilter, DeviceDirs: []string{GOPRO_DIR}, Scan: defaultScanRules}
+var CamVideoProfile = DeviceProfile{Name: "camvideo", DeviceFilter: CamFilter, DeviceDirs: []string{CAM_FILES_DIR}, Scan: defaultScanRules}
+var SdPhotosProfile = DeviceProfile{Name: "sdphotos", DeviceFilter: SdPhotosFilter, DeviceDirs: []string{DCIM_DIR}, Scan: defaultScanRules}
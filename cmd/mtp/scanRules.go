@@ -0,0 +1,115 @@
+package mtp
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// regexpRulePrefix marks file rules which are regular expressions
+const regexpRulePrefix = "re:"
+
+// DefaultIgnorePatterns are system dirs which are never imported
+var DefaultIgnorePatterns = []string{"System Volume Information", "$RECYCLE.BIN"}
+
+var defaultScanRules = mustParseScanRules(DefaultIgnorePatterns, nil)
+
+// FileRule matches device files and dirs. Pattern is either a name ('leinfo.sav'), a glob of the path relative
+// to the device root ('*.THM', 'DCIM/**/*.LRV') or a regexp of the name with 're:' prefix ('re:^\..+').
+// Names and globs are case insensitive
+type FileRule struct {
+	pattern string
+	regex   *regexp.Regexp
+}
+
+// ScanRules select device objects while device dirs are scanned
+type ScanRules struct {
+	// Ignore objects are not scanned, copied or deleted
+	Ignore []FileRule
+	// Junk files are deleted from the device but never copied
+	Junk []FileRule
+}
+
+// ParseFileRule parses file rule pattern
+func ParseFileRule(pattern string) (FileRule, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return FileRule{}, fmt.Errorf("empty pattern")
+	}
+
+	if expression, isRegexp := strings.CutPrefix(pattern, regexpRulePrefix); isRegexp {
+		regex, err := regexp.Compile(expression)
+		if err != nil {
+			return FileRule{}, fmt.Errorf("invalid '%s' regexp: %w", expression, err)
+		}
+		return FileRule{pattern: pattern, regex: regex}, nil
+	}
+
+	if _, err := path.Match(strings.ToLower(filepath.ToSlash(pattern)), ""); err != nil {
+		return FileRule{}, fmt.Errorf("invalid '%s' glob: %w", pattern, err)
+	}
+	return FileRule{pattern: pattern}, nil
+}
+
+// ParseScanRules parses ignore and junk patterns
+func ParseScanRules(ignore []string, junk []string) (ScanRules, error) {
+	result := ScanRules{}
+	for i, pattern := range ignore {
+		rule, err := ParseFileRule(pattern)
+		if err != nil {
+			return ScanRules{}, fmt.Errorf("ignore[%v]: %w", i, err)
+		}
+		result.Ignore = append(result.Ignore, rule)
+	}
+	for i, pattern := range junk {
+		rule, err := ParseFileRule(pattern)
+		if err != nil {
+			return ScanRules{}, fmt.Errorf("junk[%v]: %w", i, err)
+		}
+		result.Junk = append(result.Junk, rule)
+	}
+	return result, nil
+}
+
+func mustParseScanRules(ignore []string, junk []string) ScanRules {
+	result, err := ParseScanRules(ignore, junk)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func (rule FileRule) String() string {
+	return rule.pattern
+}
+
+// match checks slash separated device path relative to the device root
+func (rule FileRule) match(devicePath string) bool {
+	if rule.regex != nil {
+		return rule.regex.MatchString(path.Base(devicePath))
+	}
+	return matchGlob(rule.pattern, devicePath)
+}
+
+func (rules ScanRules) isIgnored(devicePath string) bool {
+	return matchAnyRule(rules.Ignore, devicePath)
+}
+
+func (rules ScanRules) isJunk(devicePath string) bool {
+	return matchAnyRule(rules.Junk, devicePath)
+}
+
+func matchAnyRule(rules []FileRule, devicePath string) bool {
+	for _, rule := range rules {
+		if rule.match(devicePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// toRulePath converts device path into slash separated path relative to the device root
+func toRulePath(devicePath string) string {
+	return strings.TrimPrefix(filepath.ToSlash(devicePath), "/")
+}
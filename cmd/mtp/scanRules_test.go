@@ -0,0 +1,87 @@
+package mtp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRule_Match(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		devicePath string
+		want       bool
+	}{
+		{"leinfo.sav", "DCIM/100GOPRO/leinfo.sav", true},
+		{"LEINFO.SAV", "DCIM/100GOPRO/leinfo.sav", true},
+		{"leinfo.sav", "DCIM/100GOPRO/leinfo.sav.bak", false},
+		{"*.THM", "DCIM/100GOPRO/GX010001.THM", true},
+		{"*.THM", "DCIM/100GOPRO/GX010001.MP4", false},
+		{"DCIM/**/*.lrv", "DCIM/100GOPRO/GX010001.LRV", true},
+		{"MISC/**", "DCIM/MISC/x.dat", false},
+		{`re:^\..+`, "DCIM/.Trashes", true},
+		{`re:^\..+`, "DCIM/100GOPRO/GX010001.MP4", false},
+		{"System Volume Information", "System Volume Information", true},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.devicePath, func(t *testing.T) {
+			rule, err := ParseFileRule(test.pattern)
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, rule.match(test.devicePath))
+		})
+	}
+}
+
+func TestParseScanRules_Errors(t *testing.T) {
+	_, err := ParseScanRules([]string{"*.THM", ""}, nil)
+	assert.EqualError(t, err, "ignore[1]: empty pattern")
+
+	_, err = ParseScanRules(nil, []string{"re:("})
+	assert.ErrorContains(t, err, "junk[0]: invalid '(' regexp")
+
+	_, err = ParseScanRules(nil, []string{"[a-"})
+	assert.ErrorContains(t, err, "junk[0]: invalid '[a-' glob")
+}
+
+func TestLoadFromAllWpd_ScanRules(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.MP4", "video1", testModTime).
+		withFile("DCIM/100GOPRO/GX010001.THM", "thumb", testModTime).
+		withFile("DCIM/100GOPRO/leinfo.sav", "info", testModTime).
+		withFile("DCIM/100GOPRO/.hidden/data.bin", "hidden", testModTime)
+	source := newFakeSource(goPro)
+
+	profile := GoProProfile
+	profile.Scan = mustParseScanRules([]string{`re:^\.`}, []string{"*.THM", "leinfo.sav"})
+	resultDir, err := LoadFromAllWpd(source, profile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "0", "GX010001.MP4"), "video1", testModTime)
+	r.NoFileExists(filepath.Join(resultDir, "0", "GX010001.THM"))
+	r.NoFileExists(filepath.Join(resultDir, "0", "leinfo.sav"))
+	r.NoDirExists(filepath.Join(resultDir, "0", ".hidden"))
+	r.Zero(goPro.readCounts["/DCIM/100GOPRO/GX010001.THM"])
+
+	r.ElementsMatch([]string{"/DCIM/100GOPRO/GX010001.MP4", "/DCIM/100GOPRO/GX010001.THM", "/DCIM/100GOPRO/leinfo.sav"}, goPro.deleted)
+	r.True(goPro.hasFile("DCIM/100GOPRO/.hidden/data.bin"))
+}
+
+func TestLoadFromAllWpd_KeepSourceKeepsJunk(t *testing.T) {
+	r := require.New(t)
+
+	goPro := newFakeDevice("HERO8 Black", "GoPro").
+		withFile("DCIM/100GOPRO/GX010001.THM", "thumb", testModTime)
+	source := newFakeSource(goPro)
+
+	profile := GoProProfile
+	profile.Scan = mustParseScanRules(nil, []string{"*.THM"})
+	_, err := LoadFromAllWpd(source, profile, t.TempDir(), ImportOptions{KeepSource: true})
+
+	r.NoError(err)
+	r.Empty(goPro.deleted)
+}
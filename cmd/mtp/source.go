@@ -0,0 +1,100 @@
+package mtp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	SourceAuto = "auto"
+	SourceWpd  = "wpd"
+	SourceFs   = "fs"
+)
+
+// PathSeparator is used to build device paths. Matches gowpd.PathSeparator
+var PathSeparator = string(os.PathSeparator)
+
+// Object describes single file or directory exposed by a device
+type Object struct {
+	Id      string
+	Name    string
+	Size    int64
+	ModTime int64
+	IsDir   bool
+}
+
+// DeviceInfo describes device properties available for filters. Empty values mean the source does not provide them
+type DeviceInfo struct {
+	Id           int
+	Label        string
+	Name         string
+	Description  string
+	Manufacturer string
+	SerialNumber string
+	// Capacity is total storage capacity in bytes, 0 if unknown
+	Capacity int64
+}
+
+// Storage is a device storage (internal memory, SD card, mounted volume etc.)
+type Storage struct {
+	Id   string
+	Name string
+	// FreeSpace and Capacity are in bytes, 0 if unknown
+	FreeSpace int64
+	Capacity  int64
+}
+
+// Device is a single connected device (camera, card reader, mounted volume etc.)
+type Device interface {
+	GetStorages() ([]Storage, error)
+	FindObject(path string) *Object
+	GetChildObjects(id string) ([]*Object, error)
+	GetReader(id string) (io.ReadCloser, error)
+	Delete(id string) error
+}
+
+// Source enumerates devices of a certain kind (WPD, mounted file systems etc.)
+type Source interface {
+	Init() error
+	Destroy()
+	GetName() string
+	GetDeviceCount() int
+	GetDeviceName(id int) string
+	GetDeviceDescription(id int) string
+	GetDeviceInfo(id int) DeviceInfo
+	ChooseDevice(id int) (Device, error)
+}
+
+// NewSource creates a media source by type. "auto" means WPD on Windows and mounted file systems on other platforms
+func NewSource(sourceType string, mountDirs []string) (Source, error) {
+	switch strings.ToLower(sourceType) {
+	case "", SourceAuto:
+		if wpdSupported {
+			return newWpdSource(), nil
+		}
+		return newFsSource(mountDirs), nil
+	case SourceWpd:
+		if !wpdSupported {
+			return nil, fmt.Errorf("'%s' source is not supported on this platform", SourceWpd)
+		}
+		return newWpdSource(), nil
+	case SourceFs:
+		return newFsSource(mountDirs), nil
+	}
+	return nil, fmt.Errorf("unknown '%s' source type", sourceType)
+}
+
+func setFileTime(path string, modTime int64) error {
+	tm := time.Unix(modTime, 0)
+	return os.Chtimes(path, tm, tm)
+}
+
+func cleanDevicePath(path string) string {
+	if PathSeparator != "/" {
+		path = strings.ReplaceAll(path, "/", PathSeparator)
+	}
+	return strings.Trim(path, PathSeparator)
+}
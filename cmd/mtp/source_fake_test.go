@@ -0,0 +1,293 @@
+package mtp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"testing/iotest"
+	"time"
+)
+
+// fakeSource is in-memory Source implementation for tests
+type fakeSource struct {
+	devices   []*fakeDevice
+	initError error
+	destroyed bool
+}
+
+// fakeDevice is in-memory Device with configurable files tree and injectable errors
+type fakeDevice struct {
+	name         string
+	description  string
+	manufacturer string
+	serialNumber string
+	capacity     int64
+	chooseError  error
+	objects      map[string]*fakeObject
+	readErrors   map[string]error
+	deleteErrors map[string]error
+	firstReads   map[string][]byte
+	brokenReads  map[string]int
+	storages     []string
+	readCounts   map[string]int
+	deleted      []string
+}
+
+type fakeObject struct {
+	Object
+	content  []byte
+	children []string
+}
+
+func newFakeSource(devices ...*fakeDevice) *fakeSource {
+	return &fakeSource{devices: devices}
+}
+
+func (source *fakeSource) Init() error {
+	return source.initError
+}
+
+func (source *fakeSource) Destroy() {
+	source.destroyed = true
+}
+
+func (source *fakeSource) GetName() string {
+	return "FAKE"
+}
+
+func (source *fakeSource) GetDeviceCount() int {
+	return len(source.devices)
+}
+
+func (source *fakeSource) GetDeviceName(id int) string {
+	return source.devices[id].name
+}
+
+func (source *fakeSource) GetDeviceDescription(id int) string {
+	return source.devices[id].description
+}
+
+func (source *fakeSource) GetDeviceInfo(id int) DeviceInfo {
+	device := source.devices[id]
+	return DeviceInfo{
+		Id:           id,
+		Name:         device.name,
+		Description:  device.description,
+		Manufacturer: device.manufacturer,
+		SerialNumber: device.serialNumber,
+		Capacity:     device.capacity,
+	}
+}
+
+func (source *fakeSource) ChooseDevice(id int) (Device, error) {
+	device := source.devices[id]
+	if device.chooseError != nil {
+		return nil, device.chooseError
+	}
+	return device, nil
+}
+
+func newFakeDevice(name string, description string) *fakeDevice {
+	root := &fakeObject{Object: Object{Id: "", IsDir: true}}
+	return &fakeDevice{
+		name:         name,
+		description:  description,
+		objects:      map[string]*fakeObject{"": root},
+		readErrors:   make(map[string]error),
+		deleteErrors: make(map[string]error),
+		firstReads:   make(map[string][]byte),
+		brokenReads:  make(map[string]int),
+		readCounts:   make(map[string]int),
+		deleted:      make([]string, 0),
+	}
+}
+
+// withFile adds a file (and all missing parent dirs). filePath uses '/' separator
+func (dev *fakeDevice) withFile(filePath string, content string, modTime time.Time) *fakeDevice {
+	id := fakeObjectId(filePath)
+	dev.ensureDir(path.Dir(id))
+
+	obj := &fakeObject{
+		Object: Object{
+			Id:      id,
+			Name:    path.Base(id),
+			Size:    int64(len(content)),
+			ModTime: modTime.Unix(),
+		},
+		content: []byte(content),
+	}
+	dev.addObject(obj)
+	return dev
+}
+
+// withDir adds an empty dir (and all missing parent dirs). dirPath uses '/' separator
+func (dev *fakeDevice) withDir(dirPath string) *fakeDevice {
+	dev.ensureDir(fakeObjectId(dirPath))
+	return dev
+}
+
+func (dev *fakeDevice) withInfo(manufacturer string, serialNumber string, capacity int64) *fakeDevice {
+	dev.manufacturer = manufacturer
+	dev.serialNumber = serialNumber
+	dev.capacity = capacity
+	return dev
+}
+
+func (dev *fakeDevice) withReadError(filePath string, err error) *fakeDevice {
+	dev.readErrors[fakeObjectId(filePath)] = err
+	return dev
+}
+
+func (dev *fakeDevice) withDeleteError(filePath string, err error) *fakeDevice {
+	dev.deleteErrors[fakeObjectId(filePath)] = err
+	return dev
+}
+
+// withSize overrides file size reported by the device
+func (dev *fakeDevice) withSize(filePath string, size int64) *fakeDevice {
+	dev.objects[fakeObjectId(filePath)].Size = size
+	return dev
+}
+
+// withFirstRead makes the first read of a file return different (e.g. corrupted) content
+func (dev *fakeDevice) withFirstRead(filePath string, content string) *fakeDevice {
+	dev.firstReads[fakeObjectId(filePath)] = []byte(content)
+	return dev
+}
+
+// withBrokenReads makes the first count reads of a file fail in the middle of the content
+func (dev *fakeDevice) withBrokenReads(filePath string, count int) *fakeDevice {
+	dev.brokenReads[fakeObjectId(filePath)] = count
+	return dev
+}
+
+func (dev *fakeDevice) hasFile(filePath string) bool {
+	_, exists := dev.objects[fakeObjectId(filePath)]
+	return exists
+}
+
+func (dev *fakeDevice) ensureDir(id string) {
+	if id == "/" || id == "." || id == "" {
+		return
+	}
+	if _, exists := dev.objects[id]; exists {
+		return
+	}
+	dev.ensureDir(path.Dir(id))
+	dev.addObject(&fakeObject{Object: Object{Id: id, Name: path.Base(id), IsDir: true}})
+}
+
+func (dev *fakeDevice) addObject(obj *fakeObject) {
+	dev.objects[obj.Id] = obj
+	parent := dev.objects[fakeParentId(obj.Id)]
+	parent.children = append(parent.children, obj.Id)
+	sort.Strings(parent.children)
+}
+
+// withStorages makes top-level dirs storages of the device, e.g. files of 'SD1' storage are added as 'SD1/DCIM/...'
+func (dev *fakeDevice) withStorages(names ...string) *fakeDevice {
+	for _, name := range names {
+		dev.ensureDir(fakeObjectId(name))
+	}
+	dev.storages = names
+	return dev
+}
+
+func (dev *fakeDevice) GetStorages() ([]Storage, error) {
+	if len(dev.storages) == 0 {
+		return []Storage{{Id: "", Name: "Internal", FreeSpace: dev.capacity / 2, Capacity: dev.capacity}}, nil
+	}
+
+	result := make([]Storage, 0, len(dev.storages))
+	for _, name := range dev.storages {
+		result = append(result, Storage{Id: fakeObjectId(name), Name: name})
+	}
+	return result, nil
+}
+
+func (dev *fakeDevice) FindObject(objPath string) *Object {
+	obj, exists := dev.objects[fakeObjectId(objPath)]
+	if !exists {
+		return nil
+	}
+	result := obj.Object
+	return &result
+}
+
+func (dev *fakeDevice) GetChildObjects(id string) ([]*Object, error) {
+	obj, exists := dev.objects[id]
+	if !exists {
+		return nil, fmt.Errorf("'%v' was not found", id)
+	}
+
+	result := make([]*Object, 0, len(obj.children))
+	for _, childId := range obj.children {
+		child := dev.objects[childId].Object
+		result = append(result, &child)
+	}
+	return result, nil
+}
+
+func (dev *fakeDevice) GetReader(id string) (io.ReadCloser, error) {
+	if err, exists := dev.readErrors[id]; exists {
+		return nil, err
+	}
+	obj, exists := dev.objects[id]
+	if !exists {
+		return nil, fmt.Errorf("'%v' was not found", id)
+	}
+
+	dev.readCounts[id]++
+	if dev.readCounts[id] <= dev.brokenReads[id] {
+		content := obj.content[:len(obj.content)/2]
+		return io.NopCloser(io.MultiReader(bytes.NewReader(content), iotest.ErrReader(errors.New("connection reset")))), nil
+	}
+	if content, exists := dev.firstReads[id]; exists && dev.readCounts[id] == 1 {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	return io.NopCloser(bytes.NewReader(obj.content)), nil
+}
+
+func (dev *fakeDevice) Delete(id string) error {
+	if err, exists := dev.deleteErrors[id]; exists {
+		return err
+	}
+	if _, exists := dev.objects[id]; !exists {
+		return fmt.Errorf("'%v' was not found", id)
+	}
+
+	delete(dev.objects, id)
+	parent := dev.objects[fakeParentId(id)]
+	children := make([]string, 0, len(parent.children))
+	for _, childId := range parent.children {
+		if childId != id {
+			children = append(children, childId)
+		}
+	}
+	parent.children = children
+
+	dev.deleted = append(dev.deleted, id)
+	return nil
+}
+
+// fakeObjectId converts device path with any separator into '/DIR/FILE' form
+func fakeObjectId(objPath string) string {
+	objPath = strings.ReplaceAll(objPath, "\\", "/")
+	objPath = strings.Trim(objPath, "/")
+	if objPath == "" {
+		return ""
+	}
+	return "/" + objPath
+}
+
+func fakeParentId(id string) string {
+	parent := path.Dir(id)
+	if parent == "/" {
+		return ""
+	}
+	return parent
+}
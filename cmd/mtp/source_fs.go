@@ -0,0 +1,155 @@
+package mtp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// DefaultMountDirs are glob patterns of typical removable volume locations
+var DefaultMountDirs = []string{"/media/*/*", "/run/media/*/*", "/Volumes/*"}
+
+// fsSource exposes mounted file systems (card readers, USB mass storage cameras etc.) as devices
+type fsSource struct {
+	mountDirs []string
+	volumes   []string
+}
+
+type fsDevice struct {
+	rootDir string
+}
+
+func newFsSource(mountDirs []string) Source {
+	if len(mountDirs) == 0 {
+		mountDirs = DefaultMountDirs
+	}
+	return &fsSource{mountDirs: mountDirs}
+}
+
+func (source *fsSource) Init() error {
+	source.volumes = make([]string, 0)
+	known := make(map[string]bool)
+
+	for _, pattern := range source.mountDirs {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid '%s' mount dir pattern: %w", pattern, err)
+		}
+		for _, match := range matches {
+			if known[match] {
+				continue
+			}
+			if stat, err := os.Stat(match); err != nil || !stat.IsDir() {
+				continue
+			}
+			known[match] = true
+			source.volumes = append(source.volumes, match)
+		}
+	}
+	log.Debugf("Found %v mounted volume(s): %v", len(source.volumes), source.volumes)
+	return nil
+}
+
+func (source *fsSource) Destroy() {
+	source.volumes = nil
+}
+
+func (source *fsSource) GetName() string {
+	return "FS"
+}
+
+// UnsupportedProperties returns device properties which are not known for mounted volumes
+func (source *fsSource) UnsupportedProperties() []string {
+	return []string{"manufacturer", "serial"}
+}
+
+func (source *fsSource) GetDeviceCount() int {
+	return len(source.volumes)
+}
+
+func (source *fsSource) GetDeviceName(id int) string {
+	volume := source.volumes[id]
+	name := filepath.Base(volume)
+	if name == PathSeparator || name == "." {
+		return volume
+	}
+	return name
+}
+
+func (source *fsSource) GetDeviceDescription(id int) string {
+	return source.volumes[id]
+}
+
+func (source *fsSource) GetDeviceInfo(id int) DeviceInfo {
+	info := DeviceInfo{Id: id, Name: source.GetDeviceName(id), Description: source.GetDeviceDescription(id)}
+	if _, total, err := GetDiskSpace(source.volumes[id]); err == nil {
+		info.Capacity = total
+	} else {
+		log.Debugf("Unable to read '%v' capacity: %v", source.volumes[id], err)
+	}
+	return info
+}
+
+func (source *fsSource) ChooseDevice(id int) (Device, error) {
+	if id < 0 || id >= len(source.volumes) {
+		return nil, fmt.Errorf("unknown #%v volume", id)
+	}
+	return &fsDevice{rootDir: source.volumes[id]}, nil
+}
+
+// GetStorages returns mounted volume as the only storage
+func (dev *fsDevice) GetStorages() ([]Storage, error) {
+	storage := Storage{Id: dev.rootDir, Name: filepath.Base(dev.rootDir)}
+	free, total, err := GetDiskSpace(dev.rootDir)
+	if err != nil {
+		log.Debugf("Unable to read '%v' disk space: %v", dev.rootDir, err)
+	}
+	storage.FreeSpace, storage.Capacity = free, total
+	return []Storage{storage}, nil
+}
+
+func (dev *fsDevice) FindObject(path string) *Object {
+	objPath := filepath.Join(dev.rootDir, cleanDevicePath(path))
+	stat, err := os.Stat(objPath)
+	if err != nil {
+		return nil
+	}
+	return fileInfoToObject(objPath, stat)
+}
+
+func (dev *fsDevice) GetChildObjects(id string) ([]*Object, error) {
+	entries, err := os.ReadDir(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Object, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			log.Debugf("Unable to read '%v' info: %v", entry.Name(), err)
+			continue
+		}
+		result = append(result, fileInfoToObject(filepath.Join(id, entry.Name()), info))
+	}
+	return result, nil
+}
+
+func (dev *fsDevice) GetReader(id string) (io.ReadCloser, error) {
+	return os.Open(id)
+}
+
+func (dev *fsDevice) Delete(id string) error {
+	return os.Remove(id)
+}
+
+func fileInfoToObject(path string, info os.FileInfo) *Object {
+	return &Object{
+		Id:      path,
+		Name:    info.Name(),
+		Size:    info.Size(),
+		ModTime: info.ModTime().Unix(),
+		IsDir:   info.IsDir(),
+	}
+}
@@ -0,0 +1,133 @@
+package mtp
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFsSource_Volumes(t *testing.T) {
+	r := require.New(t)
+
+	mountDir := t.TempDir()
+	createFsFile(t, mountDir, "NIKON/DCIM/100NIKON/DSC_0001.NEF", "raw")
+	createFsFile(t, mountDir, "HERO8/DCIM/100GOPRO/GX010001.MP4", "video")
+	createFsFile(t, mountDir, "not_a_volume.txt", "text")
+
+	sut := newFsSource([]string{filepath.Join(mountDir, "*")})
+	r.NoError(sut.Init())
+	defer sut.Destroy()
+
+	r.Equal("FS", sut.GetName())
+	r.Equal(2, sut.GetDeviceCount())
+	r.Equal("HERO8", sut.GetDeviceName(0))
+	r.Equal(filepath.Join(mountDir, "HERO8"), sut.GetDeviceDescription(0))
+	r.Equal("NIKON", sut.GetDeviceName(1))
+
+	info := sut.GetDeviceInfo(0)
+	r.Equal("HERO8", info.Name)
+	r.Greater(info.Capacity, int64(0))
+
+	_, err := sut.ChooseDevice(2)
+	r.Error(err)
+}
+
+func TestFsSource_InvalidPattern(t *testing.T) {
+	r := require.New(t)
+
+	sut := newFsSource([]string{"["})
+
+	r.Error(sut.Init())
+}
+
+func TestFsDevice_Objects(t *testing.T) {
+	r := require.New(t)
+
+	mountDir := t.TempDir()
+	createFsFile(t, mountDir, "NIKON/DCIM/100NIKON/DSC_0001.NEF", "raw")
+
+	sut := &fsDevice{rootDir: filepath.Join(mountDir, "NIKON")}
+
+	r.Nil(sut.FindObject(PathSeparator + "MISSING"))
+
+	dir := sut.FindObject(PathSeparator + filepath.Join("DCIM", "100NIKON"))
+	r.NotNil(dir)
+	r.True(dir.IsDir)
+	r.Equal("100NIKON", dir.Name)
+
+	children, err := sut.GetChildObjects(dir.Id)
+	r.NoError(err)
+	r.Len(children, 1)
+	r.Equal("DSC_0001.NEF", children[0].Name)
+	r.Equal(int64(3), children[0].Size)
+	r.False(children[0].IsDir)
+
+	reader, err := sut.GetReader(children[0].Id)
+	r.NoError(err)
+	content, err := io.ReadAll(reader)
+	r.NoError(err)
+	r.NoError(reader.Close())
+	r.Equal("raw", string(content))
+
+	r.NoError(sut.Delete(children[0].Id))
+	r.Nil(sut.FindObject(PathSeparator + filepath.Join("DCIM", "100NIKON", "DSC_0001.NEF")))
+}
+
+func TestFsDevice_Filters(t *testing.T) {
+	r := require.New(t)
+
+	mountDir := t.TempDir()
+	createFsFile(t, mountDir, "NIKON/DCIM/100NIKON/DSC_0001.NEF", "raw")
+	createFsFile(t, mountDir, "SD/DCIM/100GOPRO/GX010001.MP4", "video")
+	createFsFile(t, mountDir, "CAM_SD/PRIVATE/AVCHD/BDMV/STREAM/00001.MTS", "video")
+
+	source := newFsSource([]string{filepath.Join(mountDir, "*")})
+	r.NoError(source.Init())
+
+	accepted := func(filter MtpDeviceFilter, id int) bool {
+		device, err := source.ChooseDevice(id)
+		r.NoError(err)
+		info := source.GetDeviceInfo(id)
+		info.Label = info.Name
+		return filter.accept(device, info)
+	}
+
+	// CAM_SD
+	r.True(accepted(CamFilter, 0))
+	r.False(accepted(GoProFilter, 0))
+	r.False(accepted(SdPhotosFilter, 0))
+
+	// NIKON
+	r.False(accepted(CamFilter, 1))
+	r.False(accepted(GoProFilter, 1))
+	r.True(accepted(SdPhotosFilter, 1))
+
+	// SD
+	r.False(accepted(CamFilter, 2))
+	r.True(accepted(GoProFilter, 2))
+	r.False(accepted(SdPhotosFilter, 2))
+}
+
+func TestNewSource(t *testing.T) {
+	r := require.New(t)
+
+	source, err := NewSource(SourceFs, nil)
+	r.NoError(err)
+	r.Equal("FS", source.GetName())
+
+	source, err = NewSource(SourceAuto, nil)
+	r.NoError(err)
+	r.NotNil(source)
+
+	_, err = NewSource("unknown", nil)
+	r.Error(err)
+}
+
+func createFsFile(t *testing.T, baseDir string, relPath string, content string) {
+	filePath := filepath.Join(baseDir, filepath.FromSlash(relPath))
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package mtp
+
+const wpdSupported = false
+
+func newWpdSource() Source {
+	return nil
+}
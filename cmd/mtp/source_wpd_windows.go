@@ -0,0 +1,121 @@
+//go:build windows
+// +build windows
+
+package mtp
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/tobwithu/gowpd"
+)
+
+const wpdSupported = true
+
+// wpdSource exposes Windows Portable Devices (MTP cameras, phones etc.)
+type wpdSource struct {
+}
+
+type wpdDevice struct {
+	device *gowpd.Device
+}
+
+func newWpdSource() Source {
+	return &wpdSource{}
+}
+
+func (source *wpdSource) Init() error {
+	return gowpd.Init()
+}
+
+func (source *wpdSource) Destroy() {
+	gowpd.Destroy()
+}
+
+func (source *wpdSource) GetName() string {
+	return "MTP"
+}
+
+// UnsupportedProperties returns device properties which are not exposed by gowpd
+func (source *wpdSource) UnsupportedProperties() []string {
+	return []string{"serial", "capacity"}
+}
+
+func (source *wpdSource) GetDeviceCount() int {
+	return gowpd.GetDeviceCount()
+}
+
+func (source *wpdSource) GetDeviceName(id int) string {
+	return gowpd.GetDeviceName(id)
+}
+
+func (source *wpdSource) GetDeviceDescription(id int) string {
+	return gowpd.GetDeviceDescription(id)
+}
+
+// GetDeviceInfo returns device name, description and manufacturer. Serial number and capacity are not exposed by gowpd
+func (source *wpdSource) GetDeviceInfo(id int) DeviceInfo {
+	return DeviceInfo{
+		Id:           id,
+		Name:         gowpd.GetDeviceName(id),
+		Description:  gowpd.GetDeviceDescription(id),
+		Manufacturer: gowpd.GetDeviceManufacturer(id),
+	}
+}
+
+func (source *wpdSource) ChooseDevice(id int) (Device, error) {
+	device, err := gowpd.ChooseDevice(id)
+	if err != nil {
+		return nil, err
+	}
+	return &wpdDevice{device: device}, nil
+}
+
+// GetStorages returns functional objects of the device. Free space and capacity are not exposed by gowpd
+func (dev *wpdDevice) GetStorages() ([]Storage, error) {
+	objs, err := dev.device.GetChildObjects(gowpd.WPD_DEVICE_OBJECT_ID)
+	result := make([]Storage, 0, len(objs))
+	for i, obj := range objs {
+		name := obj.Name
+		if name == "" {
+			name = fmt.Sprintf("Storage #%v", i)
+		}
+		result = append(result, Storage{Id: obj.Id, Name: name})
+	}
+	return result, err
+}
+
+func (dev *wpdDevice) FindObject(path string) *Object {
+	obj := dev.device.FindObject(path)
+	if obj == nil {
+		return nil
+	}
+	return toObject(obj)
+}
+
+func (dev *wpdDevice) GetChildObjects(id string) ([]*Object, error) {
+	objs, err := dev.device.GetChildObjects(id)
+	result := make([]*Object, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, toObject(obj))
+	}
+	return result, err
+}
+
+func (dev *wpdDevice) GetReader(id string) (io.ReadCloser, error) {
+	return dev.device.GetReader(id)
+}
+
+func (dev *wpdDevice) Delete(id string) error {
+	return dev.device.Delete(id)
+}
+
+func toObject(obj *gowpd.Object) *Object {
+	return &Object{
+		Id:      obj.Id,
+		Name:    obj.Name,
+		Size:    obj.Size,
+		ModTime: obj.ModTime,
+		IsDir:   obj.IsDir,
+	}
+}
@@ -0,0 +1,128 @@
+package mtp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// deviceStorage is a device storage selected for import
+type deviceStorage struct {
+	Storage
+	index int
+	// dir is temp subdirectory of the storage. It is empty for devices with a single storage
+	dir string
+}
+
+// listDeviceStorages returns device storages selected by label or index. Storage of devices which do not
+// expose storages has empty id, such storage is looked up from the device root
+func listDeviceStorages(dev Device, deviceLabel string, selectors []string) []deviceStorage {
+	storages, err := dev.GetStorages()
+	if err != nil {
+		log.Warningf("Unable to read %s storages: %v", deviceLabel, err)
+	}
+	if len(storages) == 0 {
+		return []deviceStorage{{}}
+	}
+
+	result := make([]deviceStorage, 0, len(storages))
+	for i, storage := range storages {
+		item := deviceStorage{Storage: storage, index: i}
+		if len(storages) > 1 {
+			item.dir = buildStorageDir(storage, i)
+		}
+
+		if !item.isSelected(selectors) {
+			log.Infof("Skipping '%v' storage of %s", storage.Name, deviceLabel)
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// isSelected checks whether storage label or index is one of selectors. Empty selectors select all storages
+func (storage deviceStorage) isSelected(selectors []string) bool {
+	if len(selectors) == 0 {
+		return true
+	}
+	for _, selector := range selectors {
+		selector = strings.TrimSpace(selector)
+		if selector == strconv.Itoa(storage.index) || strings.EqualFold(selector, storage.Name) || strings.EqualFold(selector, storage.dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildStorageDir converts storage name into a temp subdirectory name
+func buildStorageDir(storage Storage, index int) string {
+	dir := strings.Map(func(char rune) rune {
+		if strings.ContainsRune(`\/:*?"<>|`, char) || char < ' ' {
+			return '_'
+		}
+		return char
+	}, strings.TrimSpace(storage.Name))
+	dir = strings.Trim(dir, ". ")
+	if dir == "" {
+		dir = fmt.Sprintf("storage%v", index)
+	}
+	return dir
+}
+
+// buildStorageKey returns storage identifier used by import journal and ledger. It is the device key for single storage devices
+func buildStorageKey(deviceKey string, storageDir string) string {
+	if storageDir == "" {
+		return deviceKey
+	}
+	return fmt.Sprintf("%v [%v]", deviceKey, storageDir)
+}
+
+func buildStorageLabel(deviceLabel string, storageDir string) string {
+	if storageDir == "" {
+		return deviceLabel
+	}
+	return fmt.Sprintf("%v [%v]", deviceLabel, storageDir)
+}
+
+// findStorageObject finds object by path relative to the storage root
+func findStorageObject(dev Device, storageId string, objPath string) *Object {
+	if storageId == "" {
+		return dev.FindObject(objPath)
+	}
+
+	var result *Object
+	parentId := storageId
+	for _, name := range strings.Split(strings.Trim(filepath.ToSlash(objPath), "/"), "/") {
+		if name == "" {
+			continue
+		}
+
+		children, err := dev.GetChildObjects(parentId)
+		if err != nil {
+			log.Debugf("Unable to read '%v' children: %v", parentId, err)
+			return nil
+		}
+		result = findChild(children, name)
+		if result == nil {
+			return nil
+		}
+		parentId = result.Id
+	}
+	return result
+}
+
+func findChild(children []*Object, name string) *Object {
+	for _, child := range children {
+		if child.Name == name {
+			return child
+		}
+	}
+	for _, child := range children {
+		if strings.EqualFold(child.Name, name) {
+			return child
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package mtp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildStorageDir(t *testing.T) {
+	assert.Equal(t, "SD1", buildStorageDir(Storage{Name: "SD1"}, 0))
+	assert.Equal(t, "Card_Slot 2", buildStorageDir(Storage{Name: " Card/Slot 2 "}, 1))
+	assert.Equal(t, "storage1", buildStorageDir(Storage{Name: ""}, 1))
+	assert.Equal(t, "storage2", buildStorageDir(Storage{Name: ".."}, 2))
+}
+
+func TestDeviceStorage_IsSelected(t *testing.T) {
+	storage := deviceStorage{Storage: Storage{Name: "SD Card"}, index: 1, dir: "SD Card"}
+
+	assert.True(t, storage.isSelected(nil))
+	assert.True(t, storage.isSelected([]string{"sd card"}))
+	assert.True(t, storage.isSelected([]string{"0", "1"}))
+	assert.False(t, storage.isSelected([]string{"Internal", "0"}))
+}
+
+func TestFindStorageObject(t *testing.T) {
+	r := require.New(t)
+
+	camera := newFakeDevice("X-T4", "Fujifilm").
+		withStorages("SD1", "SD2").
+		withFile("SD2/DCIM/100_FUJI/DSCF0001.RAF", "raw", testModTime)
+
+	obj := findStorageObject(camera, "/SD2", filepath.FromSlash("/dcim/100_FUJI/DSCF0001.RAF"))
+	r.NotNil(obj)
+	r.Equal("/SD2/DCIM/100_FUJI/DSCF0001.RAF", obj.Id)
+
+	r.Nil(findStorageObject(camera, "/SD1", filepath.FromSlash("/DCIM/100_FUJI/DSCF0001.RAF")))
+}
+
+func TestLoadFromAllWpd_MultipleStorages(t *testing.T) {
+	r := require.New(t)
+
+	camera := newFakeDevice("X-T4", "Fujifilm").
+		withStorages("SD1", "SD2").
+		withFile("SD1/DCIM/100_FUJI/DSCF0001.JPG", "jpeg1", testModTime).
+		withFile("SD2/DCIM/100_FUJI/DSCF0001.JPG", "jpeg2", testModTime)
+	source := newFakeSource(camera)
+
+	ledger, err := OpenLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	r.NoError(err)
+	defer ledger.Close()
+
+	resultDir, err := LoadFromAllWpd(source, SdPhotosProfile, t.TempDir(), ImportOptions{Ledger: ledger})
+
+	r.NoError(err)
+	assertFile(t, filepath.Join(resultDir, "0", "SD1", "100_FUJI", "DSCF0001.JPG"), "jpeg1", testModTime)
+	assertFile(t, filepath.Join(resultDir, "0", "SD2", "100_FUJI", "DSCF0001.JPG"), "jpeg2", testModTime)
+	r.ElementsMatch([]string{"/SD1/DCIM/100_FUJI/DSCF0001.JPG", "/SD2/DCIM/100_FUJI/DSCF0001.JPG"}, camera.deleted)
+	r.Equal(2, ledger.GetEntriesCount())
+}
+
+func TestLoadFromAllWpd_SelectedStorages(t *testing.T) {
+	r := require.New(t)
+
+	camera := newFakeDevice("X-T4", "Fujifilm").
+		withStorages("SD1", "SD2").
+		withFile("SD1/DCIM/100_FUJI/DSCF0001.JPG", "jpeg1", testModTime).
+		withFile("SD2/DCIM/100_FUJI/DSCF0002.JPG", "jpeg2", testModTime)
+	source := newFakeSource(camera)
+
+	profile := SdPhotosProfile
+	profile.Storages = []string{"1"}
+	resultDir, err := LoadFromAllWpd(source, profile, t.TempDir(), ImportOptions{})
+
+	r.NoError(err)
+	r.NoDirExists(filepath.Join(resultDir, "0", "SD1"))
+	assertFile(t, filepath.Join(resultDir, "0", "SD2", "100_FUJI", "DSCF0002.JPG"), "jpeg2", testModTime)
+	r.Equal([]string{"/SD2/DCIM/100_FUJI/DSCF0002.JPG"}, camera.deleted)
+}
@@ -1,27 +1,69 @@
 package mtp
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/cheggaaa/pb/v3"
-	"github.com/tobwithu/gowpd"
 )
 
-var ignoreFiles = []string{"System Volume Information", "$RECYCLE.BIN"}
+// partFileSuffix is added to files being copied. Complete files are renamed to the final name
+const partFileSuffix = ".part"
+
+// deviceReadError is an error of reading device object. Such errors are often transient, so the copy may be retried
+type deviceReadError struct {
+	err error
+}
+
+func (readErr *deviceReadError) Error() string {
+	return readErr.err.Error()
+}
+
+func (readErr *deviceReadError) Unwrap() error {
+	return readErr.err
+}
+
+func isDeviceReadError(err error) bool {
+	var readErr *deviceReadError
+	return errors.As(err, &readErr)
+}
+
+// deviceReader marks errors of device object reader as device read errors
+type deviceReader struct {
+	reader io.Reader
+}
+
+func (reader deviceReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = &deviceReadError{err}
+	}
+	return n, err
+}
 
 type wpdFile struct {
-	filePath  string
-	fileName  string
-	parentDir string
-	wasCopied bool
-	wpdObject *gowpd.Object
-	wpdDevice *gowpd.Device
+	filePath    string
+	fileName    string
+	parentDir   string
+	wasCopied   bool
+	wasVerified bool
+	// wasPlaced marks copied files which were moved from the temp dir to the target dir
+	wasPlaced bool
+	// isJunk marks files which are deleted from the device but never copied
+	isJunk    bool
+	localPath string
+	wpdObject *Object
+	wpdDevice Device
 	chidren   []*wpdFile
 }
 
-func newWpdFile(parentDir string, dev *gowpd.Device, obj *gowpd.Object) wpdFile {
+func newWpdFile(parentDir string, dev Device, obj *Object, rules ScanRules) wpdFile {
 	result := wpdFile{
 		wpdObject: obj,
 		wpdDevice: dev,
@@ -31,11 +73,15 @@ func newWpdFile(parentDir string, dev *gowpd.Device, obj *gowpd.Object) wpdFile
 		wasCopied: false,
 		chidren:   make([]*wpdFile, 0),
 	}
-	result.initChildren()
+	result.initChildren(rules)
 	return result
 }
 
-func (wf *wpdFile) initChildren() {
+func (wf *wpdFile) initChildren(rules ScanRules) {
+	if !wf.wpdObject.IsDir {
+		return
+	}
+
 	objs, err := wf.wpdDevice.GetChildObjects(wf.wpdObject.Id)
 	if err != nil {
 		log.Warningf("Unable to read children for %v: %v", wf.filePath, err)
@@ -44,16 +90,17 @@ func (wf *wpdFile) initChildren() {
 	curPath := wf.filePath
 	for _, o := range objs {
 
-		if isIgnored(o.Name) {
-			log.Debugf("Skipping '%v' file", o.Name)
+		rel := filepath.Join(curPath, o.Name)
+
+		if rules.isIgnored(toRulePath(rel)) {
+			log.Debugf("Skipping '%v' file", rel)
 			continue
 		}
 
-		rel := filepath.Join(curPath, o.Name)
-
 		log.Debugf("Found: %v", rel)
 
-		child := newWpdFile(wf.filePath, wf.wpdDevice, o)
+		child := newWpdFile(wf.filePath, wf.wpdDevice, o, rules)
+		child.isJunk = !o.IsDir && rules.isJunk(toRulePath(rel))
 		wf.chidren = append(wf.chidren, &child)
 	}
 }
@@ -67,33 +114,93 @@ func (wf *wpdFile) relPath(basepath string) string {
 	return result
 }
 
+// copyTo copies device object into '.part' file which is renamed to targetFile when the copy is complete.
+// The part file is removed on failure. Returns number of bytes written, even if the copy failed
 func (wf *wpdFile) copyTo(targetFile string, progressBar *pb.ProgressBar) (int64, error) {
 	obj := wf.wpdObject
 	id := obj.Id
 
 	reader, err := wf.wpdDevice.GetReader(id)
 	if err != nil {
-		return 0, err
+		return 0, &deviceReadError{err}
 	}
 	defer reader.Close()
 
-	f, err := os.Create(targetFile)
+	partFile := targetFile + partFileSuffix
+	written, err := writePartFile(partFile, deviceReader{reader}, progressBar)
+	if err == nil {
+		err = os.Rename(partFile, targetFile)
+	}
+	if err != nil {
+		if removeErr := os.Remove(partFile); removeErr != nil && !os.IsNotExist(removeErr) {
+			log.Warningf("Unable to remove '%v': %v", partFile, removeErr)
+		}
+		return written, err
+	}
+	return written, setFileTime(targetFile, obj.ModTime)
+}
+
+func writePartFile(partFile string, reader io.Reader, progressBar *pb.ProgressBar) (int64, error) {
+	f, err := os.Create(partFile)
 	if err != nil {
 		return 0, err
 	}
-	writer := gowpd.NewBufWriteCloser(f, 0)
-	defer writer.Close()
+	defer f.Close()
+	writer := bufio.NewWriter(f)
 
 	proxyWriter := progressBar.NewProxyWriter(writer)
 
 	written, err := io.Copy(proxyWriter, reader)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	return written, err
+}
 
+// verify checks copied file size and (optionally) SHA-256 digest against the device object
+func (wf *wpdFile) verify(checksum bool, progressBar *pb.ProgressBar) error {
+	stat, err := os.Stat(wf.localPath)
 	if err != nil {
-		return 0, err
+		return err
+	}
+	if stat.Size() != wf.wpdObject.Size {
+		return fmt.Errorf("size mismatch: %v bytes on disk, %v bytes on device", stat.Size(), wf.wpdObject.Size)
+	}
+
+	if !checksum {
+		return nil
+	}
+
+	localDigest, err := fileDigest(wf.localPath)
+	if err != nil {
+		return err
+	}
+
+	reader, err := wf.wpdDevice.GetReader(wf.wpdObject.Id)
+	if err != nil {
+		return err
 	}
-	return written, gowpd.SetFileTime(targetFile, obj.ModTime)
+	defer reader.Close()
 
-	//return wf.wpdDevice.CopyObjectFromDevice(targetFile, wf.wpdObject)
+	deviceHash := sha256.New()
+	var writer io.Writer = deviceHash
+	if progressBar != nil {
+		writer = progressBar.NewProxyWriter(deviceHash)
+	}
+	if _, err := io.Copy(writer, reader); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(localDigest, deviceHash.Sum(nil)) {
+		return fmt.Errorf("checksum mismatch")
+	}
+	return nil
 }
 
 func (wf *wpdFile) deleteFile() error {
@@ -103,11 +210,21 @@ func (wf *wpdFile) deleteFile() error {
 	return nil
 }
 
+// isIgnored checks the name against default ignore rules
 func isIgnored(fileName string) bool {
-	for _, ignore := range ignoreFiles {
-		if fileName == ignore {
-			return true
-		}
+	return defaultScanRules.isIgnored(fileName)
+}
+
+func fileDigest(filePath string) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	digest := sha256.New()
+	if _, err := io.Copy(digest, f); err != nil {
+		return nil, err
 	}
-	return false
+	return digest.Sum(nil), nil
 }
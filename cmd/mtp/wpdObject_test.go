@@ -1,6 +1,7 @@
 package mtp
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -15,21 +16,21 @@ func Test_wpdFile_relPath(t *testing.T) {
 	}{
 		{
 			name:     "EmptyBasePath",
-			sut:      buildFile("\\tmp\\test\\file1"),
+			sut:      buildFile(filepath.FromSlash("/tmp/test/file1")),
 			basepath: "",
-			want:     "\\tmp\\test\\file1",
+			want:     filepath.FromSlash("/tmp/test/file1"),
 		},
 		{
 			name:     "NormalCase",
-			sut:      buildFile("\\tmp\\test\\file1"),
-			basepath: "\\tmp",
-			want:     "test\\file1",
+			sut:      buildFile(filepath.FromSlash("/tmp/test/file1")),
+			basepath: filepath.FromSlash("/tmp"),
+			want:     filepath.FromSlash("test/file1"),
 		},
 		{
 			name:     "DifferentRoots",
-			sut:      buildFile("\\tmp1\\test\\file1"),
-			basepath: "\\tmp2",
-			want:     "..\\tmp1\\test\\file1",
+			sut:      buildFile(filepath.FromSlash("/tmp1/test/file1")),
+			basepath: filepath.FromSlash("/tmp2"),
+			want:     filepath.FromSlash("../tmp1/test/file1"),
 		},
 	}
 	for _, tt := range tests {
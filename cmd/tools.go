@@ -18,12 +18,17 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
 )
 
 func extractAbsPath(args []string, argPosition int, defaultValue string) string {
@@ -56,8 +61,26 @@ func removeDir(dirName string, removeNonEmpty bool) {
 	}
 }
 
+// removeImportDir removes import temp dir. Import journal is ignored when the dir is checked for remaining files
+func removeImportDir(dirName string, removeNonEmpty bool) {
+	if !removeNonEmpty {
+		entries, err := os.ReadDir(dirName)
+		if err != nil {
+			log.Debugf("Unable to list '%v' children", dirName)
+			return
+		}
+		for _, entry := range entries {
+			if entry.Name() != mtp.JournalFileName && !checkDirEmpty(filepath.Join(dirName, entry.Name())) {
+				log.Infof("Some files were not moved. Please check '%v' temp directory", dirName)
+				return
+			}
+		}
+	}
+	os.RemoveAll(dirName)
+}
+
 func removeFiles(baseDir string, glob string) {
-	files, err := filepath.Glob(baseDir + "\\" + glob)
+	files, err := filepath.Glob(filepath.Join(baseDir, glob))
 	if err != nil {
 		log.Warningf("Unable to open scan '%v' with '%v' pattern", baseDir, glob)
 	}
@@ -100,6 +123,67 @@ func checkDirEmpty(dirName string) bool {
 	return true
 }
 
+// parseDuration parses Go durations (e.g. '12h') plus days and weeks (e.g. '3d', '2w')
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(value, suffix) {
+			count, err := strconv.ParseFloat(strings.TrimSuffix(value, suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid '%s' duration", value)
+			}
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(value)
+}
+
 func printCommandArgs(cmd *cobra.Command, args []string) {
 	log.Debugf("%s called with '%v' args", cmd.CommandPath(), strings.Join(args, " "))
 }
+
+// resolveToolPath expands '$APP_DIR' (the application directory) in custom path of an external tool.
+// Default command (looked up in $PATH) is returned if custom path is not set or was not found
+func resolveToolPath(customPath string, defaultCmd string) string {
+	if customPath == "" {
+		return defaultCmd
+	}
+
+	if strings.Contains(customPath, "$APP_DIR") {
+		expandedPath, err := expandToolPath(customPath)
+		if err == nil {
+			_, err = os.Stat(expandedPath)
+		}
+		if err != nil {
+			log.Infof("Unable to find custom %s: '%s'. Trying to use '%s' from $PATH", defaultCmd, err, defaultCmd)
+			return defaultCmd
+		}
+		customPath = expandedPath
+	}
+
+	return customPath
+}
+
+// expandToolPath replaces '$APP_DIR' in custom path of an external tool by the absolute application directory
+func expandToolPath(customPath string) (string, error) {
+	if !strings.Contains(customPath, "$APP_DIR") {
+		return customPath, nil
+	}
+
+	ex, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(strings.ReplaceAll(customPath, "$APP_DIR", filepath.Dir(ex)))
+}
+
+// exitOnError adapts command handler which returns error. The application exits with non-zero code if the handler fails
+func exitOnError(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		if err := run(cmd, args); err != nil {
+			log.Errorf("%v", err)
+			closeExifTool()
+			os.Exit(1)
+		}
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/redrathnure/media-tool/cmd/mtp"
 )
 
 func TestExtractAbsPath(t *testing.T) {
@@ -118,7 +121,6 @@ func TestGetAbsPath(t *testing.T) {
 	}
 }
 
-
 func prepareNonEmptyDir(t *testing.T, tempDir string) {
 	nonEmptyDir := filepath.Join(tempDir, "nonempty")
 	err := os.Mkdir(nonEmptyDir, 0755)
@@ -169,6 +171,49 @@ func TestRemoveDir_UnExistedDir(t *testing.T) {
 	assert.Error(t, err, "Directory should not exist")
 }
 
+func TestRemoveImportDir_OnlyJournal(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test_remove_import_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "0", "100GOPRO"), 0755))
+	require.NoError(t, os.WriteFile(mtp.GetJournalPath(tempDir), []byte("{}"), 0644))
+
+	removeImportDir(tempDir, false)
+
+	_, err = os.Stat(tempDir)
+	assert.Error(t, err, "Directory should not exist")
+}
+
+func TestRemoveImportDir_NotMovedFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test_remove_import_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "0"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "0", "GX010001.MP4"), []byte("video"), 0644))
+	require.NoError(t, os.WriteFile(mtp.GetJournalPath(tempDir), []byte("{}"), 0644))
+
+	removeImportDir(tempDir, false)
+
+	_, err = os.Stat(mtp.GetJournalPath(tempDir))
+	assert.NoError(t, err, "Journal should exist")
+}
+
+func TestRemoveImportDir_NonEmptyDir(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test_remove_import_dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	prepareNonEmptyDir(t, tempDir)
+	dirName := filepath.Join(tempDir, "nonempty")
+
+	removeImportDir(dirName, true)
+
+	_, err = os.Stat(dirName)
+	assert.Error(t, err, "Directory should not exist")
+}
+
 func TestRemoveFiles_ExistedFiles(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "test_remove_files")
 	require.NoError(t, err)
@@ -193,6 +238,30 @@ func TestRemoveFiles_ExistedFiles(t *testing.T) {
 	assert.NoError(t, err, "File should still exist")
 }
 
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{value: "90m", expected: 90 * time.Minute},
+		{value: "12h", expected: 12 * time.Hour},
+		{value: "3d", expected: 3 * 24 * time.Hour},
+		{value: "1.5d", expected: 36 * time.Hour},
+		{value: "2w", expected: 14 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := parseDuration(tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+
+	_, err := parseDuration("3 days")
+	assert.Error(t, err)
+}
+
 func TestCheckDirEmpty(t *testing.T) {
 	// Create a temporary directory for testing
 	tempDir, err := os.MkdirTemp("", "test_check_dir_empty")